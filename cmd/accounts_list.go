@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/hyperledger/firefly-cli/internal/core"
 	"github.com/hyperledger/firefly-cli/internal/docker"
 	"github.com/hyperledger/firefly-cli/internal/log"
 	"github.com/hyperledger/firefly-cli/internal/stacks"
@@ -28,6 +29,8 @@ import (
 )
 
 // accountsListCmd represents the "accounts list" command
+var showSecrets bool
+
 var accountsListCmd = &cobra.Command{
 	Use:     "list <stack_name>",
 	Short:   "List the accounts in the FireFly stack",
@@ -49,11 +52,21 @@ var accountsListCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if !showSecrets {
+			var redacted interface{}
+			if err := json.Unmarshal(accounts, &redacted); err != nil {
+				return err
+			}
+			if accounts, err = json.MarshalIndent(core.RedactValue(redacted), "", "  "); err != nil {
+				return err
+			}
+		}
 		fmt.Printf("%s\n", string(accounts))
 		return nil
 	},
 }
 
 func init() {
+	accountsListCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show the private keys of the accounts")
 	accountsCmd.AddCommand(accountsListCmd)
 }
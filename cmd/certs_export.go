@@ -0,0 +1,61 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var certsExportOutput string
+
+// certsExportCmd represents the "certs export" command
+var certsExportCmd = &cobra.Command{
+	Use:   "export <stack_name>",
+	Short: "Export the CA bundle of a FireFly stack",
+	Long: `Export the root CA certificate of a FireFly stack, so that apps can trust
+its HTTPS endpoints when the stack was created with --tls`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		caPEM, err := stackManager.CACertPEM()
+		if err != nil {
+			return err
+		}
+		if certsExportOutput == "" {
+			fmt.Print(string(caPEM))
+			return nil
+		}
+		return ioutil.WriteFile(certsExportOutput, caPEM, 0644)
+	},
+}
+
+func init() {
+	certsExportCmd.Flags().StringVarP(&certsExportOutput, "output", "o", "", "File to write the CA bundle to, rather than stdout")
+	certsCmd.AddCommand(certsExportCmd)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// certsListCmd represents the "certs list" command
+var certsListCmd = &cobra.Command{
+	Use:     "list <stack_name>",
+	Short:   "List the certificates in a FireFly stack",
+	Long:    `List the root CA and the data exchange certificate of each member, with their subjects and expiry`,
+	Args:    cobra.ExactArgs(1),
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		certs, err := stackManager.ListCerts()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSUBJECT\tISSUER\tEXPIRES")
+		for _, cert := range certs {
+			expires := cert.NotAfter.Format(time.RFC3339)
+			if time.Now().After(cert.NotAfter) {
+				expires += " (expired)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cert.Name, cert.Subject, cert.Issuer, expires)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	certsCmd.AddCommand(certsListCmd)
+}
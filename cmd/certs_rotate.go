@@ -0,0 +1,63 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var certsRotateMember string
+
+// certsRotateCmd represents the "certs rotate" command
+var certsRotateCmd = &cobra.Command{
+	Use:   "rotate <stack_name>",
+	Short: "Reissue the data exchange certificates of a FireFly stack",
+	Long: `Reissue the data exchange certificates of a FireFly stack from its root CA.
+
+If the stack has been started, the new certificates are copied into the data
+exchange volumes and the data exchange containers are restarted. Peers trust
+the stack's root CA, so they accept the new certificates without re-registering.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.RotateCerts(certsRotateMember); err != nil {
+			return err
+		}
+		fmt.Printf("rotated certificates for stack '%s'\n", stackName)
+		return nil
+	},
+}
+
+func init() {
+	certsRotateCmd.Flags().StringVarP(&certsRotateMember, "member", "m", "", "Only reissue the certificate of this member (ID, org name or node name)")
+	certsCmd.AddCommand(certsRotateCmd)
+}
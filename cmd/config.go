@@ -0,0 +1,48 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var configMember string
+var configComponent string
+var configBlockchain int
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change the config of the components of a FireFly stack",
+	Long: `Read and change the config files of the components of a FireFly stack.
+
+Changes are made to the runtime config of the stack (or the config it will be
+started with, if it has not been started yet), and are kept as overrides that
+are applied again whenever the config is regenerated, for example by a reset.
+Only the container of the changed component is restarted.`,
+}
+
+func init() {
+	configCmd.PersistentFlags().StringVarP(&configMember, "member", "m", "0", "The member whose config to use (ID, org name or node name)")
+	configCmd.PersistentFlags().StringVarP(&configComponent, "component", "c", stacks.ConfigComponentCore, fmt.Sprintf("The component whose config to use. Options are: %s", strings.Join(stacks.ConfigComponents, ", ")))
+	configCmd.PersistentFlags().IntVarP(&configBlockchain, "blockchain", "b", 0, "The blockchain whose connector or signer config to use, where 0 is the blockchain the stack was created with")
+	rootCmd.AddCommand(configCmd)
+}
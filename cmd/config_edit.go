@@ -0,0 +1,118 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// configEditCmd represents the "config edit" command
+var configEditCmd = &cobra.Command{
+	Use:   "edit <stack_name>",
+	Short: "Edit the config of a component of a FireFly stack",
+	Long: `Open the config file of a component of a member of a FireFly stack in your
+editor ($VISUAL or $EDITOR, or vi if neither is set). When the editor exits, the
+config is validated and saved, and the container of the component is restarted.
+
+Settings that are added or changed are kept as overrides, but removing a setting
+only lasts until the config is next regenerated.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		configPath, err := stackManager.ConfigFilePath(configComponent, configMember, configBlockchain)
+		if err != nil {
+			return err
+		}
+		original, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return err
+		}
+		// Edit a copy, so that the config is only changed once it has been validated
+		tempFile, err := ioutil.TempFile("", "*"+filepath.Ext(configPath))
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tempFile.Name())
+		if _, err := tempFile.Write(original); err != nil {
+			return err
+		}
+		tempFile.Close()
+
+		if err := runEditor(tempFile.Name()); err != nil {
+			return err
+		}
+		edited, err := ioutil.ReadFile(tempFile.Name())
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(original, edited) {
+			fmt.Println("no changes made")
+			return nil
+		}
+		removed, err := stackManager.ReplaceConfig(configComponent, configMember, configBlockchain, edited)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("updated the %s config of stack '%s'\n", configComponent, stackName)
+		if len(removed) > 0 {
+			fmt.Printf("\nNOTE: these removed settings will come back if the config is regenerated: %s\n", strings.Join(removed, ", "))
+		}
+		return nil
+	},
+}
+
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may include arguments, such as "code --wait"
+	parts := strings.Fields(editor)
+	editorCmd := exec.Command(parts[0], append(parts[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	return editorCmd.Run()
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
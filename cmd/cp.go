@@ -0,0 +1,57 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// cpCmd represents the cp command
+var cpCmd = &cobra.Command{
+	Use:   "cp <stack_name> <src> <dest>",
+	Short: "Copy files between the host and the containers or volumes of a FireFly stack",
+	Long: `Copy files or directories between the host and the containers or volumes of a
+FireFly stack, in the same way as docker cp.
+
+Paths in a container are written as <service>:<path>, where the service is
+selected in the same way as for the exec command, as in "0/dataexchange:/data/peer-certs".
+Paths in a volume of the stack are written as volume:<volume>:<path>, as in
+"volume:geth:/keystore", and can be used whether or not the stack is running.
+The other path is on the host.`,
+	Args: cobra.ExactArgs(3),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(args[0]); err != nil {
+			return err
+		}
+		return stackManager.Copy(args[1], args[2])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
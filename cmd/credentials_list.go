@@ -0,0 +1,73 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var showCredentialSecrets bool
+
+// credentialsListCmd represents the "credentials list" command
+var credentialsListCmd = &cobra.Command{
+	Use:   "list <stack_name>",
+	Short: "List the generated credentials of a stack",
+	Long: `List the generated credentials of a stack, such as the API users of each
+member. The passwords are redacted unless --show-secrets is set.`,
+	Args:    cobra.ExactArgs(1),
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if stackManager.Stack.State.Credentials == nil {
+			fmt.Printf("stack '%s' was created before credentials were generated, and uses the default passwords\n", stackName)
+			return nil
+		}
+		credentials, err := json.MarshalIndent(stackManager.Stack.State.Credentials, "", "  ")
+		if err != nil {
+			return err
+		}
+		if !showCredentialSecrets {
+			var redacted interface{}
+			if err := json.Unmarshal(credentials, &redacted); err != nil {
+				return err
+			}
+			if credentials, err = json.MarshalIndent(core.RedactValue(redacted), "", "  "); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("%s\n", string(credentials))
+		return nil
+	},
+}
+
+func init() {
+	credentialsListCmd.Flags().BoolVar(&showCredentialSecrets, "show-secrets", false, "Show the passwords")
+	credentialsCmd.AddCommand(credentialsListCmd)
+}
@@ -0,0 +1,61 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// credentialsRotateCmd represents the "credentials rotate" command
+var credentialsRotateCmd = &cobra.Command{
+	Use:   "rotate <stack_name>",
+	Short: "Generate new keystore and database passwords for a stack",
+	Long: `Generate new keystore and database passwords for a stack.
+
+The keystores are re-encrypted with the new passwords, the postgres passwords
+are changed, the configs are updated to match, and the stack is restarted.
+The new passwords are saved before anything is changed, so if the rotation
+fails part way, running this command again completes it.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.RotateCredentials(); err != nil {
+			return err
+		}
+		fmt.Printf("rotated credentials for stack '%s'\n", stackName)
+		return nil
+	},
+}
+
+func init() {
+	credentialsCmd.AddCommand(credentialsRotateCmd)
+}
@@ -0,0 +1,36 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var dbMember string
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Back up, restore and inspect the databases of a FireFly stack",
+	Long: `Back up, restore and inspect the database FireFly core uses for a member of
+a stack, whether that is postgres or sqlite3`,
+}
+
+func init() {
+	dbCmd.PersistentFlags().StringVarP(&dbMember, "member", "m", "0", "The member whose database to use (ID, org name or node name)")
+	rootCmd.AddCommand(dbCmd)
+}
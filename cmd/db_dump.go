@@ -0,0 +1,72 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var dbDumpOutput string
+
+// dbDumpCmd represents the "db dump" command
+var dbDumpCmd = &cobra.Command{
+	Use:   "dump <stack_name>",
+	Short: "Back up the database of a member of a FireFly stack",
+	Long: `Back up the database of a member of a FireFly stack to a file.
+
+Postgres databases are dumped as SQL with pg_dump, and sqlite3 databases are
+copied with the sqlite3 backup command. The file can be loaded again with
+the "db restore" command.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		output := dbDumpOutput
+		if output == "" {
+			ext := "db"
+			if stackManager.Stack.Database.Equals(types.DatabaseSelectionPostgres) {
+				ext = "sql"
+			}
+			output = fmt.Sprintf("%s-member-%s.%s", stackName, dbMember, ext)
+		}
+		if err := stackManager.DumpDatabase(dbMember, output); err != nil {
+			return err
+		}
+		fmt.Printf("database of member %s written to %s\n", dbMember, output)
+		return nil
+	},
+}
+
+func init() {
+	dbDumpCmd.Flags().StringVarP(&dbDumpOutput, "output", "o", "", "Path of the file to write (default \"<stack_name>-member-<member>.sql\", or .db for sqlite3)")
+	dbCmd.AddCommand(dbDumpCmd)
+}
@@ -0,0 +1,61 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// dbRestoreCmd represents the "db restore" command
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore <stack_name> <file>",
+	Short: "Restore the database of a member of a FireFly stack from a backup",
+	Long: `Replace the database of a member of a FireFly stack with a backup written by
+the "db dump" command, and restart its FireFly core to pick up the restored state.
+
+The backup must come from a stack using the same database type. Restoring a
+backup taken from another member or stack is possible, but FireFly will then
+see the identities and data of that member.`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.RestoreDatabase(dbMember, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("database of member %s restored from %s\n", dbMember, args[1])
+		return nil
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbRestoreCmd)
+}
@@ -1,4 +1,4 @@
-// Copyright © 2021 Kaleido, Inc.
+// Copyright © 2022 Kaleido, Inc.
 //
 // SPDX-License-Identifier: Apache-2.0
 //
@@ -14,9 +14,17 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package fabric
+package cmd
 
-var FabricToolsImageName = "hyperledger/fabric-tools:2.3"
-var FabricCAImageName = "hyperledger/fabric-ca:1.5"
-var FabricOrdererImageName = "hyperledger/fabric-orderer:2.3"
-var FabricPeerImageName = "hyperledger/fabric-peer:2.3"
+import (
+	"github.com/spf13/cobra"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Develop FireFly components against a stack",
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+}
@@ -0,0 +1,58 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var devLinkCmd = &cobra.Command{
+	Use:   "link <stack_name> <component>=<source_dir>...",
+	Short: "Run components of a stack from local source checkouts",
+	Long: `Run components of a stack from local source checkouts, e.g. firefly=../firefly.
+The image of each component is built from the Dockerfile in its source directory, and
+tagged for this stack. Running containers of the components are recreated.
+Use "ff dev rebuild" after changing the source, and "ff images set" to unlink.`,
+	Args: cobra.MinimumNArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackManager := stacks.NewStackManager(ctx)
+		stackName := args[0]
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.DevLink(args[1:]); err != nil {
+			return err
+		}
+		fmt.Printf("stack '%s' now uses the linked source directories\n", stackName)
+		return nil
+	},
+}
+
+func init() {
+	devCmd.AddCommand(devLinkCmd)
+}
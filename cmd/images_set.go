@@ -0,0 +1,58 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+var imagesSetCmd = &cobra.Command{
+	Use:   "set <stack_name> <component>=<image>[:tag]...",
+	Short: "Override the images of components of a stack",
+	Long: `Override the images of components of a stack, such as a custom build of a connector.
+The component is a key of the version manifest, e.g. firefly, evmconnect or postgres.
+Images that only exist locally are not pulled. The docker compose file is regenerated,
+and the running containers of the affected services are recreated.`,
+	Args: cobra.MinimumNArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackManager := stacks.NewStackManager(ctx)
+		stackName := args[0]
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.SetImages(args[1:]); err != nil {
+			return err
+		}
+		fmt.Printf("images of stack '%s' updated\n", stackName)
+		return nil
+	},
+}
+
+func init() {
+	imagesCmd.AddCommand(imagesSetCmd)
+}
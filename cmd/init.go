@@ -29,6 +29,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/hyperledger/firefly-cli/internal/core"
 	"github.com/hyperledger/firefly-cli/internal/log"
 	"github.com/hyperledger/firefly-cli/internal/stacks"
 	"github.com/hyperledger/firefly-cli/pkg/types"
@@ -37,6 +38,8 @@ import (
 
 var initOptions types.InitOptions
 var promptNames bool
+var coreConfigPaths []string
+var externalComponents []string
 
 var ffNameValidator = regexp.MustCompile(`^[0-9a-zA-Z]([0-9a-zA-Z._-]{0,62}[0-9a-zA-Z])?$`)
 
@@ -80,6 +83,18 @@ func initCommon(args []string) error {
 	if err := validateIPFSMode(initOptions.IPFSMode); err != nil {
 		return err
 	}
+	if err := validateSecretsEncryption(initOptions.SecretsEncryption); err != nil {
+		return err
+	}
+	if err := validateAdditionalBlockchains(initOptions.AdditionalBlockchains); err != nil {
+		return err
+	}
+	if err := validateImageOverrides(initOptions.ImageOverrides); err != nil {
+		return err
+	}
+	if err := stacks.ValidateTracing(context.Background(), &initOptions); err != nil {
+		return err
+	}
 
 	fmt.Println("initializing new FireFly stack...")
 
@@ -135,9 +150,95 @@ func initCommon(args []string) error {
 	initOptions.OrgNames = orgNames
 	initOptions.NodeNames = nodeNames
 
+	if err := parseCoreConfigPaths(coreConfigPaths); err != nil {
+		return err
+	}
+	return parseExternalComponents(externalComponents)
+}
+
+// parseCoreConfigPaths sorts the --core-config flags into the extra config for every member, and
+// the extra config for a single member, which is given as <member_index>=<path>
+func parseCoreConfigPaths(input []string) error {
+	initOptions.MemberCoreConfigPaths = make(map[string]string)
+	for _, c := range input {
+		parts := strings.SplitN(c, "=", 2)
+		if len(parts) == 2 {
+			memberIndex, err := strconv.Atoi(parts[0])
+			if err == nil {
+				if memberIndex < 0 || memberIndex >= initOptions.MemberCount {
+					return fmt.Errorf("invalid core config '%s' - the stack does not have a member %d", c, memberIndex)
+				}
+				initOptions.MemberCoreConfigPaths[fmt.Sprint(memberIndex)] = parts[1]
+				continue
+			}
+		}
+		if initOptions.ExtraCoreConfigPath != "" {
+			return fmt.Errorf("only one core config can be given for every member - use <member_index>=<path> for the config of a single member")
+		}
+		initOptions.ExtraCoreConfigPath = c
+	}
 	return nil
 }
 
+// parseExternalComponents sorts the --external-component flags, given as <member_index>=<component>[,<component>...],
+// into the components of each member that run outside of docker compose
+func parseExternalComponents(input []string) error {
+	initOptions.ExternalComponents = make(map[string][]string)
+	externalCount := map[string]int{}
+	for i := 0; i < initOptions.MemberCount && i < initOptions.ExternalProcesses; i++ {
+		externalCount[types.ComponentCore]++
+	}
+	for _, e := range input {
+		parts := strings.SplitN(e, "=", 2)
+		memberIndex, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil {
+			return fmt.Errorf("invalid external component '%s' - use <member_index>=<component>[,<component>...]", e)
+		}
+		if memberIndex < 0 || memberIndex >= initOptions.MemberCount {
+			return fmt.Errorf("invalid external component '%s' - the stack does not have a member %d", e, memberIndex)
+		}
+		for _, component := range strings.Split(parts[1], ",") {
+			if err := types.CheckMemberComponent(component); err != nil {
+				return err
+			}
+			switch component {
+			case types.ComponentConnector:
+				if usesFabric() {
+					return fmt.Errorf("an external connector is not supported for fabric")
+				}
+			case types.ComponentDataExchange:
+				if !initOptions.MultipartyEnabled && !initOptions.GatewaySharedServices {
+					return fmt.Errorf("an external data exchange needs multiparty mode or --gateway-shared-services")
+				}
+			}
+			if component == types.ComponentCore && memberIndex < initOptions.ExternalProcesses {
+				continue
+			}
+			initOptions.ExternalComponents[fmt.Sprint(memberIndex)] = append(initOptions.ExternalComponents[fmt.Sprint(memberIndex)], component)
+			externalCount[component]++
+		}
+	}
+	// Smart contracts are extracted from the containers of FireFly core and the token connectors
+	for _, component := range []string{types.ComponentCore, types.ComponentTokens} {
+		if externalCount[component] >= initOptions.MemberCount && (component == types.ComponentCore || len(initOptions.TokenProviders) > 0) {
+			return fmt.Errorf("at least one member must run %s in docker, to be able to extract and deploy smart contracts", component)
+		}
+	}
+	return nil
+}
+
+func usesFabric() bool {
+	if initOptions.BlockchainProvider == types.BlockchainProviderFabric.String() {
+		return true
+	}
+	for _, b := range initOptions.AdditionalBlockchains {
+		if selection, err := types.ParseBlockchainSelection(context.Background(), b); err == nil && selection.BlockchainProvider.Equals(types.BlockchainProviderFabric) {
+			return true
+		}
+	}
+	return false
+}
+
 func validateStackName(stackName string) error {
 	if strings.TrimSpace(stackName) == "" {
 		return errors.New("stack name must not be empty")
@@ -220,6 +321,24 @@ func validateTokensProvider(input []string, blockchainNodeProviderInput string)
 	return nil
 }
 
+func validateAdditionalBlockchains(input []string) error {
+	for _, b := range input {
+		if _, err := types.ParseBlockchainSelection(context.Background(), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateImageOverrides(input []string) error {
+	for _, override := range input {
+		if _, _, err := types.ParseImageOverride(override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateReleaseChannel(input string) error {
 	_, err := fftypes.FFEnumParseString(context.Background(), types.ReleaseChannelSelection, input)
 	return err
@@ -230,6 +349,11 @@ func validateIPFSMode(input string) error {
 	return err
 }
 
+func validateSecretsEncryption(input string) error {
+	_, err := fftypes.FFEnumParseString(context.Background(), types.SecretsEncryption, input)
+	return err
+}
+
 func randomHexString(length int) string {
 	bytes := make([]byte, length)
 	rand.Read(bytes)
@@ -243,24 +367,45 @@ func init() {
 	initCmd.Flags().StringVarP(&initOptions.BlockchainConnector, "blockchain-connector", "c", "ethconnect", fmt.Sprintf("Blockchain connector to use. Options are: %v", fftypes.FFEnumValues(types.BlockchainConnector)))
 	initCmd.Flags().StringVarP(&initOptions.BlockchainProvider, "blockchain-provider", "b", "ethereum", fmt.Sprintf("Blockchain to use. Options are: %v", fftypes.FFEnumValues(types.BlockchainProvider)))
 	initCmd.Flags().StringVarP(&initOptions.BlockchainNodeProvider, "blockchain-node", "n", "geth", fmt.Sprintf("Blockchain node type to use. Options are: %v", fftypes.FFEnumValues(types.BlockchainNodeProvider)))
+	initCmd.PersistentFlags().StringArrayVar(&initOptions.AdditionalBlockchains, "additional-blockchain", []string{}, "Run an additional blockchain alongside the first one, as <provider>[:<node>[:<connector>]] - each gets its own blockchain plugin in FireFly core")
 	initCmd.PersistentFlags().StringArrayVarP(&initOptions.TokenProviders, "token-providers", "t", []string{"erc20_erc721"}, fmt.Sprintf("Token providers to use. Options are: %v", fftypes.FFEnumValues(types.TokenProvider)))
 	initCmd.PersistentFlags().IntVarP(&initOptions.ExternalProcesses, "external", "e", 0, "Manage a number of FireFly core processes outside of the docker-compose stack - useful for development and debugging")
+	initCmd.PersistentFlags().StringArrayVar(&externalComponents, "external-component", []string{}, fmt.Sprintf("Run components of a member outside of the docker-compose stack, as <member_index>=<component>[,<component>...] - options are: %s", strings.Join(types.MemberComponents, ", ")))
 	initCmd.PersistentFlags().StringVarP(&initOptions.FireFlyVersion, "release", "r", "latest", "Select the FireFly release version to use")
 	initCmd.PersistentFlags().StringVarP(&initOptions.ManifestPath, "manifest", "m", "", "Path to a manifest.json file containing the versions of each FireFly microservice to use. Overrides the --release flag.")
+	initCmd.PersistentFlags().StringArrayVar(&initOptions.ImageOverrides, "image", []string{}, "Use a specific image for a component, as <component>=<image>[:tag] - overrides the manifest, e.g. evmconnect=myreg/evmconnect:dev")
 	initCmd.PersistentFlags().BoolVar(&promptNames, "prompt-names", false, "Prompt for org and node names instead of using the defaults")
 	initCmd.PersistentFlags().BoolVar(&initOptions.PrometheusEnabled, "prometheus-enabled", false, "Enables Prometheus metrics exposition and aggregation to a shared Prometheus server")
 	initCmd.PersistentFlags().BoolVar(&initOptions.SandboxEnabled, "sandbox-enabled", true, "Enables the FireFly Sandbox to be started with your FireFly stack")
 	initCmd.PersistentFlags().IntVar(&initOptions.PrometheusPort, "prometheus-port", 9090, "Port for the shared Prometheus server")
-	initCmd.PersistentFlags().StringVar(&initOptions.ExtraCoreConfigPath, "core-config", "", "The path to a yaml file containing extra config for FireFly Core")
+	initCmd.PersistentFlags().BoolVar(&initOptions.GrafanaEnabled, "grafana-enabled", false, "Enables a Grafana server with dashboards for the metrics collected by Prometheus (implies --prometheus-enabled)")
+	initCmd.PersistentFlags().IntVar(&initOptions.GrafanaPort, "grafana-port", 3000, "Port for the Grafana server")
+	initCmd.PersistentFlags().BoolVar(&initOptions.TracingEnabled, "tracing", false, "Adds an OpenTelemetry collector and a Jaeger UI for traces - only besu exports traces, so this needs a besu node and cannot be used with --prometheus-enabled or --grafana-enabled")
+	initCmd.PersistentFlags().IntVar(&initOptions.JaegerPort, "jaeger-port", 16686, "Port for the Jaeger UI")
+	initCmd.PersistentFlags().StringArrayVar(&coreConfigPaths, "core-config", []string{}, "The path to a yaml file containing extra config for FireFly Core, or <member_index>=<path> for extra config for a single member")
 	initCmd.PersistentFlags().StringVar(&initOptions.ExtraConnectorConfigPath, "connector-config", "", "The path to a yaml file containing extra config for the blockchain connector")
 	initCmd.Flags().IntVar(&initOptions.BlockPeriod, "block-period", -1, "Block period in seconds. Default is variable based on selected blockchain provider.")
 	initCmd.Flags().StringVar(&initOptions.ContractAddress, "contract-address", "", "Do not automatically deploy a contract, instead use a pre-configured address")
 	initCmd.Flags().StringVar(&initOptions.RemoteNodeURL, "remote-node-url", "", "For cases where the node is pre-existing and running remotely")
 	initCmd.Flags().Int64Var(&initOptions.ChainID, "chain-id", 2021, "The chain ID (Ethereum only) - also used as the network ID")
 	initCmd.PersistentFlags().IntVar(&initOptions.RequestTimeout, "request-timeout", 0, "Custom request timeout (in seconds) - useful for registration to public chains")
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.RetryMaxAttempts, "retry-max-attempts", 0, fmt.Sprintf("Maximum number of attempts for each request the CLI makes to the stack (default %d)", core.DefaultRetryMaxAttempts))
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.RetryInitialDelayMs, "retry-initial-delay", 0, fmt.Sprintf("Initial delay (in milliseconds) before retrying a failed request (default %d)", core.DefaultRetryInitialDelayMs))
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.RetryMaxDelayMs, "retry-max-delay", 0, fmt.Sprintf("Maximum delay (in milliseconds) between retries of a failed request (default %d)", core.DefaultRetryMaxDelayMs))
+	initCmd.PersistentFlags().Float64Var(&initOptions.HTTPClient.RetryFactor, "retry-factor", 0, fmt.Sprintf("Factor the retry delay is multiplied by after each attempt (default %v)", core.DefaultRetryFactor))
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.RetryDeadlineSecs, "retry-deadline", 0, fmt.Sprintf("Overall time (in seconds) to keep retrying a failed request (default %d)", core.DefaultRetryDeadlineSecs))
+	initCmd.PersistentFlags().IntSliceVar(&initOptions.HTTPClient.RetryStatusCodes, "retry-status-codes", []int{}, fmt.Sprintf("HTTP status codes that should be retried (default %v)", core.DefaultRetryStatusCodes))
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.ConnectTimeoutSecs, "connect-timeout", 0, fmt.Sprintf("Timeout (in seconds) for establishing a connection to a service in the stack (default %d)", core.DefaultConnectTimeoutSecs))
+	initCmd.PersistentFlags().IntVar(&initOptions.HTTPClient.ResponseTimeoutSecs, "response-timeout", 0, "Timeout (in seconds) waiting for a service in the stack to respond - no timeout by default")
 	initCmd.PersistentFlags().StringVar(&initOptions.ReleaseChannel, "channel", "stable", fmt.Sprintf("Select the FireFly release channel to use. Options are: %v", fftypes.FFEnumValues(types.ReleaseChannelSelection)))
 	initCmd.PersistentFlags().BoolVar(&initOptions.MultipartyEnabled, "multiparty", true, "Enable or disable multiparty mode")
+	initCmd.PersistentFlags().BoolVar(&initOptions.GatewaySharedServices, "gateway-shared-services", false, "Keep IPFS and data exchange for each member of a stack with multiparty disabled, which otherwise does not run them")
+	initCmd.PersistentFlags().BoolVar(&initOptions.SharedInfrastructure, "shared-infrastructure", false, "Run a single postgres for all members, with a database and user for each, and a single IPFS node in public IPFS mode")
 	initCmd.PersistentFlags().StringVar(&initOptions.IPFSMode, "ipfs-mode", "private", fmt.Sprintf("Set the mode in which IFPS operates. Options are: %v", fftypes.FFEnumValues(types.IPFSMode)))
+	initCmd.PersistentFlags().StringVar(&initOptions.SecretsEncryption, "secrets-encryption", "none", fmt.Sprintf("Encrypt private keys and passwords in the stack state with a passphrase or a key stored in the OS keyring. Options are: %v", fftypes.FFEnumValues(types.SecretsEncryption)))
+	initCmd.PersistentFlags().BoolVar(&initOptions.TLSEnabled, "tls", false, "Serve the FireFly core, connector, signer and token connector APIs over HTTPS, with certificates issued by a CA for the stack")
+	initCmd.PersistentFlags().BoolVar(&initOptions.BasicAuthEnabled, "basic-auth", false, "Require a generated username and password on the FireFly APIs of each member, and on their connectors where supported")
+	initCmd.PersistentFlags().BoolVar(&initOptions.PerMemberCredentials, "per-member-credentials", false, "Generate separate database and keystore passwords for each member, rather than one set for the whole stack")
 	initCmd.PersistentFlags().StringArrayVar(&initOptions.OrgNames, "org-name", []string{}, "Organization name")
 	initCmd.PersistentFlags().StringArrayVar(&initOptions.NodeNames, "node-name", []string{}, "Node name")
 	rootCmd.AddCommand(initCmd)
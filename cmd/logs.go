@@ -22,19 +22,26 @@ import (
 	"github.com/hyperledger/firefly-cli/internal/docker"
 	"github.com/hyperledger/firefly-cli/internal/log"
 	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/hyperledger/firefly-cli/pkg/types"
 	"github.com/spf13/cobra"
 )
 
-var follow bool
+var logsOptions types.LogsOptions
 
-// logsCmd represents the logs command
 var logsCmd = &cobra.Command{
 	Use:   "logs <stack_name>",
 	Short: "View log output from a stack",
 	Long: `View log output from a stack.
 
 The most recent logs can be viewed, or you can follow the
-output with the -f flag.`,
+output with the -f flag.
+
+Logs from all of the selected services are merged and shown in
+timestamp order. Use --member and --service to select services,
+for example "--member 0 --service firefly --service tokens".
+Services can be selected by their name in the docker compose file,
+or by their logical name (firefly, connector, dataexchange, ipfs,
+postgres, tokens, sandbox, signer, blockchain).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := log.WithVerbosity(context.Background(), verbose)
 		ctx = context.WithValue(ctx, docker.CtxIsLogCmd{}, true)
@@ -59,16 +66,11 @@ output with the -f flag.`,
 		}
 
 		if stackHasRunBefore {
-			fmt.Println("getting logs... ")
-			commandLine := []string{}
-			if fancyFeatures {
-				commandLine = append(commandLine, "--ansi", "always")
-			}
-			commandLine = append(commandLine, "-p", stackName, "logs")
-			if follow {
-				commandLine = append(commandLine, "-f")
+			if !logsOptions.JSON {
+				fmt.Println("getting logs... ")
 			}
-			docker.RunDockerComposeCommand(ctx, stackManager.Stack.RuntimeDir, commandLine...)
+			logsOptions.Color = fancyFeatures && !logsOptions.JSON
+			return stackManager.Logs(&logsOptions)
 		} else {
 			fmt.Println("no logs found - stack has not been started")
 		}
@@ -78,5 +80,13 @@ output with the -f flag.`,
 
 func init() {
 	rootCmd.AddCommand(logsCmd)
-	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "follow log output")
+	logsCmd.Flags().BoolVarP(&logsOptions.Follow, "follow", "f", false, "follow log output")
+	logsCmd.Flags().StringArrayVarP(&logsOptions.Members, "member", "m", []string{}, "only show logs for the given member (index, org name or node name)")
+	logsCmd.Flags().StringArrayVarP(&logsOptions.Services, "service", "s", []string{}, "only show logs for the given service")
+	logsCmd.Flags().StringVarP(&logsOptions.Tail, "tail", "n", "all", "number of lines to show from the end of the logs of each service")
+	logsCmd.Flags().StringVar(&logsOptions.Since, "since", "", "show logs since a timestamp (e.g. 2022-08-01T13:23:37Z) or relative time (e.g. 42m)")
+	logsCmd.Flags().StringVar(&logsOptions.Until, "until", "", "show logs before a timestamp (e.g. 2022-08-01T13:23:37Z) or relative time (e.g. 42m)")
+	logsCmd.Flags().StringVarP(&logsOptions.Grep, "grep", "g", "", "only show log lines matching a regular expression")
+	logsCmd.Flags().StringVarP(&logsOptions.Level, "level", "l", "", "only show log lines at or above the given level (\"trace\"|\"debug\"|\"info\"|\"warn\"|\"error\"|\"fatal\")")
+	logsCmd.Flags().BoolVar(&logsOptions.JSON, "json", false, "print each log line as a JSON object")
 }
@@ -0,0 +1,33 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// namespacesCmd represents the namespaces command
+var namespacesCmd = &cobra.Command{
+	Use:     "namespaces",
+	Short:   "Manage the namespaces of a FireFly stack",
+	Long:    `Add and remove predefined namespaces in the core config of every member of a FireFly stack`,
+	Aliases: []string{"ns"},
+}
+
+func init() {
+	rootCmd.AddCommand(namespacesCmd)
+}
@@ -0,0 +1,84 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/spf13/cobra"
+)
+
+var namespaceOptions types.NamespaceOptions
+
+// namespacesAddCmd represents the "namespaces add" command
+var namespacesAddCmd = &cobra.Command{
+	Use:   "add <stack_name> <namespace_name>",
+	Short: "Add a namespace to a FireFly stack",
+	Long: `Add a predefined namespace to the core config of every member of a FireFly stack.
+
+The stack must have been started. A token connector is started on every member
+for each token provider of the namespace, and its contracts are deployed. A
+multiparty namespace gets its own FireFly contract, unless the address of an
+existing one is set, and the org and node of each member are registered in it.
+FireFly core is restarted to pick up the new namespace.`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		namespaceOptions.Name = args[1]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if namespaceOptions.Blockchain < 0 || namespaceOptions.Blockchain >= stackManager.Stack.BlockchainCount() {
+			return fmt.Errorf("stack '%s' does not have a blockchain with index %d", stackName, namespaceOptions.Blockchain)
+		}
+		blockchainStack := stackManager.Stack.BlockchainStack(namespaceOptions.Blockchain)
+		if len(namespaceOptions.TokenProviders) > 0 && blockchainStack.BlockchainProvider.Equals(types.BlockchainProviderEthereum) {
+			if err := validateTokensProvider(namespaceOptions.TokenProviders, blockchainStack.BlockchainNodeProvider.String()); err != nil {
+				return err
+			}
+		}
+		messages, err := stackManager.AddNamespace(&namespaceOptions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added namespace '%s' to stack '%s'\n", namespaceOptions.Name, stackName)
+		for _, message := range messages {
+			fmt.Printf("\n%s\n", message)
+		}
+		return nil
+	},
+}
+
+func init() {
+	namespacesAddCmd.Flags().BoolVar(&namespaceOptions.Multiparty, "multiparty", false, "Enable multiparty mode for the namespace")
+	namespacesAddCmd.Flags().StringVar(&namespaceOptions.ContractAddress, "contract-address", "", "Use an existing FireFly contract for a multiparty namespace, instead of deploying a new one")
+	namespacesAddCmd.Flags().IntVar(&namespaceOptions.Blockchain, "blockchain", 0, "Index of the blockchain plugin the namespace uses, for a stack with additional blockchains")
+	namespacesAddCmd.Flags().StringArrayVarP(&namespaceOptions.TokenProviders, "token-providers", "t", []string{}, fmt.Sprintf("Token providers to add to the namespace. Options are: %v", fftypes.FFEnumValues(types.TokenProvider)))
+	namespacesCmd.AddCommand(namespacesAddCmd)
+}
@@ -0,0 +1,61 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// namespacesListCmd represents the "namespaces list" command
+var namespacesListCmd = &cobra.Command{
+	Use:     "list <stack_name>",
+	Short:   "List the namespaces of a FireFly stack",
+	Long:    `List the predefined namespaces in the core config of a FireFly stack, with the plugins they use`,
+	Args:    cobra.ExactArgs(1),
+	Aliases: []string{"ls"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		namespaces, err := stackManager.ListNamespaces()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tMULTIPARTY\tPLUGINS")
+		for _, ns := range namespaces {
+			fmt.Fprintf(w, "%s\t%t\t%s\n", ns.Name, ns.Multiparty, strings.Join(ns.Plugins, ","))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	namespacesCmd.AddCommand(namespacesListCmd)
+}
@@ -0,0 +1,62 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// namespacesRemoveCmd represents the "namespaces remove" command
+var namespacesRemoveCmd = &cobra.Command{
+	Use:   "remove <stack_name> <namespace_name>",
+	Short: "Remove a namespace from a FireFly stack",
+	Long: `Remove a namespace that was added with the "namespaces add" command from the
+core config of every member, stop its token connectors and restart FireFly core.
+
+The data of the namespace is kept in the database, and its contracts stay on
+the chain.`,
+	Args:    cobra.ExactArgs(2),
+	Aliases: []string{"rm"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		namespace := args[1]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.RemoveNamespace(namespace); err != nil {
+			return err
+		}
+		fmt.Printf("removed namespace '%s' from stack '%s'\n", namespace, stackName)
+		return nil
+	},
+}
+
+func init() {
+	namespacesCmd.AddCommand(namespacesRemoveCmd)
+}
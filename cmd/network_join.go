@@ -0,0 +1,77 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+// networkJoinCmd represents the "network join" command
+var networkJoinCmd = &cobra.Command{
+	Use:   "join <host_stack_name> <joining_stack_name>",
+	Short: "Make the members of one stack join the network of another",
+	Long: `Make the members of one stack join the multiparty network of another.
+
+The host stack must have been started, so that its FireFly contract has been
+deployed. The joining stack must not have been started yet (use 'ff reset' if
+it has), as it adopts the chain ID, genesis block, swarm key and FireFly
+contract of the host.
+
+Both stacks are attached to a shared docker network. A joining stack with a
+geth node peers it with the geth node of the host, and a joining stack with
+the remote-rpc node provider points its signer at the node of the host.
+Peering besu nodes is not supported: a stack with a besu node can host a
+network, but stacks joining it must use the remote-rpc node provider. The
+data exchange certificates of both stacks are exchanged, and each time the
+joining stack starts its IPFS nodes are connected to those of the host, so
+the host must be started first.`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		hostStackName := args[0]
+		stackName := args[1]
+		hostStackManager := stacks.NewStackManager(ctx)
+		if err := hostStackManager.LoadStack(hostStackName); err != nil {
+			return err
+		}
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if err := stackManager.JoinNetwork(hostStackManager); err != nil {
+			return err
+		}
+		fmt.Printf("stack '%s' has joined the network of stack '%s'\n\n", stackName, hostStackName)
+		fmt.Printf("If stack '%s' is running, restart it to attach it to the shared network:\n\n%s stop %s && %s start %s\n\n", hostStackName, rootCmd.Use, hostStackName, rootCmd.Use, hostStackName)
+		fmt.Printf("Then start the joining stack:\n\n%s start %s\n\n", rootCmd.Use, stackName)
+		return nil
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkJoinCmd)
+}
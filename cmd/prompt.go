@@ -18,10 +18,14 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/secrets"
+	"golang.org/x/term"
 )
 
 func prompt(promptText string, validate func(string) error) (string, error) {
@@ -84,6 +88,32 @@ func selectMenu(promptText string, options []string) (string, error) {
 	}
 }
 
+// promptPassphrase reads a passphrase without echoing it to the terminal
+func promptPassphrase(stackName string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stack '%s' has encrypted secrets - please set %s to the passphrase for the stack", stackName, secrets.PassphraseEnvVar)
+	}
+	fmt.Printf("passphrase for stack '%s': ", stackName)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Printf("confirm passphrase for stack '%s': ", stackName)
+		confirmation, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		if string(confirmation) != string(passphrase) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
 func printError(err error) {
 	if fancyFeatures {
 		fmt.Printf("\u001b[31mError: %s\u001b[0m\n", err.Error())
@@ -26,7 +26,9 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 
+	"github.com/hyperledger/firefly-cli/internal/core"
 	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/secrets"
 )
 
 var cfgFile string
@@ -34,6 +36,8 @@ var ansi string
 var fancyFeatures bool
 var verbose bool
 var force bool
+var traceHTTPFile string
+var traceHTTPFormat string
 var logger log.Logger = &log.StdoutLogger{
 	LogLevel: log.Debug,
 }
@@ -63,7 +67,7 @@ commands to manage the lifecycle of stacks.
 
 To get started run: ff init
 	`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if ansi == "always" {
 			fancyFeatures = true
 		} else if ansi == "auto" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())) {
@@ -71,6 +75,10 @@ To get started run: ff init
 		} else {
 			fancyFeatures = false
 		}
+		if traceHTTPFile != "" {
+			return core.EnableHTTPTrace(traceHTTPFile, traceHTTPFormat)
+		}
+		return nil
 	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -82,11 +90,14 @@ To get started run: ff init
 func Execute() {
 	rootCmd.PersistentFlags().StringVarP(&ansi, "ansi", "", "auto", "control when to print ANSI control characters (\"never\"|\"always\"|\"auto\")")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose log output")
+	rootCmd.PersistentFlags().StringVar(&traceHTTPFile, "trace-http", "", "record every HTTP request made by the CLI, with secrets redacted, to the specified file")
+	rootCmd.PersistentFlags().StringVar(&traceHTTPFormat, "trace-http-format", "", "format of the HTTP trace file (\"har\"|\"jsonl\") - inferred from the file extension by default")
 	cobra.CheckErr(rootCmd.Execute())
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	secrets.PassphrasePrompt = promptPassphrase
 }
 
 func cancel() {
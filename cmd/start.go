@@ -82,7 +82,7 @@ This command will start a stack and run it in the background.
 			fmt.Printf("%s\n\n", message)
 		}
 		for _, member := range stackManager.Stack.Members {
-			fmt.Printf("Web UI for member '%v': http://127.0.0.1:%v/ui\n", member.ID, member.ExposedFireflyPort)
+			fmt.Printf("Web UI for member '%v': %s://127.0.0.1:%v/ui\n", member.ID, stackManager.Stack.Scheme(), member.ExposedFireflyPort)
 			if stackManager.Stack.SandboxEnabled {
 				fmt.Printf("Sandbox UI for member '%v': http://127.0.0.1:%v\n\n", member.ID, member.ExposedSandboxPort)
 			}
@@ -91,6 +91,27 @@ This command will start a stack and run it in the background.
 		if stackManager.Stack.PrometheusEnabled {
 			fmt.Printf("Web UI for shared Prometheus: http://127.0.0.1:%v\n", stackManager.Stack.ExposedPrometheusPort)
 		}
+		if stackManager.Stack.GrafanaEnabled {
+			fmt.Printf("Grafana dashboards: http://127.0.0.1:%v\n", stackManager.Stack.ExposedGrafanaPort)
+		}
+		if stackManager.Stack.TracingEnabled {
+			fmt.Printf("Jaeger UI for traces: http://127.0.0.1:%v\n", stackManager.Stack.ExposedJaegerPort)
+			stackManager.PrintTracingSummary()
+		}
+
+		if stackManager.Stack.BasicAuthEnabled {
+			fmt.Print("\nThe FireFly APIs require basic auth with these users:\n\n")
+			for _, member := range stackManager.Stack.Members {
+				if auth := stackManager.Stack.APICredentials(member.ID); auth != nil {
+					fmt.Printf("Member '%v': username '%s'\n", member.ID, auth.Username)
+				}
+			}
+			fmt.Printf("\nTo see their passwords run:\n\n%s credentials list %s --show-secrets\n", rootCmd.Use, stackName)
+		}
+
+		if stackManager.Stack.TLSEnabled {
+			fmt.Printf("\nThe stack serves HTTPS with certificates issued by its own CA. To trust it, use the CA bundle at:\n\n%s\n", stackManager.Stack.TLSCAFile())
+		}
 
 		fmt.Printf("\nTo see logs for your stack run:\n\n%s logs %s\n\n", rootCmd.Use, stackName)
 		return nil
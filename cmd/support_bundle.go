@@ -0,0 +1,73 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/stacks"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var supportBundleOptions types.SupportBundleOptions
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle <stack_name>",
+	Short: "Collect diagnostic information about a stack for a bug report",
+	Long: `Collect diagnostic information about a stack for a bug report.
+
+The bundle is a zip file containing the stack configuration, the generated
+config files for each component, the docker compose files, docker inspect
+output and recent logs for each container, image digests, and host, docker
+and CLI versions. Private keys and passwords are redacted. A manifest.json
+file in the bundle lists everything that was collected.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return docker.CheckDockerConfig()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := log.WithVerbosity(context.Background(), verbose)
+		ctx = log.WithLogger(ctx, logger)
+		stackName := args[0]
+		stackManager := stacks.NewStackManager(ctx)
+		if err := stackManager.LoadStack(stackName); err != nil {
+			return err
+		}
+		if supportBundleOptions.OutputPath == "" {
+			supportBundleOptions.OutputPath = fmt.Sprintf("%s-support-bundle.zip", stackName)
+		}
+		info := getVersionInfo()
+		supportBundleOptions.CLIVersion = info.Version
+		supportBundleOptions.CLICommit = info.Commit
+		supportBundleOptions.CLIBuildDate = info.Date
+		if err := stackManager.CreateSupportBundle(&supportBundleOptions); err != nil {
+			return err
+		}
+		fmt.Printf("Support bundle for stack '%s' written to: %s\n", stackName, supportBundleOptions.OutputPath)
+		return nil
+	},
+}
+
+func init() {
+	supportBundleCmd.Flags().StringVarP(&supportBundleOptions.OutputPath, "output", "o", "", "Path of the zip file to write (default \"<stack_name>-support-bundle.zip\")")
+	supportBundleCmd.Flags().IntVar(&supportBundleOptions.LogLines, "log-lines", 1000, "Number of recent log lines to include for each container")
+	rootCmd.AddCommand(supportBundleCmd)
+}
@@ -46,21 +46,7 @@ var versionCmd = &cobra.Command{
 	Long:  "Prints the version info of the CLI binary",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		info := &Info{
-			Version: BuildVersionOverride,
-			Date:    BuildDate,
-			Commit:  BuildCommit,
-			License: "Apache-2.0",
-		}
-
-		// Where you are using go install, we will get good version information usefully from Go
-		// When we're in go-releaser in a Github action, we will have the version passed in explicitly
-		if info.Version == "" {
-			buildInfo, ok := debug.ReadBuildInfo()
-			if ok {
-				info.Version = buildInfo.Main.Version
-			}
-		}
+		info := getVersionInfo()
 
 		if shortened {
 			fmt.Println(info.Version)
@@ -90,6 +76,25 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+func getVersionInfo() *Info {
+	info := &Info{
+		Version: BuildVersionOverride,
+		Date:    BuildDate,
+		Commit:  BuildCommit,
+		License: "Apache-2.0",
+	}
+
+	// Where you are using go install, we will get good version information usefully from Go
+	// When we're in go-releaser in a Github action, we will have the version passed in explicitly
+	if info.Version == "" {
+		buildInfo, ok := debug.ReadBuildInfo()
+		if ok {
+			info.Version = buildInfo.Main.Version
+		}
+	}
+	return info
+}
+
 func init() {
 	versionCmd.Flags().BoolVarP(&shortened, "short", "s", false, "print only the version")
 	versionCmd.Flags().StringVarP(&output, "output", "o", "json", "output format (\"yaml\"|\"json\")")
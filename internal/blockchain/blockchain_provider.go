@@ -34,6 +34,7 @@ type IBlockchainProvider interface {
 	GetContracts(filename string, extraArgs []string) ([]string, error)
 	DeployContract(filename, contractName, instanceName string, member *types.Organization, extraArgs []string) (*types.ContractDeploymentResult, error)
 	CreateAccount(args []string) (interface{}, error)
+	RotateKeystorePasswords(oldPassword func(address string) string) error
 	ParseAccount(interface{}) interface{}
 	GetConnectorName() string
 	GetConnectorURL(org *types.Organization) string
@@ -24,6 +24,8 @@ import (
 	"path/filepath"
 
 	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/internal/secrets"
+	"github.com/hyperledger/firefly-cli/pkg/types"
 	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
 	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 )
@@ -52,6 +54,81 @@ func CreateWalletFile(outputDirectory, prefix, password string) (*secp256k1.KeyP
 	return keyPair, filename, nil
 }
 
+// CreateStackWalletFile creates the keystore file for a new account in a stack. When the stack has
+// per-member credentials each account gets its own password, otherwise the stack's password is used
+func CreateStackWalletFile(stack *types.Stack, outputDirectory, prefix string) (keyPair *secp256k1.KeyPair, filename, password string, err error) {
+	password = stack.KeystorePassword("")
+	if stack.PerMemberCredentials() {
+		if password, err = secrets.GeneratePassword(); err != nil {
+			return nil, "", "", err
+		}
+	}
+	if keyPair, filename, err = CreateWalletFile(outputDirectory, prefix, password); err != nil {
+		return nil, "", "", err
+	}
+	if stack.PerMemberCredentials() {
+		stack.SetKeystorePassword(keyPair.Address.String(), password)
+	}
+	return keyPair, filename, password, nil
+}
+
+// ReencryptWalletFile decrypts a keystore file with its old password and writes it back encrypted with a new one
+func ReencryptWalletFile(filename, oldPassword, newPassword string) (*secp256k1.KeyPair, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := keystorev3.ReadWalletFile(b, []byte(oldPassword))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt keystore file %s: %s", filename, err)
+	}
+	keyPair := wallet.KeyPair()
+	return keyPair, ioutil.WriteFile(filename, keystorev3.NewWalletFileStandard(newPassword, keyPair).JSON(), 0755)
+}
+
+// ReencryptKeystore re-encrypts every keystore file in a directory with the stack's current keystore
+// passwords, and returns the paths of the files that were rewritten
+func ReencryptKeystore(stack *types.Stack, keystoreDirectory string, oldPassword func(address string) string) ([]string, error) {
+	files, err := ioutil.ReadDir(keystoreDirectory)
+	if err != nil {
+		return nil, err
+	}
+	walletFilePaths := []string{}
+	for _, file := range files {
+		name := file.Name()
+		// Skip the signer's .toml metadata and .password files, which sit alongside the keystore files
+		if file.IsDir() || filepath.Ext(name) != "" || len(name) < 40 {
+			continue
+		}
+		address := "0x" + name[len(name)-40:]
+		newPassword := stack.KeystorePassword(address)
+		if stack.PerMemberCredentials() && !stack.HasAccountKeystorePassword(address) {
+			if newPassword, err = secrets.GeneratePassword(); err != nil {
+				return nil, err
+			}
+			stack.SetKeystorePassword(address, newPassword)
+		}
+		walletFilePath := filepath.Join(keystoreDirectory, name)
+		if _, err := ReencryptWalletFile(walletFilePath, oldPassword(address), newPassword); err != nil {
+			// A rotation that is being completed may have re-encrypted this file already
+			if !walletFileHasPassword(walletFilePath, newPassword) {
+				return nil, err
+			}
+		}
+		walletFilePaths = append(walletFilePaths, walletFilePath)
+	}
+	return walletFilePaths, nil
+}
+
+func walletFileHasPassword(filename, password string) bool {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	_, err = keystorev3.ReadWalletFile(b, []byte(password))
+	return err == nil
+}
+
 func CopyWalletFileToVolume(ctx context.Context, walletFilePath, volumeName string) error {
 	if err := docker.MkdirInVolume(ctx, volumeName, "/keystore"); err != nil {
 		return err
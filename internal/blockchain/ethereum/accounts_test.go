@@ -0,0 +1,75 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethereum
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReencryptKeystorePerMember(T *testing.T) {
+	dir := T.TempDir()
+	stack := &types.Stack{
+		State: &types.StackState{
+			Credentials: &types.StackCredentials{
+				Credentials: types.Credentials{KeystorePassword: "stack"},
+				PerMember:   true,
+			},
+		},
+	}
+
+	keyPair, walletFilePath, password, err := CreateStackWalletFile(stack, dir, "")
+	assert.NoError(T, err)
+	address := keyPair.Address.String()
+	assert.NotEqual(T, "stack", password)
+	assert.Equal(T, password, stack.KeystorePassword(address))
+
+	oldStack := &types.Stack{State: &types.StackState{Credentials: stack.State.Credentials}}
+	stack.State = &types.StackState{
+		Credentials: &types.StackCredentials{
+			Credentials: types.Credentials{KeystorePassword: "rotated"},
+			PerMember:   true,
+		},
+	}
+	rewritten, err := ReencryptKeystore(stack, dir, oldStack.KeystorePassword)
+	assert.NoError(T, err)
+	assert.Equal(T, []string{walletFilePath}, rewritten)
+
+	newPassword := stack.KeystorePassword(address)
+	assert.NotEqual(T, password, newPassword)
+	b, err := ioutil.ReadFile(walletFilePath)
+	assert.NoError(T, err)
+	wallet, err := keystorev3.ReadWalletFile(b, []byte(newPassword))
+	assert.NoError(T, err)
+	assert.Equal(T, address, wallet.KeyPair().Address.String())
+
+	// Completing a rotation that failed part way skips the files that were already re-encrypted
+	rewritten, err = ReencryptKeystore(stack, dir, oldStack.KeystorePassword)
+	assert.NoError(T, err)
+	assert.Equal(T, []string{walletFilePath}, rewritten)
+	assert.Equal(T, newPassword, stack.KeystorePassword(address))
+}
+
+func TestDefaultKeystorePasswordForOldStacks(T *testing.T) {
+	stack := &types.Stack{State: &types.StackState{}}
+	assert.Equal(T, types.DefaultKeystorePassword, stack.KeystorePassword("0x1234"))
+	assert.Equal(T, types.DefaultDatabasePassword, stack.DatabasePassword("0"))
+}
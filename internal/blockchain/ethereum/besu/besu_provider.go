@@ -34,8 +34,6 @@ import (
 	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
-var besuImage = "hyperledger/besu:22.4"
-
 type BesuProvider struct {
 	ctx       context.Context
 	stack     *types.Stack
@@ -47,9 +45,9 @@ func NewBesuProvider(ctx context.Context, stack *types.Stack) *BesuProvider {
 	var connector connector.Connector
 	switch stack.BlockchainConnector {
 	case types.BlockchainConnectorEthconnect:
-		connector = ethconnect.NewEthconnect(ctx)
+		connector = ethconnect.NewEthconnect(ctx, stack)
 	case types.BlockchainConnectorEvmconnect:
-		connector = evmconnect.NewEvmconnect(ctx)
+		connector = evmconnect.NewEvmconnect(ctx, stack)
 	}
 
 	return &BesuProvider{
@@ -61,16 +59,16 @@ func NewBesuProvider(ctx context.Context, stack *types.Stack) *BesuProvider {
 }
 
 func (p *BesuProvider) WriteConfig(options *types.InitOptions) error {
-	if err := p.signer.WriteConfig(options, "http://besu:8545"); err != nil {
+	if err := p.signer.WriteConfig(options, fmt.Sprintf("http://%s:8545", p.stack.BlockchainServiceName("besu"))); err != nil {
 		return err
 	}
 
 	initDir := filepath.Join(constants.StacksDir, p.stack.Name, "init")
-	for i, member := range p.stack.Members {
+	for _, member := range p.stack.Members {
 
 		// Generate the connector config for each member
-		connectorConfigPath := filepath.Join(initDir, "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		if err := p.connector.GenerateConfig(p.stack, member, "ethsigner").WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
+		connectorConfigPath := filepath.Join(initDir, "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		if err := p.connector.GenerateConfig(p.stack, member, p.stack.BlockchainServiceName("ethsigner")).WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
 			return nil
 		}
 
@@ -80,12 +78,12 @@ func (p *BesuProvider) WriteConfig(options *types.InitOptions) error {
 	// Generate node key
 	nodeAddress, nodeKey := ethereum.GenerateAddressAndPrivateKey()
 	// Write the node key to disk
-	if err := ioutil.WriteFile(filepath.Join(initDir, "blockchain", "nodeKey"), []byte(nodeKey), 0755); err != nil {
+	if err := ioutil.WriteFile(filepath.Join(initDir, p.stack.BlockchainDirName(), "nodeKey"), []byte(nodeKey), 0755); err != nil {
 		return err
 	}
 	// Drop the 0x on the front of the address here because that's what is expected in the genesis.json
 	genesis := CreateGenesis([]string{nodeAddress[2:]}, options.BlockPeriod, p.stack.ChainID())
-	if err := genesis.WriteGenesisJson(filepath.Join(initDir, "blockchain", "genesis.json")); err != nil {
+	if err := genesis.WriteGenesisJson(filepath.Join(initDir, p.stack.BlockchainDirName(), "genesis.json")); err != nil {
 		return err
 	}
 
@@ -93,8 +91,8 @@ func (p *BesuProvider) WriteConfig(options *types.InitOptions) error {
 }
 
 func (p *BesuProvider) FirstTimeSetup() error {
-	besuVolumeName := fmt.Sprintf("%s_besu", p.stack.Name)
-	blockchainDir := filepath.Join(p.stack.RuntimeDir, "blockchain")
+	besuVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("besu"))
+	blockchainDir := filepath.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
 	contractsDir := filepath.Join(p.stack.RuntimeDir, "contracts")
 
 	if err := p.signer.FirstTimeSetup(); err != nil {
@@ -109,10 +107,10 @@ func (p *BesuProvider) FirstTimeSetup() error {
 		return err
 	}
 
-	for i := range p.stack.Members {
+	for _, member := range p.stack.Members {
 		// Copy connector config to each member's volume
-		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		connectorConfigVolumeName := fmt.Sprintf("%s_%s_config_%v", p.stack.Name, p.connector.Name(), i)
+		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		connectorConfigVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainMemberServiceName(p.connector.Name()+"_config", member.ID))
 		docker.CopyFileToVolume(p.ctx, connectorConfigVolumeName, connectorConfigPath, "config.yaml")
 	}
 
@@ -156,34 +154,46 @@ func (p *BesuProvider) GetDockerServiceDefinitions() []*docker.ServiceDefinition
 	}
 	besuCommand := fmt.Sprintf(`--genesis-file=/data/genesis.json --network-id %d --rpc-http-enabled --rpc-http-api=ETH,NET,CLIQUE --host-allowlist="*" --rpc-http-cors-origins="all" --sync-mode=FULL --discovery-enabled=false --node-private-key-file=/data/nodeKey --min-gas-price=0`, p.stack.ChainID())
 
+	// Besu exports its metrics either to Prometheus or over OpenTelemetry, which also gets it to export traces
+	var besuEnvironment map[string]interface{}
+	if p.stack.PrometheusEnabled {
+		besuCommand += " --metrics-enabled --metrics-host=0.0.0.0 --metrics-port=9545"
+	} else if p.stack.TracingEnabled {
+		besuCommand += " --metrics-enabled --metrics-protocol=opentelemetry"
+		besuEnvironment = map[string]interface{}{
+			"OTEL_SERVICE_NAME":           p.stack.BlockchainServiceName("besu"),
+			"OTEL_RESOURCE_ATTRIBUTES":    fmt.Sprintf("firefly.stack=%s", p.stack.Name),
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://otel_collector:4317",
+		}
+	}
+
 	serviceDefinitions := make([]*docker.ServiceDefinition, 2)
 	serviceDefinitions[0] = &docker.ServiceDefinition{
-		ServiceName: "besu",
+		ServiceName: p.stack.BlockchainServiceName("besu"),
 		Service: &docker.Service{
-			Image:         besuImage,
-			ContainerName: fmt.Sprintf("%s_besu", p.stack.Name),
+			Image:         p.stack.VersionManifest.Besu.GetDockerImageString(),
+			ContainerName: fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("besu")),
 			User:          "root",
 			Command:       besuCommand,
+			Environment:   besuEnvironment,
 			Volumes: []string{
-				"besu:/data",
+				p.stack.BlockchainServiceName("besu") + ":/data",
 			},
 			Logging: docker.StandardLogOptions,
 		},
 
-		VolumeNames: []string{"besu"},
+		VolumeNames: []string{p.stack.BlockchainServiceName("besu")},
 	}
-	serviceDefinitions[1] = p.signer.GetDockerServiceDefinition("http://besu:8545")
-	serviceDefinitions = append(serviceDefinitions, p.connector.GetServiceDefinitions(p.stack, map[string]string{"ethsigner": "service_healthy"})...)
+	if p.stack.PrometheusEnabled {
+		serviceDefinitions[0].Metrics = &docker.MetricsEndpoint{Job: "besu", Port: 9545, Path: "/metrics"}
+	}
+	serviceDefinitions[1] = p.signer.GetDockerServiceDefinition(fmt.Sprintf("http://%s:8545", p.stack.BlockchainServiceName("besu")))
+	serviceDefinitions = append(serviceDefinitions, p.connector.GetServiceDefinitions(p.stack, map[string]string{p.stack.BlockchainServiceName("ethsigner"): "service_healthy"})...)
 	return serviceDefinitions
 }
 
 func (p *BesuProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Organization) (blockchainConfig *types.BlockchainConfig) {
-	var connectorURL string
-	if m.External {
-		connectorURL = p.GetConnectorExternalURL(m)
-	} else {
-		connectorURL = p.GetConnectorURL(m)
-	}
+	connectorURL := m.ComponentURL(types.ComponentCore, types.ComponentConnector, p.GetConnectorURL(m), p.GetConnectorExternalURL(m))
 
 	blockchainConfig = &types.BlockchainConfig{
 		Type: "ethereum",
@@ -191,6 +201,8 @@ func (p *BesuProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Or
 			Ethconnect: &types.EthconnectConfig{
 				URL:   connectorURL,
 				Topic: m.ID,
+				Auth:  stack.ConnectorCredentials(m.ID),
+				TLS:   stack.ClientTLSConfig("firefly_core_"+m.ID, m.External),
 			},
 		},
 	}
@@ -236,6 +248,10 @@ func (p *BesuProvider) CreateAccount(args []string) (interface{}, error) {
 	return p.signer.CreateAccount(args)
 }
 
+func (p *BesuProvider) RotateKeystorePasswords(oldPassword func(address string) string) error {
+	return p.signer.RotateKeystorePasswords(oldPassword)
+}
+
 func (p *BesuProvider) ParseAccount(account interface{}) interface{} {
 	accountMap := account.(map[string]interface{})
 	return &ethereum.Account{
@@ -249,9 +265,9 @@ func (p *BesuProvider) GetConnectorName() string {
 }
 
 func (p *BesuProvider) GetConnectorURL(org *types.Organization) string {
-	return fmt.Sprintf("http://%s_%s:%v", p.connector.Name(), org.ID, p.connector.Port())
+	return fmt.Sprintf("%s://%s:%v", p.stack.Scheme(), p.stack.BlockchainMemberServiceName(p.connector.Name(), org.ID), p.connector.Port())
 }
 
 func (p *BesuProvider) GetConnectorExternalURL(org *types.Organization) string {
-	return fmt.Sprintf("http://127.0.0.1:%v", org.ExposedConnectorPort)
+	return fmt.Sprintf("%s://127.0.0.1:%v", p.stack.Scheme(), org.ExposedConnectorPort)
 }
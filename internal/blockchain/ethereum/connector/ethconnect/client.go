@@ -32,7 +32,8 @@ import (
 )
 
 type Ethconnect struct {
-	ctx context.Context
+	ctx   context.Context
+	stack *types.Stack
 }
 
 type PublishAbiResponseBody struct {
@@ -88,9 +89,10 @@ type EthconnectReplyHeaders struct {
 	Type          string  `json:"type,omitempty"`
 }
 
-func NewEthconnect(ctx context.Context) *Ethconnect {
+func NewEthconnect(ctx context.Context, stack *types.Stack) *Ethconnect {
 	return &Ethconnect{
-		ctx: ctx,
+		ctx:   ctx,
+		stack: stack,
 	}
 }
 
@@ -103,7 +105,7 @@ func (e *Ethconnect) Port() int {
 }
 
 func (e *Ethconnect) DeployContract(contract *ethtypes.CompiledContract, contractName string, member *types.Organization, extraArgs []string) (*types.ContractDeploymentResult, error) {
-	ethconnectUrl := fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort)
+	ethconnectUrl := fmt.Sprintf("%s://127.0.0.1:%v", e.stack.Scheme(), member.ExposedConnectorPort)
 	address := member.Account.(*ethereum.Account).Address
 	hexBytecode, err := hex.DecodeString(strings.TrimPrefix(contract.Bytecode, "0x"))
 	if err != nil {
@@ -45,6 +45,15 @@ type RestGateway struct {
 
 type RPC struct {
 	URL string `yaml:"url,omitempty"`
+	TLS *TLS   `yaml:"tls,omitempty"`
+}
+
+// TLS uses ethconnect's field names, where the server certificate and key are set as the "client" files
+type TLS struct {
+	Enabled         bool   `yaml:"enabled,omitempty"`
+	ClientCertsFile string `yaml:"clientCertsFile,omitempty"`
+	ClientKeyFile   string `yaml:"clientKeyFile,omitempty"`
+	CACertsFile     string `yaml:"caCertsFile,omitempty"`
 }
 
 type OpenAPI struct {
@@ -54,7 +63,8 @@ type OpenAPI struct {
 }
 
 type HTTP struct {
-	Port int `yaml:"port,omitempty"`
+	Port int  `yaml:"port,omitempty"`
+	TLS  *TLS `yaml:"tls,omitempty"`
 }
 
 func (e *Config) WriteConfig(filename string, extraConnectorConfigPath string) error {
@@ -79,19 +89,52 @@ func (e *Config) WriteConfig(filename string, extraConnectorConfigPath string) e
 }
 
 func (e *Ethconnect) GenerateConfig(stack *types.Stack, member *types.Organization, blockchainServiceName string) connector.Config {
+	// An external connector runs on the host, where it listens on its exposed port and keeps its data in the runtime directory
+	external := member.IsExternal(types.ComponentConnector)
+	serviceName := stack.BlockchainMemberServiceName("ethconnect", member.ID)
+	tlsDir := types.TLSContainerDir
+	port := 8080
+	rpcHost := blockchainServiceName + ":8545"
+	storagePath, eventsDB := "./abis", "./events"
+	if external {
+		tlsDir = stack.TLSConfigDir(serviceName)
+		port = member.ExposedConnectorPort
+		rpcHost = fmt.Sprintf("127.0.0.1:%v", stack.ExposedBlockchainPort)
+		storagePath = filepath.Join(stack.RuntimeDir, serviceName, "abis")
+		eventsDB = filepath.Join(stack.RuntimeDir, serviceName, "events")
+	}
+
+	var httpTLS, rpcTLS *TLS
+	rpcScheme := "http"
+	if stack.TLSEnabled {
+		httpTLS = &TLS{
+			Enabled:         true,
+			ClientCertsFile: filepath.Join(tlsDir, "cert.pem"),
+			ClientKeyFile:   filepath.Join(tlsDir, "key.pem"),
+		}
+		// The signer serves TLS when it is enabled, but blockchain nodes are always called over plain HTTP
+		if blockchainServiceName == stack.BlockchainServiceName("ethsigner") {
+			rpcScheme = "https"
+			rpcTLS = &TLS{
+				Enabled:     true,
+				CACertsFile: filepath.Join(tlsDir, "ca.pem"),
+			}
+		}
+	}
 	return &Config{
 		Rest: &Rest{
 			RestGateway: &RestGateway{
 				MaxTXWaitTime: 60,
 				MaxInFlight:   10,
-				RPC:           &RPC{URL: fmt.Sprintf("http://%s:8545", blockchainServiceName)},
+				RPC:           &RPC{URL: fmt.Sprintf("%s://%s", rpcScheme, rpcHost), TLS: rpcTLS},
 				OpenAPI: &OpenAPI{
 					EventPollingIntervalSec: 1,
-					StoragePath:             "./abis",
-					EventsDB:                "./events",
+					StoragePath:             storagePath,
+					EventsDB:                eventsDB,
 				},
 				HTTP: &HTTP{
-					Port: 8080,
+					Port: port,
+					TLS:  httpTLS,
 				},
 			},
 		},
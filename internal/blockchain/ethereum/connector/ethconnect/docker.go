@@ -31,26 +31,31 @@ func (e *Ethconnect) GetServiceDefinitions(s *types.Stack, dependentServices map
 	serviceDefinitions := make([]*docker.ServiceDefinition, len(s.Members))
 	for i, member := range s.Members {
 		serviceDefinitions[i] = &docker.ServiceDefinition{
-			ServiceName: "ethconnect_" + member.ID,
+			ServiceName: s.BlockchainMemberServiceName("ethconnect", member.ID),
 			Service: &docker.Service{
 				Image:         s.VersionManifest.Ethconnect.GetDockerImageString(),
-				ContainerName: fmt.Sprintf("%s_ethconnect_%v", s.Name, i),
+				ContainerName: fmt.Sprintf("%s_%s_%v", s.Name, s.BlockchainServiceName("ethconnect"), i),
 				Command:       "server -f ./config/config.yaml -d 2",
 				DependsOn:     dependsOn,
 				Ports:         []string{fmt.Sprintf("%d:8080", member.ExposedConnectorPort)},
 				Volumes: []string{
-					fmt.Sprintf("ethconnect_config_%s:/ethconnect/config", member.ID),
-					fmt.Sprintf("ethconnect_abis_%s:/ethconnect/abis", member.ID),
-					fmt.Sprintf("ethconnect_events_%s:/ethconnect/events", member.ID),
+					s.BlockchainMemberServiceName("ethconnect_config", member.ID) + ":/ethconnect/config",
+					s.BlockchainMemberServiceName("ethconnect_abis", member.ID) + ":/ethconnect/abis",
+					s.BlockchainMemberServiceName("ethconnect_events", member.ID) + ":/ethconnect/events",
 				},
 				Logging: docker.StandardLogOptions,
 			},
 			VolumeNames: []string{
-				fmt.Sprintf("ethconnect_config_%v", member.ID),
-				fmt.Sprintf("ethconnect_abis_%v", member.ID),
-				fmt.Sprintf("ethconnect_events_%v", member.ID),
+				s.BlockchainMemberServiceName("ethconnect_config", member.ID),
+				s.BlockchainMemberServiceName("ethconnect_abis", member.ID),
+				s.BlockchainMemberServiceName("ethconnect_events", member.ID),
 			},
 		}
 	}
+	if s.TLSEnabled {
+		for _, serviceDefinition := range serviceDefinitions {
+			serviceDefinition.Service.Volumes = append(serviceDefinition.Service.Volumes, s.TLSVolume(serviceDefinition.ServiceName))
+		}
+	}
 	return serviceDefinitions
 }
@@ -57,12 +57,14 @@ type ExtraInfo struct {
 }
 
 type Evmconnect struct {
-	ctx context.Context
+	ctx   context.Context
+	stack *types.Stack
 }
 
-func NewEvmconnect(ctx context.Context) *Evmconnect {
+func NewEvmconnect(ctx context.Context, stack *types.Stack) *Evmconnect {
 	return &Evmconnect{
-		ctx: ctx,
+		ctx:   ctx,
+		stack: stack,
 	}
 }
 
@@ -75,7 +77,7 @@ func (e *Evmconnect) Port() int {
 }
 
 func (e *Evmconnect) DeployContract(contract *ethtypes.CompiledContract, contractName string, member *types.Organization, extraArgs []string) (*types.ContractDeploymentResult, error) {
-	evmconnectURL := fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort)
+	evmconnectURL := fmt.Sprintf("%s://127.0.0.1:%v", e.stack.Scheme(), member.ExposedConnectorPort)
 	fromAddress := member.Account.(*ethereum.Account).Address
 
 	params := make([]interface{}, len(extraArgs))
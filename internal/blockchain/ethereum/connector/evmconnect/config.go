@@ -39,13 +39,16 @@ type Config struct {
 }
 
 type APIConfig struct {
-	Port      int    `yaml:"port,omitempty"`
-	Address   string `yaml:"address,omitempty"`
-	PublicURL string `yaml:"publicURL,omitempty"`
+	Port      int               `yaml:"port,omitempty"`
+	Address   string            `yaml:"address,omitempty"`
+	PublicURL string            `yaml:"publicURL,omitempty"`
+	TLS       *types.TLSConfig  `yaml:"tls,omitempty"`
+	Auth      *types.AuthConfig `yaml:"auth,omitempty"`
 }
 
 type ConnectorConfig struct {
-	URL string `yaml:"url,omitempty"`
+	URL string           `yaml:"url,omitempty"`
+	TLS *types.TLSConfig `yaml:"tls,omitempty"`
 }
 
 type PersistenceConfig struct {
@@ -57,8 +60,10 @@ type LevelDBConfig struct {
 }
 
 type FFCoreConfig struct {
-	URL        string   `yaml:"url,omitempty"`
-	Namespaces []string `yaml:"namespaces,omitempty"`
+	URL        string           `yaml:"url,omitempty"`
+	Namespaces []string         `yaml:"namespaces,omitempty"`
+	Auth       *types.BasicAuth `yaml:"auth,omitempty"`
+	TLS        *types.TLSConfig `yaml:"tls,omitempty"`
 }
 
 type ConfirmationsConfig struct {
@@ -116,26 +121,51 @@ func (e *Evmconnect) GenerateConfig(stack *types.Stack, org *types.Organization,
 		metrics = nil
 	}
 
+	// The signer serves TLS when it is enabled, but blockchain nodes are always called over plain HTTP
+	serviceName := stack.BlockchainMemberServiceName("evmconnect", org.ID)
+	external := org.IsExternal(types.ComponentConnector)
+	blockchainScheme := "http"
+	var blockchainTLS *types.TLSConfig
+	if blockchainServiceName == stack.BlockchainServiceName("ethsigner") {
+		blockchainScheme = stack.Scheme()
+		blockchainTLS = stack.ClientTLSConfig(serviceName, external)
+	}
+
+	// An external connector runs on the host, where it listens on its exposed port and keeps its data in the runtime directory
+	port := e.Port()
+	blockchainURL := fmt.Sprintf("%s://%s:8545", blockchainScheme, blockchainServiceName)
+	levelDBPath := "/evmconnect/leveldb"
+	if external {
+		port = org.ExposedConnectorPort
+		blockchainURL = fmt.Sprintf("%s://127.0.0.1:%v", blockchainScheme, stack.ExposedBlockchainPort)
+		levelDBPath = filepath.Join(stack.RuntimeDir, serviceName, "leveldb")
+	}
+
 	return &Config{
 		Log: &types.LogConfig{
 			Level: "debug",
 		},
 		API: &APIConfig{
-			Port:      e.Port(),
+			Port:      port,
 			Address:   "0.0.0.0",
-			PublicURL: fmt.Sprintf("http://127.0.0.1:%v", org.ExposedConnectorPort),
+			PublicURL: fmt.Sprintf("%s://127.0.0.1:%v", stack.Scheme(), org.ExposedConnectorPort),
+			TLS:       stack.ServerTLSConfig(serviceName, external),
+			Auth:      stack.ServerAuthConfig(org.ID, external),
 		},
 		Connector: &ConnectorConfig{
-			URL: fmt.Sprintf("http://%s:8545", blockchainServiceName),
+			URL: blockchainURL,
+			TLS: blockchainTLS,
 		},
 		Persistence: &PersistenceConfig{
 			LevelDB: &LevelDBConfig{
-				Path: "/evmconnect/leveldb",
+				Path: levelDBPath,
 			},
 		},
 		FFCore: &FFCoreConfig{
-			URL:        getCoreURL(org),
+			URL:        getCoreURL(stack, org),
 			Namespaces: []string{"default"},
+			Auth:       stack.APICredentials(org.ID),
+			TLS:        stack.ClientTLSConfig(serviceName, external),
 		},
 		Metrics: metrics,
 		Confirmations: &ConfirmationsConfig{
@@ -150,10 +180,8 @@ func (e *Evmconnect) GenerateConfig(stack *types.Stack, org *types.Organization,
 	}
 }
 
-func getCoreURL(org *types.Organization) string {
-	host := fmt.Sprintf("firefly_core_%v", org.ID)
-	if org.External {
-		host = "host.docker.internal"
-	}
-	return fmt.Sprintf("http://%s:%v", host, org.ExposedFireflyPort)
+func getCoreURL(stack *types.Stack, org *types.Organization) string {
+	return org.ComponentURL(types.ComponentConnector, types.ComponentCore,
+		fmt.Sprintf("%s://firefly_core_%v:%v", stack.Scheme(), org.ID, org.ExposedFireflyPort),
+		fmt.Sprintf("%s://127.0.0.1:%v", stack.Scheme(), org.ExposedFireflyPort))
 }
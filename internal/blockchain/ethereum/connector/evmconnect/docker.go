@@ -31,24 +31,39 @@ func (e *Evmconnect) GetServiceDefinitions(s *types.Stack, dependentServices map
 	serviceDefinitions := make([]*docker.ServiceDefinition, len(s.Members))
 	for i, member := range s.Members {
 		serviceDefinitions[i] = &docker.ServiceDefinition{
-			ServiceName: "evmconnect_" + member.ID,
+			ServiceName: s.BlockchainMemberServiceName("evmconnect", member.ID),
 			Service: &docker.Service{
 				Image:         s.VersionManifest.Evmconnect.GetDockerImageString(),
-				ContainerName: fmt.Sprintf("%s_evmconnect_%v", s.Name, i),
+				ContainerName: fmt.Sprintf("%s_%s_%v", s.Name, s.BlockchainServiceName("evmconnect"), i),
 				Command:       "-f /evmconnect/config/config.yaml",
 				DependsOn:     dependsOn,
 				Ports:         []string{fmt.Sprintf("%d:%v", member.ExposedConnectorPort, e.Port())},
 				Volumes: []string{
-					fmt.Sprintf("evmconnect_config_%s:/evmconnect/config", member.ID),
-					fmt.Sprintf("evmconnect_leveldb_%s:/evmconnect/leveldb", member.ID),
+					s.BlockchainMemberServiceName("evmconnect_config", member.ID) + ":/evmconnect/config",
+					s.BlockchainMemberServiceName("evmconnect_leveldb", member.ID) + ":/evmconnect/leveldb",
 				},
 				Logging: docker.StandardLogOptions,
 			},
 			VolumeNames: []string{
-				fmt.Sprintf("evmconnect_config_%s", member.ID),
-				fmt.Sprintf("evmconnect_leveldb_%s", member.ID),
+				s.BlockchainMemberServiceName("evmconnect_config", member.ID),
+				s.BlockchainMemberServiceName("evmconnect_leveldb", member.ID),
 			},
 		}
 	}
+	if s.PrometheusEnabled {
+		for i, serviceDefinition := range serviceDefinitions {
+			serviceDefinition.Metrics = &docker.MetricsEndpoint{Job: "evmconnect", Port: s.Members[i].ExposedConnectorMetricsPort, Path: "/metrics"}
+		}
+	}
+	if s.TLSEnabled {
+		for _, serviceDefinition := range serviceDefinitions {
+			serviceDefinition.Service.Volumes = append(serviceDefinition.Service.Volumes, s.TLSVolume(serviceDefinition.ServiceName))
+		}
+	}
+	if s.BasicAuthEnabled {
+		for i, serviceDefinition := range serviceDefinitions {
+			serviceDefinition.Service.Volumes = append(serviceDefinition.Service.Volumes, s.BasicAuthVolume(s.Members[i].ID))
+		}
+	}
 	return serviceDefinitions
 }
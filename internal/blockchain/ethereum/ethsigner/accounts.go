@@ -25,6 +25,20 @@ import (
 	"github.com/hyperledger/firefly-cli/internal/docker"
 )
 
+// passwordFilePath returns the path in the signer's volume of the password file for a key. With per-member
+// credentials each key has its own password file in the keystore, otherwise they all share the stack's password
+func (p *EthSignerProvider) passwordFilePath(walletFilePath string) string {
+	if p.stack.PerMemberCredentials() {
+		return fmt.Sprintf("/data/keystore/%s.password", filepath.Base(walletFilePath))
+	}
+	return "/data/password"
+}
+
+func (p *EthSignerProvider) writePasswordFile(walletFilePath, password string) (string, error) {
+	filename := fmt.Sprintf("%s.password", walletFilePath)
+	return filename, ioutil.WriteFile(filename, []byte(password), 0644)
+}
+
 func (p *EthSignerProvider) writeTomlKeyFile(walletFilePath string) (string, error) {
 	outputDirectory := filepath.Dir(walletFilePath)
 	keyFile := filepath.Base(walletFilePath)
@@ -35,17 +49,17 @@ description = "File based configuration"
 [signing]
 type = "file-based-signer"
 key-file = "/data/keystore/%s"
-password-file = "/data/password"
-`, keyFile)
+password-file = "%s"
+`, keyFile, p.passwordFilePath(walletFilePath))
 	filename := filepath.Join(outputDirectory, fmt.Sprintf("%s.toml", keyFile))
 	return filename, ioutil.WriteFile(filename, []byte(toml), 0755)
 }
 
-func (p *EthSignerProvider) copyTomlFileToVolume(ctx context.Context, tomlFilePath, volumeName string) error {
+func (p *EthSignerProvider) copyKeystoreFileToVolume(ctx context.Context, filePath, volumeName string) error {
 	if err := docker.MkdirInVolume(ctx, volumeName, "/keystore"); err != nil {
 		return err
 	}
-	if err := docker.CopyFileToVolume(ctx, volumeName, tomlFilePath, "/keystore"); err != nil {
+	if err := docker.CopyFileToVolume(ctx, volumeName, filePath, "/keystore"); err != nil {
 		return err
 	}
 	return nil
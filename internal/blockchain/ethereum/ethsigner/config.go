@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 
+	"github.com/hyperledger/firefly-cli/pkg/types"
 	"gopkg.in/yaml.v2"
 )
 
@@ -43,8 +44,9 @@ type FileWalletConfig struct {
 }
 
 type ServerConfig struct {
-	Port    int    `yaml:"port,omitempty"`
-	Address string `yaml:"address,omitempty"`
+	Port    int              `yaml:"port,omitempty"`
+	Address string           `yaml:"address,omitempty"`
+	TLS     *types.TLSConfig `yaml:"tls,omitempty"`
 }
 
 type BackendConfig struct {
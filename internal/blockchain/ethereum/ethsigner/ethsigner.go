@@ -33,9 +33,6 @@ import (
 	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
-// TODO: Probably randomize this and make it different per member?
-var keyPassword = "correcthorsebatterystaple"
-
 const useJavaSigner = false // also need to change the image appropriately if you recompile to use the Java signer
 
 type EthSignerProvider struct {
@@ -54,16 +51,18 @@ func (p *EthSignerProvider) WriteConfig(options *types.InitOptions, rpcURL strin
 
 	// Write the password that will be used to encrypt the private key
 	initDir := filepath.Join(constants.StacksDir, p.stack.Name, "init")
-	blockchainDirectory := filepath.Join(initDir, "blockchain")
+	blockchainDirectory := filepath.Join(initDir, p.stack.BlockchainDirName())
 	if err := os.MkdirAll(blockchainDirectory, 0755); err != nil {
 		return err
 	}
-	if err := ioutil.WriteFile(filepath.Join(initDir, "blockchain", "password"), []byte(keyPassword), 0755); err != nil {
+	if err := ioutil.WriteFile(filepath.Join(initDir, p.stack.BlockchainDirName(), "password"), []byte(p.stack.KeystorePassword("")), 0644); err != nil {
 		return err
 	}
 
-	signerConfigPath := filepath.Join(initDir, "config", "ethsigner.yaml")
-	if err := GenerateSignerConfig(options.ChainID, rpcURL).WriteConfig(signerConfigPath); err != nil {
+	signerConfigPath := filepath.Join(initDir, "config", p.stack.BlockchainServiceName("ethsigner")+".yaml")
+	signerConfig := GenerateSignerConfig(options.ChainID, rpcURL)
+	signerConfig.Server.TLS = p.stack.ServerTLSConfig(p.stack.BlockchainServiceName("ethsigner"), false)
+	if err := signerConfig.WriteConfig(signerConfigPath); err != nil {
 		return nil
 	}
 
@@ -71,8 +70,8 @@ func (p *EthSignerProvider) WriteConfig(options *types.InitOptions, rpcURL strin
 }
 
 func (p *EthSignerProvider) FirstTimeSetup() error {
-	ethsignerVolumeName := fmt.Sprintf("%s_ethsigner", p.stack.Name)
-	blockchainDir := filepath.Join(p.stack.RuntimeDir, "blockchain")
+	ethsignerVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("ethsigner"))
+	blockchainDir := filepath.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
 	contractsDir := filepath.Join(p.stack.RuntimeDir, "contracts")
 
 	if err := docker.CreateVolume(p.ctx, ethsignerVolumeName); err != nil {
@@ -84,8 +83,8 @@ func (p *EthSignerProvider) FirstTimeSetup() error {
 	}
 
 	// Copy the signer config to the volume
-	signerConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", "ethsigner.yaml")
-	signerConfigVolumeName := fmt.Sprintf("%s_ethsigner_config", p.stack.Name)
+	signerConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", p.stack.BlockchainServiceName("ethsigner")+".yaml")
+	signerConfigVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("ethsigner_config"))
 	docker.CopyFileToVolume(p.ctx, signerConfigVolumeName, signerConfigPath, "firefly.ffsigner")
 
 	// Copy the wallet files all members to the blockchain volume
@@ -139,44 +138,56 @@ func (p *EthSignerProvider) GetDockerServiceDefinition(rpcURL string) *docker.Se
 		}
 	}
 
+	volumes := []string{
+		p.stack.BlockchainServiceName("ethsigner") + ":/data",
+		p.stack.BlockchainServiceName("ethsigner_config") + ":/etc/firefly",
+	}
+	healthCheckURL := "http://localhost:8545/"
+	healthCheckTLS := []string{}
+	if p.stack.TLSEnabled {
+		volumes = append(volumes, p.stack.TLSVolume(p.stack.BlockchainServiceName("ethsigner")))
+		healthCheckURL = "https://localhost:8545/"
+		healthCheckTLS = []string{"--cacert", path.Join(types.TLSContainerDir, "ca.pem")}
+	}
+
+	healthCheck := []string{
+		"CMD",
+		"curl",
+		"-X", "POST",
+		"-H", "Content-Type: application/json",
+		"-d", `{"jsonrpc":"2.0","method":"net_version","params":[],"id":"1"}`,
+		"-w", "%{http_code}",
+		"-sS",
+		"--fail",
+	}
+	healthCheck = append(healthCheck, healthCheckTLS...)
+	healthCheck = append(healthCheck, healthCheckURL)
+
 	return &docker.ServiceDefinition{
-		ServiceName: "ethsigner",
+		ServiceName: p.stack.BlockchainServiceName("ethsigner"),
 		Service: &docker.Service{
 			Image:         p.stack.VersionManifest.Signer.GetDockerImageString(),
-			ContainerName: fmt.Sprintf("%s_ethsigner", p.stack.Name),
+			ContainerName: fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("ethsigner")),
 			User:          "root",
 			Command:       p.getCommand(rpcURL),
-			Volumes: []string{
-				"ethsigner:/data",
-				"ethsigner_config:/etc/firefly",
-			},
-			Logging: docker.StandardLogOptions,
+			Volumes:       volumes,
+			Logging:       docker.StandardLogOptions,
 			HealthCheck: &docker.HealthCheck{
-				Test: []string{
-					"CMD",
-					"curl",
-					"-X", "POST",
-					"-H", "Content-Type: application/json",
-					"-d", `{"jsonrpc":"2.0","method":"net_version","params":[],"id":"1"}`,
-					"-w", "%{http_code}",
-					"-sS",
-					"--fail",
-					"http://localhost:8545/",
-				},
+				Test:     healthCheck,
 				Interval: "15s", // 6000 requests in a day
 				Retries:  60,
 			},
 			Ports: []string{fmt.Sprintf("%d:8545", p.stack.ExposedBlockchainPort)},
 		},
 		VolumeNames: []string{
-			"ethsigner",
-			"ethsigner_config",
+			p.stack.BlockchainServiceName("ethsigner"),
+			p.stack.BlockchainServiceName("ethsigner_config"),
 		},
 	}
 }
 
 func (p *EthSignerProvider) CreateAccount(args []string) (interface{}, error) {
-	ethsignerVolumeName := fmt.Sprintf("%s_ethsigner", p.stack.Name)
+	ethsignerVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("ethsigner"))
 	var directory string
 	stackHasRunBefore, err := p.stack.HasRunBefore()
 	if err != nil {
@@ -188,8 +199,8 @@ func (p *EthSignerProvider) CreateAccount(args []string) (interface{}, error) {
 		directory = p.stack.InitDir
 	}
 
-	outputDirectory := filepath.Join(directory, "blockchain", "keystore")
-	keyPair, walletFilePath, err := ethereum.CreateWalletFile(outputDirectory, "", keyPassword)
+	outputDirectory := filepath.Join(directory, p.stack.BlockchainDirName(), "keystore")
+	keyPair, walletFilePath, password, err := ethereum.CreateStackWalletFile(p.stack, outputDirectory, "")
 	if err != nil {
 		return nil, err
 	}
@@ -198,16 +209,26 @@ func (p *EthSignerProvider) CreateAccount(args []string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	keystoreFiles := []string{tomlFilePath}
 
-	if stackHasRunBefore {
-		if err := ethereum.CopyWalletFileToVolume(p.ctx, walletFilePath, ethsignerVolumeName); err != nil {
+	if p.stack.PerMemberCredentials() {
+		passwordFilePath, err := p.writePasswordFile(walletFilePath, password)
+		if err != nil {
 			return nil, err
 		}
+		keystoreFiles = append(keystoreFiles, passwordFilePath)
+	}
 
-		if err := p.copyTomlFileToVolume(p.ctx, tomlFilePath, ethsignerVolumeName); err != nil {
+	if stackHasRunBefore {
+		if err := ethereum.CopyWalletFileToVolume(p.ctx, walletFilePath, ethsignerVolumeName); err != nil {
 			return nil, err
 		}
 
+		for _, filePath := range keystoreFiles {
+			if err := p.copyKeystoreFileToVolume(p.ctx, filePath, ethsignerVolumeName); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return &ethereum.Account{
@@ -215,3 +236,35 @@ func (p *EthSignerProvider) CreateAccount(args []string) (interface{}, error) {
 		PrivateKey: hex.EncodeToString(keyPair.PrivateKey.Serialize()),
 	}, nil
 }
+
+func (p *EthSignerProvider) RotateKeystorePasswords(oldPassword func(address string) string) error {
+	ethsignerVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("ethsigner"))
+	blockchainDir := filepath.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
+	keystoreDir := filepath.Join(blockchainDir, "keystore")
+
+	walletFilePaths, err := ethereum.ReencryptKeystore(p.stack, keystoreDir, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(blockchainDir, "password"), []byte(p.stack.KeystorePassword("")), 0644); err != nil {
+		return err
+	}
+	for _, walletFilePath := range walletFilePaths {
+		if p.stack.PerMemberCredentials() {
+			address := "0x" + filepath.Base(walletFilePath)
+			if _, err := p.writePasswordFile(walletFilePath, p.stack.KeystorePassword(address)); err != nil {
+				return err
+			}
+		}
+		if _, err := p.writeTomlKeyFile(walletFilePath); err != nil {
+			return err
+		}
+	}
+
+	// Replace the keystore and password in the signer's volume
+	if err := docker.CopyFileToVolume(p.ctx, ethsignerVolumeName, keystoreDir, "/"); err != nil {
+		return err
+	}
+	return docker.CopyFileToVolume(p.ctx, ethsignerVolumeName, filepath.Join(blockchainDir, "password"), "password")
+}
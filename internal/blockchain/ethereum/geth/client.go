@@ -18,13 +18,17 @@ package geth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
 )
 
 type GethClient struct {
+	ctx    context.Context
 	rpcUrl string
 }
 
@@ -47,8 +51,9 @@ type JSONRPCError struct {
 	Message string `json:"message"`
 }
 
-func NewGethClient(rpcUrl string) *GethClient {
+func NewGethClient(ctx context.Context, rpcUrl string) *GethClient {
 	return &GethClient{
+		ctx:    ctx,
 		rpcUrl: rpcUrl,
 	}
 }
@@ -63,13 +68,12 @@ func (g *GethClient) UnlockAccount(address string, password string) error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("POST", g.rpcUrl, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(g.ctx, "POST", g.rpcUrl, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := core.HTTPClientFromContext(g.ctx).Do(req)
 	if err != nil {
 		return err
 	}
@@ -36,11 +36,6 @@ import (
 	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
-var gethImage = "ethereum/client-go:release-1.10"
-
-// TODO: Probably randomize this and make it different per member?
-var keyPassword = "correcthorsebatterystaple"
-
 type GethProvider struct {
 	ctx       context.Context
 	stack     *types.Stack
@@ -51,9 +46,9 @@ func NewGethProvider(ctx context.Context, stack *types.Stack) *GethProvider {
 	var connector connector.Connector
 	switch stack.BlockchainConnector {
 	case types.BlockchainConnectorEthconnect:
-		connector = ethconnect.NewEthconnect(ctx)
+		connector = ethconnect.NewEthconnect(ctx, stack)
 	case types.BlockchainConnectorEvmconnect:
-		connector = evmconnect.NewEvmconnect(ctx)
+		connector = evmconnect.NewEvmconnect(ctx, stack)
 	}
 
 	return &GethProvider{
@@ -65,10 +60,10 @@ func NewGethProvider(ctx context.Context, stack *types.Stack) *GethProvider {
 
 func (p *GethProvider) WriteConfig(options *types.InitOptions) error {
 	initDir := filepath.Join(constants.StacksDir, p.stack.Name, "init")
-	for i, member := range p.stack.Members {
+	for _, member := range p.stack.Members {
 		// Generate the connector config for each member
-		connectorConfigPath := filepath.Join(initDir, "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		if err := p.connector.GenerateConfig(p.stack, member, "geth").WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
+		connectorConfigPath := filepath.Join(initDir, "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		if err := p.connector.GenerateConfig(p.stack, member, p.stack.BlockchainServiceName("geth")).WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
 			return nil
 		}
 	}
@@ -81,7 +76,7 @@ func (p *GethProvider) WriteConfig(options *types.InitOptions) error {
 		addresses[i] = address[2:]
 	}
 	genesis := CreateGenesis(addresses, options.BlockPeriod, p.stack.ChainID())
-	if err := genesis.WriteGenesisJson(filepath.Join(initDir, "blockchain", "genesis.json")); err != nil {
+	if err := genesis.WriteGenesisJson(filepath.Join(initDir, p.stack.BlockchainDirName(), "genesis.json")); err != nil {
 		return err
 	}
 
@@ -89,18 +84,18 @@ func (p *GethProvider) WriteConfig(options *types.InitOptions) error {
 }
 
 func (p *GethProvider) FirstTimeSetup() error {
-	gethVolumeName := fmt.Sprintf("%s_geth", p.stack.Name)
-	blockchainDir := path.Join(p.stack.RuntimeDir, "blockchain")
+	gethVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("geth"))
+	blockchainDir := path.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
 	contractsDir := path.Join(p.stack.RuntimeDir, "contracts")
 
 	if err := os.MkdirAll(contractsDir, 0755); err != nil {
 		return err
 	}
 
-	for i := range p.stack.Members {
+	for _, member := range p.stack.Members {
 		// Copy connector config to each member's volume
-		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		connectorConfigVolumeName := fmt.Sprintf("%s_%s_config_%v", p.stack.Name, p.connector.Name(), i)
+		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		connectorConfigVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainMemberServiceName(p.connector.Name()+"_config", member.ID))
 		docker.CopyFileToVolume(p.ctx, connectorConfigVolumeName, connectorConfigPath, "config.yaml")
 	}
 
@@ -116,7 +111,7 @@ func (p *GethProvider) FirstTimeSetup() error {
 	}
 
 	// Initialize the genesis block
-	if err := docker.RunDockerCommand(p.ctx, p.stack.StackDir, "run", "--rm", "-v", fmt.Sprintf("%s:/data", gethVolumeName), gethImage, "--datadir", "/data", "init", "/data/genesis.json"); err != nil {
+	if err := docker.RunDockerCommand(p.ctx, p.stack.StackDir, "run", "--rm", "-v", fmt.Sprintf("%s:/data", gethVolumeName), p.stack.VersionManifest.Geth.GetDockerImageString(), "--datadir", "/data", "init", "/data/genesis.json"); err != nil {
 		return err
 	}
 
@@ -133,7 +128,7 @@ func (p *GethProvider) PostStart(firstTimeSetup bool) error {
 	for _, account := range p.stack.State.Accounts {
 		address := account.(*ethereum.Account).Address
 		l.Info(fmt.Sprintf("unlocking account %s", address))
-		if err := p.unlockAccount(address, keyPassword); err != nil {
+		if err := p.unlockAccount(address, p.stack.KeystorePassword(address)); err != nil {
 			return err
 		}
 	}
@@ -144,7 +139,7 @@ func (p *GethProvider) PostStart(firstTimeSetup bool) error {
 func (p *GethProvider) unlockAccount(address, password string) error {
 	l := log.LoggerFromContext(p.ctx)
 	verbose := log.VerbosityFromContext(p.ctx)
-	gethClient := NewGethClient(fmt.Sprintf("http://127.0.0.1:%v", p.stack.ExposedBlockchainPort))
+	gethClient := NewGethClient(p.ctx, fmt.Sprintf("http://127.0.0.1:%v", p.stack.ExposedBlockchainPort))
 	retries := 10
 	for {
 		if err := gethClient.UnlockAccount(address, password); err != nil {
@@ -174,30 +169,32 @@ func (p *GethProvider) DeployFireFlyContract() (*types.ContractDeploymentResult,
 func (p *GethProvider) GetDockerServiceDefinitions() []*docker.ServiceDefinition {
 	gethCommand := fmt.Sprintf(`--datadir /data --syncmode 'full' --port 30311 --http --http.addr "0.0.0.0" --http.corsdomain="*"  -http.port 8545 --http.vhosts "*" --http.api 'admin,personal,eth,net,web3,txpool,miner,clique,debug' --networkid %d --miner.gasprice 0 --password /data/password --mine --allow-insecure-unlock --nodiscover --verbosity 4 --miner.gaslimit 16777215`, p.stack.ChainID())
 
+	if p.stack.PrometheusEnabled {
+		gethCommand += " --metrics --metrics.addr 0.0.0.0 --metrics.port 6060"
+	}
+
 	serviceDefinitions := make([]*docker.ServiceDefinition, 1)
 	serviceDefinitions[0] = &docker.ServiceDefinition{
-		ServiceName: "geth",
+		ServiceName: p.stack.BlockchainServiceName("geth"),
 		Service: &docker.Service{
-			Image:         gethImage,
-			ContainerName: fmt.Sprintf("%s_geth", p.stack.Name),
+			Image:         p.stack.VersionManifest.Geth.GetDockerImageString(),
+			ContainerName: fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("geth")),
 			Command:       gethCommand,
-			Volumes:       []string{"geth:/data"},
+			Volumes:       []string{p.stack.BlockchainServiceName("geth") + ":/data"},
 			Logging:       docker.StandardLogOptions,
 			Ports:         []string{fmt.Sprintf("%d:8545", p.stack.ExposedBlockchainPort)},
 		},
-		VolumeNames: []string{"geth"},
+		VolumeNames: []string{p.stack.BlockchainServiceName("geth")},
+	}
+	if p.stack.PrometheusEnabled {
+		serviceDefinitions[0].Metrics = &docker.MetricsEndpoint{Job: "geth", Port: 6060, Path: "/debug/metrics/prometheus"}
 	}
-	serviceDefinitions = append(serviceDefinitions, p.connector.GetServiceDefinitions(p.stack, map[string]string{"geth": "service_started"})...)
+	serviceDefinitions = append(serviceDefinitions, p.connector.GetServiceDefinitions(p.stack, map[string]string{p.stack.BlockchainServiceName("geth"): "service_started"})...)
 	return serviceDefinitions
 }
 
 func (p *GethProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Organization) (blockchainConfig *types.BlockchainConfig) {
-	var connectorURL string
-	if m.External {
-		connectorURL = p.GetConnectorExternalURL(m)
-	} else {
-		connectorURL = p.GetConnectorURL(m)
-	}
+	connectorURL := m.ComponentURL(types.ComponentCore, types.ComponentConnector, p.GetConnectorURL(m), p.GetConnectorExternalURL(m))
 
 	blockchainConfig = &types.BlockchainConfig{
 		Type: "ethereum",
@@ -205,6 +202,8 @@ func (p *GethProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Or
 			Ethconnect: &types.EthconnectConfig{
 				URL:   connectorURL,
 				Topic: m.ID,
+				Auth:  stack.ConnectorCredentials(m.ID),
+				TLS:   stack.ClientTLSConfig("firefly_core_"+m.ID, m.External),
 			},
 		},
 	}
@@ -247,7 +246,7 @@ func (p *GethProvider) DeployContract(filename, contractName, instanceName strin
 }
 
 func (p *GethProvider) CreateAccount(args []string) (interface{}, error) {
-	gethVolumeName := fmt.Sprintf("%s_geth", p.stack.Name)
+	gethVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("geth"))
 	var directory string
 	stackHasRunBefore, err := p.stack.HasRunBefore()
 	if err != nil {
@@ -260,8 +259,8 @@ func (p *GethProvider) CreateAccount(args []string) (interface{}, error) {
 	}
 
 	prefix := strconv.FormatInt(time.Now().UnixNano(), 10)
-	outputDirectory := filepath.Join(directory, "blockchain", "keystore")
-	keyPair, walletFilePath, err := ethereum.CreateWalletFile(outputDirectory, prefix, keyPassword)
+	outputDirectory := filepath.Join(directory, p.stack.BlockchainDirName(), "keystore")
+	keyPair, walletFilePath, password, err := ethereum.CreateStackWalletFile(p.stack, outputDirectory, prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -270,7 +269,7 @@ func (p *GethProvider) CreateAccount(args []string) (interface{}, error) {
 		if err := ethereum.CopyWalletFileToVolume(p.ctx, walletFilePath, gethVolumeName); err != nil {
 			return nil, err
 		}
-		if err := p.unlockAccount(keyPair.Address.String(), keyPassword); err != nil {
+		if err := p.unlockAccount(keyPair.Address.String(), password); err != nil {
 			return nil, err
 		}
 	}
@@ -281,6 +280,15 @@ func (p *GethProvider) CreateAccount(args []string) (interface{}, error) {
 	}, nil
 }
 
+func (p *GethProvider) RotateKeystorePasswords(oldPassword func(address string) string) error {
+	gethVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainServiceName("geth"))
+	keystoreDirectory := filepath.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName(), "keystore")
+	if _, err := ethereum.ReencryptKeystore(p.stack, keystoreDirectory, oldPassword); err != nil {
+		return err
+	}
+	return docker.CopyFileToVolume(p.ctx, gethVolumeName, keystoreDirectory, "/")
+}
+
 func (p *GethProvider) ParseAccount(account interface{}) interface{} {
 	accountMap := account.(map[string]interface{})
 	return &ethereum.Account{
@@ -294,9 +302,9 @@ func (p *GethProvider) GetConnectorName() string {
 }
 
 func (p *GethProvider) GetConnectorURL(org *types.Organization) string {
-	return fmt.Sprintf("http://%s_%s:%v", p.connector.Name(), org.ID, p.connector.Port())
+	return fmt.Sprintf("%s://%s:%v", p.stack.Scheme(), p.stack.BlockchainMemberServiceName(p.connector.Name(), org.ID), p.connector.Port())
 }
 
 func (p *GethProvider) GetConnectorExternalURL(org *types.Organization) string {
-	return fmt.Sprintf("http://127.0.0.1:%v", org.ExposedConnectorPort)
+	return fmt.Sprintf("%s://127.0.0.1:%v", p.stack.Scheme(), org.ExposedConnectorPort)
 }
@@ -42,9 +42,9 @@ func NewRemoteRPCProvider(ctx context.Context, stack *types.Stack) *RemoteRPCPro
 	var connector connector.Connector
 	switch stack.BlockchainConnector {
 	case types.BlockchainConnectorEthconnect:
-		connector = ethconnect.NewEthconnect(ctx)
+		connector = ethconnect.NewEthconnect(ctx, stack)
 	case types.BlockchainConnectorEvmconnect:
-		connector = evmconnect.NewEvmconnect(ctx)
+		connector = evmconnect.NewEvmconnect(ctx, stack)
 	}
 
 	return &RemoteRPCProvider{
@@ -57,11 +57,11 @@ func NewRemoteRPCProvider(ctx context.Context, stack *types.Stack) *RemoteRPCPro
 
 func (p *RemoteRPCProvider) WriteConfig(options *types.InitOptions) error {
 	initDir := filepath.Join(constants.StacksDir, p.stack.Name, "init")
-	for i, member := range p.stack.Members {
+	for _, member := range p.stack.Members {
 
 		// Generate the connector config for each member
-		connectorConfigPath := filepath.Join(initDir, "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		if err := p.connector.GenerateConfig(p.stack, member, "ethsigner").WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
+		connectorConfigPath := filepath.Join(initDir, "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		if err := p.connector.GenerateConfig(p.stack, member, p.stack.BlockchainServiceName("ethsigner")).WriteConfig(connectorConfigPath, options.ExtraConnectorConfigPath); err != nil {
 			return err
 		}
 
@@ -75,10 +75,10 @@ func (p *RemoteRPCProvider) FirstTimeSetup() error {
 		return err
 	}
 
-	for i := range p.stack.Members {
+	for _, member := range p.stack.Members {
 		// Copy connector config to each member's volume
-		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", fmt.Sprintf("%s_%v.yaml", p.connector.Name(), i))
-		connectorConfigVolumeName := fmt.Sprintf("%s_%s_config_%v", p.stack.Name, p.connector.Name(), i)
+		connectorConfigPath := filepath.Join(p.stack.StackDir, "runtime", "config", p.stack.BlockchainMemberServiceName(p.connector.Name(), member.ID)+".yaml")
+		connectorConfigVolumeName := fmt.Sprintf("%s_%s", p.stack.Name, p.stack.BlockchainMemberServiceName(p.connector.Name()+"_config", member.ID))
 		docker.CopyFileToVolume(p.ctx, connectorConfigVolumeName, connectorConfigPath, "config.yaml")
 	}
 
@@ -101,17 +101,12 @@ func (p *RemoteRPCProvider) GetDockerServiceDefinitions() []*docker.ServiceDefin
 	defs := []*docker.ServiceDefinition{
 		p.signer.GetDockerServiceDefinition(p.stack.RemoteNodeURL),
 	}
-	defs = append(defs, p.connector.GetServiceDefinitions(p.stack, map[string]string{"ethsigner": "service_healthy"})...)
+	defs = append(defs, p.connector.GetServiceDefinitions(p.stack, map[string]string{p.stack.BlockchainServiceName("ethsigner"): "service_healthy"})...)
 	return defs
 }
 
 func (p *RemoteRPCProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Organization) (blockchainConfig *types.BlockchainConfig) {
-	var connectorURL string
-	if m.External {
-		connectorURL = p.GetConnectorExternalURL(m)
-	} else {
-		connectorURL = p.GetConnectorURL(m)
-	}
+	connectorURL := m.ComponentURL(types.ComponentCore, types.ComponentConnector, p.GetConnectorURL(m), p.GetConnectorExternalURL(m))
 
 	blockchainConfig = &types.BlockchainConfig{
 		Type: "ethereum",
@@ -119,6 +114,8 @@ func (p *RemoteRPCProvider) GetBlockchainPluginConfig(stack *types.Stack, m *typ
 			Ethconnect: &types.EthconnectConfig{
 				URL:   connectorURL,
 				Topic: m.ID,
+				Auth:  stack.ConnectorCredentials(m.ID),
+				TLS:   stack.ClientTLSConfig("firefly_core_"+m.ID, m.External),
 			},
 		},
 	}
@@ -169,11 +166,15 @@ func (p *RemoteRPCProvider) GetConnectorName() string {
 }
 
 func (p *RemoteRPCProvider) GetConnectorURL(org *types.Organization) string {
-	return fmt.Sprintf("http://%s_%s:%v", p.connector.Name(), org.ID, p.connector.Port())
+	return fmt.Sprintf("%s://%s:%v", p.stack.Scheme(), p.stack.BlockchainMemberServiceName(p.connector.Name(), org.ID), p.connector.Port())
 }
 
 func (p *RemoteRPCProvider) GetConnectorExternalURL(org *types.Organization) string {
-	return fmt.Sprintf("http://127.0.0.1:%v", org.ExposedConnectorPort)
+	return fmt.Sprintf("%s://127.0.0.1:%v", p.stack.Scheme(), org.ExposedConnectorPort)
+}
+
+func (p *RemoteRPCProvider) RotateKeystorePasswords(oldPassword func(address string) string) error {
+	return p.signer.RotateKeystorePasswords(oldPassword)
 }
 
 func (p *RemoteRPCProvider) ParseAccount(account interface{}) interface{} {
@@ -18,12 +18,15 @@ package fabconnect
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
 )
 
 type CreateIdentityRequest struct {
@@ -45,7 +48,7 @@ type EnrollIdentityResponse struct {
 	Success string
 }
 
-func CreateIdentity(fabconnectUrl string, signer string) (*CreateIdentityResponse, error) {
+func CreateIdentity(ctx context.Context, fabconnectUrl string, signer string) (*CreateIdentityResponse, error) {
 	u, err := url.Parse(fabconnectUrl)
 	if err != nil {
 		return nil, err
@@ -59,7 +62,7 @@ func CreateIdentity(fabconnectUrl string, signer string) (*CreateIdentityRespons
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -67,8 +70,7 @@ func CreateIdentity(fabconnectUrl string, signer string) (*CreateIdentityRespons
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := core.HTTPClientFromContext(ctx).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +87,7 @@ func CreateIdentity(fabconnectUrl string, signer string) (*CreateIdentityRespons
 	return createIdentityResponseBody, nil
 }
 
-func EnrollIdentity(fabconnectUrl, signer, secret string) (*EnrollIdentityResponse, error) {
+func EnrollIdentity(ctx context.Context, fabconnectUrl, signer, secret string) (*EnrollIdentityResponse, error) {
 	u, err := url.Parse(fabconnectUrl)
 	if err != nil {
 		return nil, err
@@ -99,7 +101,7 @@ func EnrollIdentity(fabconnectUrl, signer, secret string) (*EnrollIdentityRespon
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -107,8 +109,7 @@ func EnrollIdentity(fabconnectUrl, signer, secret string) (*EnrollIdentityRespon
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := core.HTTPClientFromContext(ctx).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +29,7 @@ func GenerateDockerServiceDefinitions(s *types.Stack) []*docker.ServiceDefinitio
 		{
 			ServiceName: "fabric_ca",
 			Service: &docker.Service{
-				Image:         FabricCAImageName,
+				Image:         s.VersionManifest.FabricCA.GetDockerImageString(),
 				ContainerName: fmt.Sprintf("%s_fabric_ca", s.Name),
 				Environment: map[string]interface{}{
 					"FABRIC_CA_HOME":                            "/etc/hyperledger/fabric-ca-server",
@@ -55,7 +55,7 @@ func GenerateDockerServiceDefinitions(s *types.Stack) []*docker.ServiceDefinitio
 		{
 			ServiceName: "fabric_orderer",
 			Service: &docker.Service{
-				Image:         FabricOrdererImageName,
+				Image:         s.VersionManifest.FabricOrderer.GetDockerImageString(),
 				ContainerName: fmt.Sprintf("%s_fabric_orderer", s.Name),
 				Environment: map[string]interface{}{
 					"FABRIC_LOGGING_SPEC":                       "INFO",
@@ -101,7 +101,7 @@ func GenerateDockerServiceDefinitions(s *types.Stack) []*docker.ServiceDefinitio
 		{
 			ServiceName: "fabric_peer",
 			Service: &docker.Service{
-				Image:         FabricPeerImageName,
+				Image:         s.VersionManifest.FabricPeer.GetDockerImageString(),
 				ContainerName: fmt.Sprintf("%s_fabric_peer", s.Name),
 				Environment: map[string]interface{}{
 					"CORE_VM_ENDPOINT":                      "unix:///host/var/run/docker.sock",
@@ -136,5 +136,22 @@ func GenerateDockerServiceDefinitions(s *types.Stack) []*docker.ServiceDefinitio
 			VolumeNames: []string{"fabric_peer"},
 		},
 	}
+	if s.PrometheusEnabled {
+		// Each component serves its metrics on its operations endpoint
+		metricsEnvVars := map[string]string{
+			"fabric_ca":      "FABRIC_CA_SERVER_METRICS_PROVIDER",
+			"fabric_orderer": "ORDERER_METRICS_PROVIDER",
+			"fabric_peer":    "CORE_METRICS_PROVIDER",
+		}
+		operationsPorts := map[string]int{
+			"fabric_ca":      17054,
+			"fabric_orderer": 17050,
+			"fabric_peer":    17051,
+		}
+		for _, serviceDefinition := range serviceDefinitions {
+			serviceDefinition.Service.Environment[metricsEnvVars[serviceDefinition.ServiceName]] = "prometheus"
+			serviceDefinition.Metrics = &docker.MetricsEndpoint{Job: serviceDefinition.ServiceName, Port: operationsPorts[serviceDefinition.ServiceName], Path: "/metrics"}
+		}
+	}
 	return serviceDefinitions
 }
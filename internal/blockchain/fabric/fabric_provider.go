@@ -61,7 +61,7 @@ func NewFabricProvider(ctx context.Context, stack *types.Stack) *FabricProvider
 }
 
 func (p *FabricProvider) WriteConfig(options *types.InitOptions) error {
-	blockchainDirectory := path.Join(p.stack.InitDir, "blockchain")
+	blockchainDirectory := path.Join(p.stack.InitDir, p.stack.BlockchainDirName())
 
 	os.MkdirAll(blockchainDirectory, 0755)
 	if p.stack.RemoteFabricNetwork {
@@ -100,7 +100,7 @@ func (p *FabricProvider) FirstTimeSetup() error {
 		if err := docker.CreateVolume(p.ctx, volumeName); err != nil {
 			return err
 		}
-		blockchainDirectory := path.Join(p.stack.RuntimeDir, "blockchain")
+		blockchainDirectory := path.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
 		cryptogenYamlPath := path.Join(blockchainDirectory, "cryptogen.yaml")
 
 		// Run cryptogen to generate MSP
@@ -110,7 +110,7 @@ func (p *FabricProvider) FirstTimeSetup() error {
 			"--rm",
 			"-v", fmt.Sprintf("%s:/etc/template.yml", cryptogenYamlPath),
 			"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-			FabricToolsImageName,
+			p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 			"cryptogen", "generate",
 			"--config", "/etc/template.yml",
 			"--output", "/etc/firefly/organizations",
@@ -125,7 +125,7 @@ func (p *FabricProvider) FirstTimeSetup() error {
 			"--rm",
 			"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
 			"-v", fmt.Sprintf("%s:/etc/hyperledger/fabric/configtx.yaml", path.Join(blockchainDirectory, "configtx.yaml")),
-			FabricToolsImageName,
+			p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 			"configtxgen",
 			"-outputBlock", "/etc/firefly/firefly.block",
 			"-profile", "SingleOrgApplicationGenesis",
@@ -235,12 +235,7 @@ func (p *FabricProvider) GetDockerServiceDefinitions() []*docker.ServiceDefiniti
 }
 
 func (p *FabricProvider) GetBlockchainPluginConfig(stack *types.Stack, m *types.Organization) (blockchainConfig *types.BlockchainConfig) {
-	var connectorURL string
-	if m.External {
-		connectorURL = p.GetConnectorExternalURL(m)
-	} else {
-		connectorURL = p.GetConnectorURL(m)
-	}
+	connectorURL := m.ComponentURL(types.ComponentCore, types.ComponentConnector, p.GetConnectorURL(m), p.GetConnectorExternalURL(m))
 	blockchainConfig = &types.BlockchainConfig{
 		Type: "fabric",
 		Fabric: &types.FabricConfig{
@@ -269,7 +264,7 @@ func (p *FabricProvider) Reset() error {
 }
 
 func (p *FabricProvider) getFabconnectServiceDefinitions(members []*types.Organization) []*docker.ServiceDefinition {
-	blockchainDirectory := path.Join(p.stack.RuntimeDir, "blockchain")
+	blockchainDirectory := path.Join(p.stack.RuntimeDir, p.stack.BlockchainDirName())
 	serviceDefinitions := make([]*docker.ServiceDefinition, len(members))
 	for i, member := range members {
 		serviceDefinitions[i] = &docker.ServiceDefinition{
@@ -319,7 +314,7 @@ func (p *FabricProvider) getFabconnectServiceDefinitions(members []*types.Organi
 
 func (p *FabricProvider) writeConfigtxYaml() error {
 	if !p.stack.RemoteFabricNetwork {
-		filePath := path.Join(p.stack.InitDir, "blockchain", "configtx.yaml")
+		filePath := path.Join(p.stack.InitDir, p.stack.BlockchainDirName(), "configtx.yaml")
 		return ioutil.WriteFile(filePath, []byte(configtxYaml), 0755)
 	}
 	return nil
@@ -335,7 +330,7 @@ func (p *FabricProvider) createChannel() error {
 		"--rm",
 		fmt.Sprintf("--network=%s_default", p.stack.Name),
 		"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"osnadmin", "channel", "join",
 		"--channelID", "firefly",
 		"--config-block", "/etc/firefly/firefly.block",
@@ -361,7 +356,7 @@ func (p *FabricProvider) joinChannel() error {
 		"-e", "CORE_PEER_TLS_ROOTCERT_FILE=/etc/firefly/organizations/peerOrganizations/org1.example.com/peers/fabric_peer.org1.example.com/tls/ca.crt",
 		"-e", "CORE_PEER_LOCALMSPID=Org1MSP",
 		"-e", "CORE_PEER_MSPCONFIGPATH=/etc/firefly/organizations/peerOrganizations/org1.example.com/users/Admin@org1.example.com/msp",
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"peer", "channel", "join",
 		"-b", "/etc/firefly/firefly.block")
 }
@@ -406,7 +401,7 @@ func (p *FabricProvider) installChaincode(packageFilename string) error {
 		"-e", "CORE_PEER_MSPCONFIGPATH=/etc/firefly/organizations/peerOrganizations/org1.example.com/users/Admin@org1.example.com/msp",
 		"-v", fmt.Sprintf("%s:/package.tar.gz", packageFilename),
 		"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"peer", "lifecycle", "chaincode", "install", "/package.tar.gz",
 	)
 }
@@ -425,7 +420,7 @@ func (p *FabricProvider) queryInstalled() (*QueryInstalledResponse, error) {
 		"-e", "CORE_PEER_LOCALMSPID=Org1MSP",
 		"-e", "CORE_PEER_MSPCONFIGPATH=/etc/firefly/organizations/peerOrganizations/org1.example.com/users/Admin@org1.example.com/msp",
 		"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"peer", "lifecycle", "chaincode", "queryinstalled",
 		"--output", "json",
 	)
@@ -454,7 +449,7 @@ func (p *FabricProvider) approveChaincode(channel, chaincode, version, packageId
 		"-e", "CORE_PEER_LOCALMSPID=Org1MSP",
 		"-e", "CORE_PEER_MSPCONFIGPATH=/etc/firefly/organizations/peerOrganizations/org1.example.com/users/Admin@org1.example.com/msp",
 		"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"peer", "lifecycle", "chaincode", "approveformyorg",
 		"-o", "fabric_orderer:7050",
 		"--ordererTLSHostnameOverride", "fabric_orderer",
@@ -482,7 +477,7 @@ func (p *FabricProvider) commitChaincode(channel, chaincode, version string) err
 		"-e", "CORE_PEER_LOCALMSPID=Org1MSP",
 		"-e", "CORE_PEER_MSPCONFIGPATH=/etc/firefly/organizations/peerOrganizations/org1.example.com/users/Admin@org1.example.com/msp",
 		"-v", fmt.Sprintf("%s:/etc/firefly", volumeName),
-		FabricToolsImageName,
+		p.stack.VersionManifest.FabricTools.GetDockerImageString(),
 		"peer", "lifecycle", "chaincode", "commit",
 		"-o", "fabric_orderer:7050",
 		"--ordererTLSHostnameOverride", "fabric_orderer",
@@ -496,11 +491,11 @@ func (p *FabricProvider) commitChaincode(channel, chaincode, version string) err
 }
 
 func (p *FabricProvider) registerIdentity(member *types.Organization, name string) (*Account, error) {
-	res, err := fabconnect.CreateIdentity(fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort), name)
+	res, err := fabconnect.CreateIdentity(p.ctx, fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort), name)
 	if err != nil {
 		return nil, err
 	}
-	_, err = fabconnect.EnrollIdentity(fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort), name, res.Secret)
+	_, err = fabconnect.EnrollIdentity(p.ctx, fmt.Sprintf("http://127.0.0.1:%v", member.ExposedConnectorPort), name, res.Secret)
 	if err != nil {
 		return nil, err
 	}
@@ -607,6 +602,11 @@ func getDockerPlatform() string {
 	return "linux/amd64"
 }
 
+func (p *FabricProvider) RotateKeystorePasswords(oldPassword func(address string) string) error {
+	// Fabric identities are enrolled through the CA, so there are no password protected keystores to rotate
+	return nil
+}
+
 func (p *FabricProvider) ParseAccount(account interface{}) interface{} {
 	accountMap := account.(map[string]interface{})
 	return &Account{
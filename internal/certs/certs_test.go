@@ -0,0 +1,51 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueFromLoadedCA(T *testing.T) {
+	dir := T.TempDir()
+	ca, err := NewCA("test_ca")
+	assert.NoError(T, err)
+	assert.NoError(T, ca.Write(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")))
+
+	loaded, err := LoadCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem"))
+	assert.NoError(T, err)
+	subject := &Subject{CommonName: "dataexchange_0", Organization: "member_0", DNSNames: []string{"dataexchange_0"}}
+	assert.NoError(T, loaded.IssueFiles(subject, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")))
+
+	cert, err := ReadCertificate(filepath.Join(dir, "cert.pem"))
+	assert.NoError(T, err)
+	assert.Equal(T, "dataexchange_0", cert.Subject.CommonName)
+	assert.Equal(T, []string{"member_0"}, cert.Subject.Organization)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		DNSName:   "dataexchange_0",
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(T, err)
+}
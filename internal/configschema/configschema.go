@@ -0,0 +1,348 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configschema
+
+import (
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The components that have a bundled schema, named after the schema files
+const (
+	Core       = "core"
+	Evmconnect = "evmconnect"
+	Ethconnect = "ethconnect"
+	Ethsigner  = "ethsigner"
+)
+
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Problem is something found in a config file that the component will not accept, or
+// that it will silently ignore, such as a misspelled key
+type Problem struct {
+	File     string
+	Line     int
+	Path     string
+	Message  string
+	Severity Severity
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Message)
+}
+
+// HasErrors returns whether any of the problems will stop the component from starting
+func HasErrors(problems []*Problem) bool {
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+//go:embed schemas/*.yaml
+var schemas embed.FS
+
+// schemaFile describes the config of a component, for the major versions listed. Sections are
+// maps of their keys, lists hold the schema of their items, and scalars are one of string, int,
+// bool, number, duration or any, with a ! suffix when required.
+type schemaFile struct {
+	Versions []string    `yaml:"versions"`
+	Schema   interface{} `yaml:"schema"`
+}
+
+func loadSchema(component string) (*schemaFile, error) {
+	b, err := schemas.ReadFile(fmt.Sprintf("schemas/%s.yaml", component))
+	if err != nil {
+		return nil, fmt.Errorf("no config schema for component '%s'", component)
+	}
+	var schema *schemaFile
+	if err := yaml.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("invalid config schema for component '%s': %s", component, err)
+	}
+	return schema, nil
+}
+
+// covers returns whether the schema describes the config of a version of the component. Versions that
+// are not a release tag, such as latest or a local build, are assumed to match the bundled schema.
+func (f *schemaFile) covers(version string) bool {
+	if len(version) < 2 || version[0] != 'v' || version[1] < '0' || version[1] > '9' {
+		return true
+	}
+	major := strings.SplitN(version, ".", 2)[0]
+	for _, v := range f.Versions {
+		if v == major {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile checks a config file against the schema of its component
+func ValidateFile(component, filename, version string) ([]*Problem, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(component, b, filename, version)
+}
+
+// Validate checks the content of a config file against the schema of its component, for the version
+// of the component that will load it. Unknown keys are reported as warnings, as the component
+// ignores them, while values of the wrong type and missing required settings are errors. Nothing
+// is checked for versions that the bundled schema does not cover.
+func Validate(component string, content []byte, filename, version string) ([]*Problem, error) {
+	schema, err := loadSchema(component)
+	if err != nil {
+		return nil, err
+	}
+	if !schema.covers(version) {
+		return nil, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", filename, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	v := &validator{filename: filename}
+	v.walk(doc.Content[0], schema.Schema, "")
+	if component == Core {
+		v.checkCorePlugins(doc.Content[0])
+	}
+	return v.problems, nil
+}
+
+type validator struct {
+	filename string
+	problems []*Problem
+}
+
+func (v *validator) report(node *yaml.Node, severity Severity, path, message string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{
+		File:     v.filename,
+		Line:     node.Line,
+		Path:     path,
+		Message:  fmt.Sprintf(message, args...),
+		Severity: severity,
+	})
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a section"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("'%s'", node.Value)
+	}
+}
+
+// lookup finds the schema of a key in a section, ignoring case as the components do
+func lookup(section map[string]interface{}, key string) (interface{}, bool) {
+	for k, schema := range section {
+		if strings.EqualFold(k, key) {
+			return schema, true
+		}
+	}
+	schema, ok := section["*"]
+	return schema, ok
+}
+
+func (v *validator) walk(node *yaml.Node, schema interface{}, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		v.walkSection(node, schema, path)
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, SeverityError, path, "'%s' must be a list, not %s", path, describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(item, schema[0], fmt.Sprintf("%s[%d]", path, i))
+		}
+	case string:
+		v.checkValue(node, strings.TrimSuffix(schema, "!"), path)
+	}
+}
+
+func (v *validator) walkSection(node *yaml.Node, schema map[string]interface{}, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.report(node, SeverityError, path, "'%s' must be a section, not %s", path, describe(node))
+		return
+	}
+	present := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Value == "<<" {
+			v.walk(valueNode, schema, path)
+			continue
+		}
+		// Keys may also be written with dots, as in "policyengine.simple"
+		parts := strings.Split(keyNode.Value, ".")
+		present[strings.ToLower(parts[0])] = true
+		var keySchema interface{} = schema
+		keyPath := path
+		for _, part := range parts {
+			keyPath = joinPath(keyPath, part)
+			section, ok := keySchema.(map[string]interface{})
+			if ok {
+				keySchema, ok = lookup(section, part)
+			}
+			if !ok {
+				v.report(keyNode, SeverityWarning, keyPath, "unknown key '%s' will be ignored", keyPath)
+				keySchema = nil
+				break
+			}
+		}
+		if keySchema != nil {
+			v.walk(valueNode, keySchema, keyPath)
+		}
+	}
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if t, ok := schema[key].(string); ok && strings.HasSuffix(t, "!") && !present[strings.ToLower(key)] {
+			v.report(node, SeverityError, path, "'%s' is required", joinPath(path, key))
+		}
+	}
+}
+
+func (v *validator) checkValue(node *yaml.Node, valueType, path string) {
+	if valueType == "any" {
+		return
+	}
+	if node.Kind != yaml.ScalarNode {
+		v.report(node, SeverityError, path, "'%s' must be a %s, not %s", path, valueType, describe(node))
+		return
+	}
+	var err error
+	switch valueType {
+	case "int":
+		_, err = strconv.ParseInt(node.Value, 10, 64)
+	case "number":
+		_, err = strconv.ParseFloat(node.Value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(node.Value)
+	case "duration":
+		// A plain number is taken as milliseconds
+		if _, err = time.ParseDuration(node.Value); err != nil {
+			_, err = strconv.ParseFloat(node.Value, 64)
+		}
+	}
+	if err != nil {
+		v.report(node, SeverityError, path, "'%s' must be a %s, not %s", path, valueType, describe(node))
+	}
+}
+
+// child returns the value of a key of a section, ignoring case
+func child(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// checkCorePlugins checks that every plugin a namespace uses is defined, and that every
+// namespace has a database, as FireFly core will not start otherwise
+func (v *validator) checkCorePlugins(root *yaml.Node) {
+	plugins := map[string]string{}
+	if pluginsNode := child(root, "plugins"); pluginsNode != nil && pluginsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(pluginsNode.Content); i += 2 {
+			pluginType, entries := pluginsNode.Content[i].Value, pluginsNode.Content[i+1]
+			if entries.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, entry := range entries.Content {
+				if name := child(entry, "name"); name != nil && name.Value != "" {
+					plugins[name.Value] = strings.ToLower(pluginType)
+				}
+			}
+		}
+	}
+
+	namespaces := child(child(root, "namespaces"), "predefined")
+	if namespaces == nil || namespaces.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, ns := range namespaces.Content {
+		path := fmt.Sprintf("namespaces.predefined[%d].plugins", i)
+		name := path
+		if nameNode := child(ns, "name"); nameNode != nil {
+			name = fmt.Sprintf("namespace '%s'", nameNode.Value)
+		}
+		nsPlugins := child(ns, "plugins")
+		if nsPlugins == nil || nsPlugins.Kind != yaml.SequenceNode {
+			// Without a list of plugins, a namespace uses every plugin that is defined
+			hasDatabase := false
+			for _, pluginType := range plugins {
+				hasDatabase = hasDatabase || pluginType == "database"
+			}
+			if !hasDatabase {
+				v.report(ns, SeverityError, path, "%s has no database plugin", name)
+			}
+			continue
+		}
+		hasDatabase := false
+		for _, plugin := range nsPlugins.Content {
+			pluginType, ok := plugins[plugin.Value]
+			if !ok {
+				v.report(plugin, SeverityError, path, "%s uses plugin '%s', which is not defined under plugins", name, plugin.Value)
+			}
+			hasDatabase = hasDatabase || pluginType == "database"
+		}
+		if !hasDatabase {
+			v.report(nsPlugins, SeverityError, path, "%s has no database plugin", name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCoreConfig(T *testing.T) {
+	config := `
+log:
+  level: debug
+http:
+  port: "5000"
+  adress: 0.0.0.0
+admin:
+  enabled: yes please
+plugins:
+  database:
+  - name: database0
+    type: postgres
+    postgres:
+      url: postgres://postgres@postgres_0:5432
+  blockchain:
+  - type: ethereum
+namespaces:
+  default: default
+  predefined:
+  - name: default
+    plugins: [database0, blockchain0]
+`
+	problems, err := Validate(Core, []byte(config), "firefly_core_0.yml", "v1.1.2")
+	assert.NoError(T, err)
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.String())
+	}
+	assert.Equal(T, []string{
+		"firefly_core_0.yml:6: warning: unknown key 'http.adress' will be ignored",
+		"firefly_core_0.yml:8: error: 'admin.enabled' must be a bool, not 'yes please'",
+		"firefly_core_0.yml:16: error: 'plugins.blockchain[0].name' is required",
+		"firefly_core_0.yml:21: error: namespace 'default' uses plugin 'blockchain0', which is not defined under plugins",
+	}, messages)
+	assert.True(T, HasErrors(problems))
+}
+
+func TestValidateSkipsOtherVersions(T *testing.T) {
+	problems, err := Validate(Ethconnect, []byte("unknown: true"), "ethconnect_0.yaml", "v4.0.0")
+	assert.NoError(T, err)
+	assert.Empty(T, problems)
+
+	problems, err = Validate(Ethconnect, []byte("unknown: true"), "ethconnect_0.yaml", "latest")
+	assert.NoError(T, err)
+	assert.Len(T, problems, 1)
+}
@@ -25,7 +25,39 @@ var homeDir, _ = os.UserHomeDir()
 var StacksDir = filepath.Join(homeDir, ".firefly", "stacks")
 
 var FireFlyCoreImageName = "ghcr.io/hyperledger/firefly"
-var IPFSImageName = "ipfs/go-ipfs:v0.10.0"
-var PostgresImageName = "postgres"
-var PrometheusImageName = "prom/prometheus"
-var SandboxImageName = "ghcr.io/hyperledger/firefly-sandbox:latest"
+
+// The third party images of new stacks, by their key in the version manifest. A manifest passed with --manifest,
+// or the release manifest of FireFly, can override any of them.
+var DefaultThirdPartyImages = map[string]string{
+	"ipfs":              "ipfs/go-ipfs:v0.10.0",
+	"postgres":          "postgres:14",
+	"postgres-exporter": "prometheuscommunity/postgres-exporter:v0.11.1",
+	"prometheus":        "prom/prometheus:v2.38.0",
+	"grafana":           "grafana/grafana:9.1.0",
+	"otel-collector":    "otel/opentelemetry-collector:0.60.0",
+	"jaeger":            "jaegertracing/all-in-one:1.37",
+	"sandbox":           "ghcr.io/hyperledger/firefly-sandbox:v1.1.0",
+	"geth":              "ethereum/client-go:release-1.10",
+	"besu":              "hyperledger/besu:22.4",
+	"fabric-tools":      "hyperledger/fabric-tools:2.3",
+	"fabric-ca":         "hyperledger/fabric-ca:1.5",
+	"fabric-orderer":    "hyperledger/fabric-orderer:2.3",
+	"fabric-peer":       "hyperledger/fabric-peer:2.3",
+	"tls-proxy":         "ghostunnel/ghostunnel:v1.7.1",
+}
+
+// The third party images that were hardcoded before they were pinned in the version manifest. Stacks
+// created by older CLI versions keep using them, so that e.g. their postgres data stays readable.
+// Components that did not exist then get the images in DefaultThirdPartyImages.
+var LegacyThirdPartyImages = map[string]string{
+	"ipfs":           "ipfs/go-ipfs:v0.10.0",
+	"postgres":       "postgres",
+	"prometheus":     "prom/prometheus",
+	"sandbox":        "ghcr.io/hyperledger/firefly-sandbox:latest",
+	"geth":           "ethereum/client-go:release-1.10",
+	"besu":           "hyperledger/besu:22.4",
+	"fabric-tools":   "hyperledger/fabric-tools:2.3",
+	"fabric-ca":      "hyperledger/fabric-ca:1.5",
+	"fabric-orderer": "hyperledger/fabric-orderer:2.3",
+	"fabric-peer":    "hyperledger/fabric-peer:2.3",
+}
@@ -19,43 +19,244 @@ package core
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
-var requestTimeout int = -1
+// Defaults used for any HTTPClientOptions field that is not set in stack.json
+const (
+	DefaultRetryMaxAttempts    = 30
+	DefaultRetryInitialDelayMs = 250
+	DefaultRetryMaxDelayMs     = 5000
+	DefaultRetryFactor         = 2.0
+	DefaultRetryDeadlineSecs   = 120
+	DefaultConnectTimeoutSecs  = 10
+)
+
+// DefaultRetryStatusCodes are the response codes that are retried when none are configured.
+// 404 is included because ethconnect returns it while a reply is not yet available.
+var DefaultRetryStatusCodes = []int{404, 408, 425, 429, 500, 502, 503, 504}
+
+type ctxHTTPClientKey struct{}
+
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Factor       float64
+	Deadline     time.Duration
+	StatusCodes  map[int]bool
+}
+
+type HTTPClient struct {
+	client         *http.Client
+	transport      *http.Transport
+	retry          *RetryPolicy
+	requestTimeout int
+	basicAuth      map[string]*types.BasicAuth
+}
+
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Body       []byte
+}
 
-func SetRequestTimeout(customRequestTimeoutSecs int) {
-	requestTimeout = customRequestTimeoutSecs
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s [%d] %s", e.URL, e.StatusCode, e.Body)
+}
+
+var defaultHTTPClient = NewHTTPClient(nil, 0)
+
+func NewHTTPClient(options *types.HTTPClientOptions, requestTimeout int) *HTTPClient {
+	if options == nil {
+		options = &types.HTTPClientOptions{}
+	}
+	retry := &RetryPolicy{
+		MaxAttempts:  intOrDefault(options.RetryMaxAttempts, DefaultRetryMaxAttempts),
+		InitialDelay: time.Duration(intOrDefault(options.RetryInitialDelayMs, DefaultRetryInitialDelayMs)) * time.Millisecond,
+		MaxDelay:     time.Duration(intOrDefault(options.RetryMaxDelayMs, DefaultRetryMaxDelayMs)) * time.Millisecond,
+		Factor:       options.RetryFactor,
+		Deadline:     time.Duration(intOrDefault(options.RetryDeadlineSecs, DefaultRetryDeadlineSecs)) * time.Second,
+		StatusCodes:  make(map[int]bool),
+	}
+	if retry.Factor < 1 {
+		retry.Factor = DefaultRetryFactor
+	}
+	statusCodes := options.RetryStatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryStatusCodes
+	}
+	for _, code := range statusCodes {
+		retry.StatusCodes[code] = true
+	}
+
+	connectTimeout := time.Duration(intOrDefault(options.ConnectTimeoutSecs, DefaultConnectTimeoutSecs)) * time.Second
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: time.Duration(options.ResponseTimeoutSecs) * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: &tracingTransport{next: transport},
+	}
+	if requestTimeout > 0 {
+		// Give the server a chance to honor the Request-Timeout header before we give up on it
+		client.Timeout = time.Duration(requestTimeout+5) * time.Second
+	}
+
+	return &HTTPClient{
+		client:         client,
+		transport:      transport,
+		retry:          retry,
+		requestTimeout: requestTimeout,
+		basicAuth:      make(map[string]*types.BasicAuth),
+	}
+}
+
+// TrustCA adds a PEM encoded CA certificate to the roots the client trusts, on top of the
+// system roots, so that it can call the HTTPS endpoints of a stack with TLS enabled
+func (c *HTTPClient) TrustCA(caPEM []byte) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil || roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no CA certificates found in PEM data")
+	}
+	c.transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	return nil
+}
+
+// SetBasicAuth makes the client send the given credentials on every request to a host, given as host:port
+func (c *HTTPClient) SetBasicAuth(host string, auth *types.BasicAuth) {
+	c.basicAuth[host] = auth
+}
+
+func (c *HTTPClient) authorize(req *http.Request) {
+	if auth, ok := c.basicAuth[req.URL.Host]; ok && auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
+
+func intOrDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+func WithHTTPClient(ctx context.Context, client *HTTPClient) context.Context {
+	return context.WithValue(ctx, ctxHTTPClientKey{}, client)
+}
+
+func HTTPClientFromContext(ctx context.Context) *HTTPClient {
+	if client, ok := ctx.Value(ctxHTTPClientKey{}).(*HTTPClient); ok && client != nil {
+		return client
+	}
+	return defaultHTTPClient
 }
 
 func RequestWithRetry(ctx context.Context, method, url string, body, result interface{}) (err error) {
+	return HTTPClientFromContext(ctx).RequestWithRetry(ctx, method, url, body, result)
+}
+
+func (c *HTTPClient) RequestWithRetry(ctx context.Context, method, url string, body, result interface{}) (err error) {
+	return c.retry.do(ctx, "request", c.isRetryable, func(attempt int) error {
+		return c.request(withAttempt(ctx, attempt), method, url, body, result)
+	})
+}
+
+// Retry runs an operation until it succeeds, with the retry policy of the stack, for the CLI to wait for
+// something other than an HTTP endpoint to be ready, such as a command in a container that has just started
+func Retry(ctx context.Context, description string, fn func() error) error {
+	return HTTPClientFromContext(ctx).retry.do(ctx, description, func(error) bool { return true }, func(int) error {
+		return fn()
+	})
+}
+
+func (r *RetryPolicy) do(ctx context.Context, description string, isRetryable func(error) bool, fn func(attempt int) error) (err error) {
 	verbose := log.VerbosityFromContext(ctx)
-	retries := 30
-	for {
-		if err := request(method, url, body, result); err != nil {
-			if retries > 0 {
-				if verbose {
-					fmt.Printf("%s - retrying request...", err.Error())
-				}
-				retries--
-				time.Sleep(1 * time.Second)
-			} else {
-				return err
-			}
-		} else {
+	deadline := time.Now().Add(r.Deadline)
+	delay := r.InitialDelay
+	for attempt := 1; ; attempt++ {
+		err = fn(attempt)
+		if err == nil {
 			return nil
 		}
+		if !isRetryable(err) {
+			return err
+		}
+		if attempt >= r.MaxAttempts {
+			return fmt.Errorf("%s - giving up after %d attempts", err, attempt)
+		}
+		sleep := r.jitter(delay)
+		if time.Now().Add(sleep).After(deadline) {
+			return fmt.Errorf("%s - giving up after %d attempts as the retry deadline of %s was reached", err, attempt, r.Deadline)
+		}
+		if verbose {
+			fmt.Printf("%s - retrying %s (attempt %d of %d) in %s...\n", err.Error(), description, attempt+1, r.MaxAttempts, sleep)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+		delay = r.next(delay)
 	}
 }
 
-func request(method, url string, body, result interface{}) (err error) {
+// Do sends a request using the stack's HTTP client, without any retry
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.authorize(req)
+	return c.client.Do(req)
+}
+
+func (c *HTTPClient) isRetryable(err error) bool {
+	if statusErr, ok := err.(*HTTPStatusError); ok {
+		return c.retry.StatusCodes[statusErr.StatusCode]
+	}
+	// Connection errors, timeouts etc. are always retried, since the service is likely still starting
+	return true
+}
+
+func (r *RetryPolicy) next(delay time.Duration) time.Duration {
+	delay = time.Duration(float64(delay) * r.Factor)
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	return delay
+}
+
+// jitter returns a random duration between half and the full length of the given delay,
+// so that concurrent callers don't all retry at exactly the same moment
+func (r *RetryPolicy) jitter(delay time.Duration) time.Duration {
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func (c *HTTPClient) request(ctx context.Context, method, url string, body, result interface{}) (err error) {
 	if body == nil {
 		body = make(map[string]interface{})
 	}
@@ -69,19 +270,16 @@ func request(method, url string, body, result interface{}) (err error) {
 		bodyReader = bytes.NewReader(requestBody)
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return err
 	}
-	if requestTimeout > 0 {
-		req.Header.Set("Request-Timeout", fmt.Sprintf("%d", requestTimeout))
+	if c.requestTimeout > 0 {
+		req.Header.Set("Request-Timeout", fmt.Sprintf("%d", c.requestTimeout))
 	}
 	req.Header.Set("Content-Type", "application/json")
-	if err != nil {
-		return err
-	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	c.authorize(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -91,7 +289,7 @@ func request(method, url string, body, result interface{}) (err error) {
 		if resp.StatusCode != 204 {
 			responseBytes, _ = ioutil.ReadAll(resp.Body)
 		}
-		return fmt.Errorf("%s [%d] %s", url, resp.StatusCode, responseBytes)
+		return &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, Body: responseBytes}
 	}
 
 	if resp.StatusCode == 204 {
@@ -0,0 +1,189 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContext(client *HTTPClient) context.Context {
+	ctx := log.WithVerbosity(context.Background(), false)
+	return WithHTTPClient(ctx, client)
+}
+
+func TestRequestWithRetryRetriesStatusCodes(T *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(T, "30", r.Header.Get("Request-Timeout"))
+		if attempts < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"id":"abc"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryInitialDelayMs: 1, RetryMaxDelayMs: 2}, 30)
+	result := map[string]string{}
+	err := RequestWithRetry(testContext(client), "GET", server.URL, nil, &result)
+	assert.NoError(T, err)
+	assert.Equal(T, 3, attempts)
+	assert.Equal(T, "abc", result["id"])
+}
+
+func TestRequestWithRetryDoesNotRetryOtherStatusCodes(T *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(400)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryInitialDelayMs: 1}, 0)
+	err := RequestWithRetry(testContext(client), "POST", server.URL, nil, nil)
+	assert.Error(T, err)
+	assert.Equal(T, 1, attempts)
+	assert.Equal(T, 400, err.(*HTTPStatusError).StatusCode)
+}
+
+func TestRequestWithRetryMaxAttempts(T *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryMaxAttempts: 4, RetryInitialDelayMs: 1, RetryMaxDelayMs: 1}, 0)
+	err := RequestWithRetry(testContext(client), "GET", server.URL, nil, nil)
+	assert.Regexp(T, "giving up after 4 attempts", err)
+	assert.Equal(T, 4, attempts)
+}
+
+func TestRetryUsesThePolicyOfTheStack(T *testing.T) {
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryMaxAttempts: 3, RetryInitialDelayMs: 1, RetryMaxDelayMs: 1}, 0)
+	attempts := 0
+	err := Retry(testContext(client), "command", func() error {
+		attempts++
+		return fmt.Errorf("not ready")
+	})
+	assert.Regexp(T, "not ready - giving up after 3 attempts", err)
+	assert.Equal(T, 3, attempts)
+
+	attempts = 0
+	assert.NoError(T, Retry(testContext(client), "command", func() error {
+		attempts++
+		if attempts < 2 {
+			return fmt.Errorf("not ready")
+		}
+		return nil
+	}))
+	assert.Equal(T, 2, attempts)
+}
+
+func TestTrustCA(T *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryMaxAttempts: 1}, 0)
+	err := client.RequestWithRetry(testContext(client), http.MethodGet, server.URL, nil, nil)
+	assert.Error(T, err)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(T, client.TrustCA(caPEM))
+	err = client.RequestWithRetry(testContext(client), http.MethodGet, server.URL, nil, nil)
+	assert.NoError(T, err)
+}
+
+func TestSetBasicAuth(T *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user1" || password != "pass1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryMaxAttempts: 1}, 0)
+	err := client.RequestWithRetry(testContext(client), http.MethodGet, server.URL, nil, nil)
+	assert.Regexp(T, "401", err)
+
+	client.SetBasicAuth(strings.TrimPrefix(server.URL, "http://"), &types.BasicAuth{Username: "user1", Password: "pass1"})
+	err = client.RequestWithRetry(testContext(client), http.MethodGet, server.URL, nil, nil)
+	assert.NoError(T, err)
+}
+
+func TestRetryPolicyBackoff(T *testing.T) {
+	client := NewHTTPClient(&types.HTTPClientOptions{RetryInitialDelayMs: 100, RetryMaxDelayMs: 300, RetryFactor: 2}, 0)
+	delay := client.retry.InitialDelay
+	delay = client.retry.next(delay)
+	assert.Equal(T, int64(200), delay.Milliseconds())
+	delay = client.retry.next(delay)
+	assert.Equal(T, int64(300), delay.Milliseconds())
+	for i := 0; i < 10; i++ {
+		j := client.retry.jitter(delay)
+		assert.GreaterOrEqual(T, j.Milliseconds(), int64(150))
+		assert.LessOrEqual(T, j.Milliseconds(), int64(300))
+	}
+}
+
+func TestHTTPTraceRedactsSecrets(T *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"user1","secret":"s3cret"}`))
+	}))
+	defer server.Close()
+
+	tracePath := filepath.Join(T.TempDir(), "trace.jsonl")
+	assert.NoError(T, EnableHTTPTrace(tracePath, ""))
+	defer func() { httpTracer = nil }()
+
+	body := map[string]interface{}{"method": "personal_unlockAccount", "params": []string{"0x1234", "hunter2", "0"}}
+	result := map[string]string{}
+	err := RequestWithRetry(testContext(NewHTTPClient(nil, 0)), "POST", server.URL, body, &result)
+	assert.NoError(T, err)
+	assert.Equal(T, "s3cret", result["secret"])
+
+	b, err := ioutil.ReadFile(tracePath)
+	assert.NoError(T, err)
+	var entry *HTTPTraceEntry
+	assert.NoError(T, json.Unmarshal(b, &entry))
+	assert.Equal(T, 1, entry.Attempt)
+	assert.Equal(T, 200, entry.Status)
+	assert.NotContains(T, entry.RequestBody, "hunter2")
+	assert.NotContains(T, entry.ResponseBody, "s3cret")
+	assert.Contains(T, entry.ResponseBody, "user1")
+}
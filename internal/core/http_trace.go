@@ -0,0 +1,339 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HTTPTraceFormatHAR   = "har"
+	HTTPTraceFormatJSONL = "jsonl"
+)
+
+type ctxAttemptKey struct{}
+
+// HTTPTraceEntry is a single request/response pair, as written to a JSONL trace file
+type HTTPTraceEntry struct {
+	StartedDateTime time.Time         `json:"startedDateTime"`
+	DurationMs      float64           `json:"durationMs"`
+	Attempt         int               `json:"attempt,omitempty"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	RequestBody     string            `json:"requestBody,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	StatusText      string            `json:"statusText,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+type HTTPTracer struct {
+	mux     sync.Mutex
+	path    string
+	format  string
+	entries []*HTTPTraceEntry
+}
+
+var httpTracer *HTTPTracer
+
+// EnableHTTPTrace records every HTTP request the CLI makes to the file at the given path.
+// If format is empty, it is inferred from the file extension.
+func EnableHTTPTrace(path, format string) error {
+	if format == "" {
+		if strings.EqualFold(filepath.Ext(path), ".har") {
+			format = HTTPTraceFormatHAR
+		} else {
+			format = HTTPTraceFormatJSONL
+		}
+	}
+	if format != HTTPTraceFormatHAR && format != HTTPTraceFormatJSONL {
+		return fmt.Errorf("invalid HTTP trace format '%s' - options are: [%s %s]", format, HTTPTraceFormatHAR, HTTPTraceFormatJSONL)
+	}
+	// Truncate any existing file, so each run of the CLI starts with a fresh trace
+	if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+		return err
+	}
+	httpTracer = &HTTPTracer{
+		path:   path,
+		format: format,
+	}
+	return httpTracer.flush(nil)
+}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, ctxAttemptKey{}, attempt)
+}
+
+func (t *HTTPTracer) record(entry *HTTPTraceEntry) error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.entries = append(t.entries, entry)
+	return t.flush(entry)
+}
+
+func (t *HTTPTracer) flush(entry *HTTPTraceEntry) error {
+	if t.format == HTTPTraceFormatHAR {
+		// HAR is a single JSON document, so re-write the whole file each time
+		// to make sure it is valid even if the CLI exits with an error
+		b, err := json.MarshalIndent(newHAR(t.entries), "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(t.path, b, 0644)
+	}
+	if entry == nil {
+		return nil
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// tracingTransport records requests to the HTTP trace file, if one is enabled
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tracer := httpTracer
+	if tracer == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	entry := &HTTPTraceEntry{
+		StartedDateTime: time.Now(),
+		Method:          req.Method,
+		URL:             redactURL(req),
+		RequestHeaders:  redactHeaders(req.Header),
+	}
+	entry.Attempt, _ = req.Context().Value(ctxAttemptKey{}).(int)
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		entry.RequestBody = RedactJSON(b)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.StatusText = http.StatusText(resp.StatusCode)
+		entry.ResponseHeaders = redactHeaders(resp.Header)
+		b, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+		entry.ResponseBody = RedactJSON(b)
+		if readErr != nil {
+			entry.Error = readErr.Error()
+		}
+	}
+	entry.DurationMs = float64(time.Since(entry.StartedDateTime).Microseconds()) / 1000
+
+	if traceErr := tracer.record(entry); traceErr != nil {
+		fmt.Fprintf(os.Stderr, "failed to write HTTP trace: %s\n", traceErr)
+	}
+	return resp, err
+}
+
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = nil
+		return strings.Replace(u.String(), "://", "://"+req.URL.User.Username()+":"+Redacted+"@", 1)
+	}
+	return u.String()
+}
+
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+func redactHeaders(headers http.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			result[name] = Redacted
+		} else {
+			result[name] = strings.Join(values, ", ")
+		}
+	}
+	return result
+}
+
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Attempt         int         `json:"_attempt,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harBody        `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harBody struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func newHAR(entries []*HTTPTraceEntry) *harLog {
+	har := &harLog{
+		Log: harContent{
+			Version: "1.2",
+			Creator: harCreator{Name: "firefly-cli", Version: "1.0"},
+			Entries: make([]*harEntry, len(entries)),
+		},
+	}
+	for i, e := range entries {
+		h := &harEntry{
+			StartedDateTime: e.StartedDateTime,
+			Time:            e.DurationMs,
+			Request: harRequest{
+				Method:      e.Method,
+				URL:         e.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.RequestHeaders),
+				QueryString: []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    len(e.RequestBody),
+			},
+			Response: harResponse{
+				Status:      e.Status,
+				StatusText:  e.StatusText,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.ResponseHeaders),
+				Content: harBody{
+					Size:     len(e.ResponseBody),
+					MimeType: e.ResponseHeaders["Content-Type"],
+					Text:     e.ResponseBody,
+				},
+				HeadersSize: -1,
+				BodySize:    len(e.ResponseBody),
+			},
+			Timings: harTimings{
+				Wait: e.DurationMs,
+			},
+			Attempt: e.Attempt,
+			Error:   e.Error,
+		}
+		if e.RequestBody != "" {
+			h.Request.PostData = &harPostData{
+				MimeType: e.RequestHeaders["Content-Type"],
+				Text:     e.RequestBody,
+			}
+		}
+		har.Log.Entries[i] = h
+	}
+	return har
+}
+
+func harHeaders(headers map[string]string) []harNameValue {
+	result := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, harNameValue{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
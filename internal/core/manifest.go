@@ -17,6 +17,7 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -62,7 +63,7 @@ func GetManifestForReleaseChannel(releaseChannel fftypes.FFEnum) (*types.Version
 
 func GetReleaseManifest(version string) (*types.VersionManifest, error) {
 	manifest := &types.VersionManifest{}
-	if err := request("GET", fmt.Sprintf("https://raw.githubusercontent.com/hyperledger/firefly/%s/manifest.json", version), nil, &manifest); err != nil {
+	if err := defaultHTTPClient.request(context.Background(), "GET", fmt.Sprintf("https://raw.githubusercontent.com/hyperledger/firefly/%s/manifest.json", version), nil, &manifest); err != nil {
 		return nil, err
 	}
 
@@ -86,3 +87,13 @@ func ReadManifestFile(p string) (*types.VersionManifest, error) {
 	}
 	return manifest, err
 }
+
+// SetDefaultImages fills in the entries of third party images that a manifest does not specify,
+// from a map of image references by manifest key. Entries that are not in the map are left unset.
+func SetDefaultImages(manifest *types.VersionManifest, images map[string]string) {
+	for key, entry := range manifest.ThirdPartyEntries() {
+		if image, ok := images[key]; ok && *entry == nil {
+			*entry = types.ParseManifestEntry(image)
+		}
+	}
+}
@@ -46,3 +46,20 @@ func TestGetLatestReleaseManifest(T *testing.T) {
 	assert.NotNil(T, manifest.TokensERC1155)
 	assert.NotNil(T, manifest.TokensERC20ERC721)
 }
+
+func TestSetDefaultImages(T *testing.T) {
+	manifest := &types.VersionManifest{
+		Postgres: &types.ManifestEntry{Image: "postgres", Tag: "13"},
+	}
+	SetDefaultImages(manifest, map[string]string{
+		"postgres": "postgres:14",
+		"ipfs":     "ipfs/go-ipfs:v0.10.0",
+		"sandbox":  "localhost:5000/sandbox",
+	})
+	assert.Equal(T, "postgres:13", manifest.Postgres.GetDockerImageString())
+	assert.Equal(T, "ipfs/go-ipfs", manifest.IPFS.Image)
+	assert.Equal(T, "v0.10.0", manifest.IPFS.Tag)
+	assert.Equal(T, "localhost:5000/sandbox", manifest.Sandbox.Image)
+	assert.Empty(T, manifest.Sandbox.Tag)
+	assert.Nil(T, manifest.Grafana)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+const Redacted = "***REDACTED***"
+
+// Token only counts as a secret at the end of a field name, so that fields such as tokens,
+// tokenProviders and tokenIndex in the FireFly config are left alone
+var redactedFieldRegex = regexp.MustCompile(`(?i)(password|passphrase|secret|privatekey|private_key|swarmkey|mnemonic|(^|_)((auth|access|api|bearer|refresh)_?)?token$)`)
+
+var envSecretRegex = regexp.MustCompile(`(?i)^([a-z0-9_]*(password|passphrase|secret|swarm_key|private_key)[a-z0-9_]*|([a-z0-9_]*_)?token)=.*$`)
+
+var urlPasswordRegex = regexp.MustCompile(`://([^:/@\s]+):([^@\s]+)@`)
+
+// JSON-RPC methods that take secrets as positional parameters, and the index of the secret
+var redactedRPCParams = map[string]int{
+	"personal_unlockAccount": 1,
+	"personal_importRawKey":  0,
+	"personal_newAccount":    0,
+}
+
+// RedactJSON returns the JSON document with any secret values replaced. If the input
+// is not valid JSON, it is returned unchanged.
+func RedactJSON(b []byte) string {
+	var body interface{}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return string(b)
+	}
+	body = RedactValue(body)
+	redacted, err := json.Marshal(body)
+	if err != nil {
+		return string(b)
+	}
+	return string(redacted)
+}
+
+// RedactString removes any password embedded in a URL in the string, or the value
+// of an environment variable assignment that looks like a secret
+func RedactString(s string) string {
+	if envSecretRegex.MatchString(s) {
+		return envSecretRegex.ReplaceAllString(s, "$1="+Redacted)
+	}
+	return urlPasswordRegex.ReplaceAllString(s, "://$1:"+Redacted+"@")
+}
+
+// RedactValue walks a generic map/slice structure, as parsed from JSON or YAML, replacing
+// any values of fields that look like secrets
+func RedactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if method, ok := v["method"].(string); ok {
+			if i, ok := redactedRPCParams[method]; ok {
+				if params, ok := v["params"].([]interface{}); ok && len(params) > i {
+					params[i] = Redacted
+				}
+			}
+		}
+		for key, value := range v {
+			if redactedFieldRegex.MatchString(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = RedactValue(value)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = RedactValue(value)
+		}
+		return v
+	case string:
+		return RedactString(v)
+	default:
+		return v
+	}
+}
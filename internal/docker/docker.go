@@ -22,12 +22,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"path"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/hyperledger/firefly-cli/internal/log"
+	"golang.org/x/term"
 )
 
 type (
@@ -87,12 +89,63 @@ func RunDockerComposeCommand(ctx context.Context, workingDir string, command ...
 	return err
 }
 
+// StreamDockerCommand runs a docker command and calls the handler with each line
+// of output from the command as it is received
+func StreamDockerCommand(ctx context.Context, workingDir string, handler func(line string), command ...string) error {
+	dockerCmd := exec.Command("docker", command...)
+	dockerCmd.Dir = workingDir
+	if log.VerbosityFromContext(ctx) {
+		fmt.Println(dockerCmd.String())
+	}
+	pr, pw := io.Pipe()
+	dockerCmd.Stdout = pw
+	dockerCmd.Stderr = pw
+	if err := dockerCmd.Start(); err != nil {
+		return err
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- dockerCmd.Wait()
+		pw.Close()
+	}()
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		handler(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		pr.Close()
+		return err
+	}
+	if err := <-errChan; err != nil {
+		return fmt.Errorf("%s [%d]", strings.Join(dockerCmd.Args, " "), dockerCmd.ProcessState.ExitCode())
+	}
+	return nil
+}
+
 func RunDockerCommandBuffered(ctx context.Context, workingDir string, command ...string) (string, error) {
 	dockerCmd := exec.Command("docker", command...)
 	dockerCmd.Dir = workingDir
 	return runCommand(ctx, dockerCmd)
 }
 
+// RunDockerCommandInteractive runs a docker command attached to the terminal of the CLI, such as a shell
+// in a container. For exec and run, a TTY is only allocated when the CLI itself is running in one.
+func RunDockerCommandInteractive(ctx context.Context, workingDir string, command ...string) error {
+	if len(command) > 0 && (command[0] == "exec" || command[0] == "run") && term.IsTerminal(int(os.Stdin.Fd())) {
+		command = append([]string{command[0], "-t"}, command[1:]...)
+	}
+	dockerCmd := exec.Command("docker", command...)
+	dockerCmd.Dir = workingDir
+	dockerCmd.Stdin = os.Stdin
+	dockerCmd.Stdout = os.Stdout
+	dockerCmd.Stderr = os.Stderr
+	if log.VerbosityFromContext(ctx) {
+		fmt.Println(dockerCmd.String())
+	}
+	return dockerCmd.Run()
+}
+
 func runCommand(ctx context.Context, cmd *exec.Cmd) (string, error) {
 	verbose := log.VerbosityFromContext(ctx)
 	isLogCmd, _ := ctx.Value(CtxIsLogCmd{}).(bool)
@@ -105,20 +158,23 @@ func runCommand(ctx context.Context, cmd *exec.Cmd) (string, error) {
 	errChan := make(chan error)
 	go pipeCommand(cmd, stdoutChan, stderrChan, errChan)
 
-outputCapture:
-	for {
+	// Read both pipes until they are closed, as a command that exits quickly can close stderr
+	// before all of its output on stdout has been read
+	for stdoutChan != nil || stderrChan != nil {
 		select {
 		case s, ok := <-stdoutChan:
+			if !ok {
+				stdoutChan = nil
+				continue
+			}
 			if isLogCmd || verbose {
-				if !ok {
-					break outputCapture
-				}
 				fmt.Print(s)
 			}
 			outputBuff.WriteString(s)
 		case s, ok := <-stderrChan:
 			if !ok {
-				break outputCapture
+				stderrChan = nil
+				continue
 			}
 			if verbose {
 				fmt.Print(s)
@@ -18,9 +18,10 @@ package docker
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
+	"strings"
 
-	"github.com/hyperledger/firefly-cli/internal/constants"
 	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
@@ -42,6 +43,15 @@ type ServiceDefinition struct {
 	ServiceName string
 	Service     *Service
 	VolumeNames []string
+	Metrics     *MetricsEndpoint
+}
+
+// MetricsEndpoint is where Prometheus scrapes the metrics of a service, in the scrape job
+// that groups the services running the same component
+type MetricsEndpoint struct {
+	Job  string
+	Port int
+	Path string
 }
 
 type Service struct {
@@ -60,12 +70,20 @@ type Service struct {
 	EntryPoint    []string                     `yaml:"entrypoint,omitempty"`
 	EnvFile       string                       `yaml:"env_file,omitempty"`
 	Expose        []int                        `yaml:"expose,omitempty"`
+	Networks      []string                     `yaml:"networks,omitempty"`
+	ExtraHosts    []string                     `yaml:"extra_hosts,omitempty"`
+	NetworkMode   string                       `yaml:"network_mode,omitempty"`
+}
+
+type Network struct {
+	External bool `yaml:"external,omitempty"`
 }
 
 type DockerComposeConfig struct {
 	Version  string              `yaml:"version,omitempty"`
 	Services map[string]*Service `yaml:"services,omitempty"`
 	Volumes  map[string]struct{} `yaml:"volumes,omitempty"`
+	Networks map[string]*Network `yaml:"networks,omitempty"`
 }
 
 var StandardLogOptions = &LoggingConfig{
@@ -99,19 +117,29 @@ func CreateDockerCompose(s *types.Stack) *DockerComposeConfig {
 				DependsOn: map[string]map[string]string{},
 				Logging:   StandardLogOptions,
 			}
-			compose.Services["firefly_core_"+member.ID].DependsOn["dataexchange_"+member.ID] = map[string]string{"condition": "service_started"}
-			compose.Services["firefly_core_"+member.ID].DependsOn["ipfs_"+member.ID] = map[string]string{"condition": "service_healthy"}
+			if s.TLSEnabled {
+				compose.Services["firefly_core_"+member.ID].Volumes = append(compose.Services["firefly_core_"+member.ID].Volumes, s.TLSVolume("firefly_core_"+member.ID))
+			}
+			if s.BasicAuthEnabled {
+				compose.Services["firefly_core_"+member.ID].Volumes = append(compose.Services["firefly_core_"+member.ID].Volumes, s.BasicAuthVolume(member.ID))
+			}
+			if s.SharedServicesEnabled() {
+				compose.Services["firefly_core_"+member.ID].DependsOn["dataexchange_"+member.ID] = map[string]string{"condition": "service_started"}
+				compose.Services["firefly_core_"+member.ID].DependsOn[s.IPFSServiceName(member.ID)] = map[string]string{"condition": "service_healthy"}
+			}
 		}
-		if s.Database == "postgres" {
-			compose.Services["postgres_"+member.ID] = &Service{
-				Image:         constants.PostgresImageName,
-				ContainerName: fmt.Sprintf("%s_postgres_%s", s.Name, member.ID),
+		// With shared infrastructure every member uses the same postgres (and IPFS node), which is only added once
+		postgresService := s.PostgresServiceName(member.ID)
+		if _, exists := compose.Services[postgresService]; s.Database == "postgres" && !exists {
+			compose.Services[postgresService] = &Service{
+				Image:         s.VersionManifest.Postgres.GetDockerImageString(),
+				ContainerName: fmt.Sprintf("%s_%s", s.Name, postgresService),
 				Ports:         []string{fmt.Sprintf("%d:5432", member.ExposedDatabasePort)},
 				Environment: map[string]interface{}{
-					"POSTGRES_PASSWORD": "f1refly",
+					"POSTGRES_PASSWORD": s.PostgresSuperuserPassword(member.ID),
 					"PGDATA":            "/var/lib/postgresql/data/pgdata",
 				},
-				Volumes: []string{fmt.Sprintf("postgres_%s:/var/lib/postgresql/data", member.ID)},
+				Volumes: []string{fmt.Sprintf("%s:/var/lib/postgresql/data", postgresService)},
 				HealthCheck: &HealthCheck{
 					Test:     []string{"CMD-SHELL", "pg_isready -U postgres"},
 					Interval: "5s",
@@ -120,62 +148,85 @@ func CreateDockerCompose(s *types.Stack) *DockerComposeConfig {
 				},
 				Logging: StandardLogOptions,
 			}
-			compose.Volumes[fmt.Sprintf("postgres_%s", member.ID)] = struct{}{}
-			if service, ok := compose.Services[fmt.Sprintf("firefly_core_%s", member.ID)]; ok {
-				service.DependsOn["postgres_"+member.ID] = map[string]string{"condition": "service_healthy"}
+			compose.Volumes[postgresService] = struct{}{}
+			if s.PrometheusEnabled {
+				compose.Services[s.PostgresExporterServiceName(member.ID)] = &Service{
+					Image:         s.VersionManifest.PostgresExporter.GetDockerImageString(),
+					ContainerName: fmt.Sprintf("%s_%s", s.Name, s.PostgresExporterServiceName(member.ID)),
+					Environment: map[string]interface{}{
+						"DATA_SOURCE_NAME": fmt.Sprintf("postgresql://postgres:%s@%s:5432/postgres?sslmode=disable", s.PostgresSuperuserPassword(member.ID), postgresService),
+					},
+					DependsOn: map[string]map[string]string{postgresService: {"condition": "service_healthy"}},
+					Logging:   StandardLogOptions,
+				}
 			}
 		}
-		sharedStorage := &Service{
-			Image:         constants.IPFSImageName,
-			ContainerName: fmt.Sprintf("%s_ipfs_%s", s.Name, member.ID),
-			Ports: []string{
-				fmt.Sprintf("%d:5001", member.ExposedIPFSApiPort),
-				fmt.Sprintf("%d:8080", member.ExposedIPFSGWPort),
-			},
-			Volumes: []string{
-				fmt.Sprintf("ipfs_staging_%s:/export", member.ID),
-				fmt.Sprintf("ipfs_data_%s:/data/ipfs", member.ID),
-			},
-			Logging: StandardLogOptions,
-			HealthCheck: &HealthCheck{
-				Test:     []string{"CMD-SHELL", `wget --post-data= http://127.0.0.1:5001/api/v0/id -O - -q`},
-				Interval: "5s",
-				Timeout:  "3s",
-				Retries:  12,
-			},
+		if service, ok := compose.Services[fmt.Sprintf("firefly_core_%s", member.ID)]; ok && s.Database == "postgres" {
+			service.DependsOn[postgresService] = map[string]string{"condition": "service_healthy"}
 		}
-		if s.IPFSMode.Equals(types.IPFSModePrivate) {
-			sharedStorage.Environment = map[string]interface{}{
-				"IPFS_SWARM_KEY":    s.SwarmKey,
-				"LIBP2P_FORCE_PNET": "1",
+		ipfsService := s.IPFSServiceName(member.ID)
+		if _, exists := compose.Services[ipfsService]; s.SharedServicesEnabled() && !exists {
+			volumeSuffix := strings.TrimPrefix(ipfsService, "ipfs")
+			sharedStorage := &Service{
+				Image:         s.VersionManifest.IPFS.GetDockerImageString(),
+				ContainerName: fmt.Sprintf("%s_%s", s.Name, ipfsService),
+				Ports: []string{
+					fmt.Sprintf("%d:5001", member.ExposedIPFSApiPort),
+					fmt.Sprintf("%d:8080", member.ExposedIPFSGWPort),
+				},
+				Volumes: []string{
+					fmt.Sprintf("ipfs_staging%s:/export", volumeSuffix),
+					fmt.Sprintf("ipfs_data%s:/data/ipfs", volumeSuffix),
+				},
+				Logging: StandardLogOptions,
+				HealthCheck: &HealthCheck{
+					Test:     []string{"CMD-SHELL", `wget --post-data= http://127.0.0.1:5001/api/v0/id -O - -q`},
+					Interval: "5s",
+					Timeout:  "3s",
+					Retries:  12,
+				},
+			}
+			if s.IPFSMode.Equals(types.IPFSModePrivate) {
+				sharedStorage.Environment = map[string]interface{}{
+					"IPFS_SWARM_KEY":    s.SwarmKey,
+					"LIBP2P_FORCE_PNET": "1",
+				}
 			}
+			compose.Services[ipfsService] = sharedStorage
+			compose.Volumes["ipfs_staging"+volumeSuffix] = struct{}{}
+			compose.Volumes["ipfs_data"+volumeSuffix] = struct{}{}
 		}
-		compose.Services["ipfs_"+member.ID] = sharedStorage
-		compose.Volumes[fmt.Sprintf("ipfs_staging_%s", member.ID)] = struct{}{}
-		compose.Volumes[fmt.Sprintf("ipfs_data_%s", member.ID)] = struct{}{}
-		compose.Services["dataexchange_"+member.ID] = &Service{
-			Image:         s.VersionManifest.DataExchange.GetDockerImageString(),
-			ContainerName: fmt.Sprintf("%s_dataexchange_%s", s.Name, member.ID),
-			Ports:         []string{fmt.Sprintf("%d:3000", member.ExposedDataexchangePort)},
-			Volumes:       []string{fmt.Sprintf("dataexchange_%s:/data", member.ID)},
-			Logging:       StandardLogOptions,
+		if s.SharedServicesEnabled() {
+			compose.Services["dataexchange_"+member.ID] = &Service{
+				Image:         s.VersionManifest.DataExchange.GetDockerImageString(),
+				ContainerName: fmt.Sprintf("%s_dataexchange_%s", s.Name, member.ID),
+				Ports:         []string{fmt.Sprintf("%d:3000", member.ExposedDataexchangePort)},
+				Volumes:       []string{fmt.Sprintf("dataexchange_%s:/data", member.ID)},
+				Logging:       StandardLogOptions,
+			}
+			compose.Volumes[fmt.Sprintf("dataexchange_%s", member.ID)] = struct{}{}
 		}
-		compose.Volumes[fmt.Sprintf("dataexchange_%s", member.ID)] = struct{}{}
 		if s.SandboxEnabled {
+			// The sandbox takes the credentials for the FireFly API from the userinfo of its endpoint URL
+			userInfo := ""
+			if auth := s.APICredentials(member.ID); auth != nil {
+				userInfo = url.UserPassword(auth.Username, auth.Password).String() + "@"
+			}
 			compose.Services["sandbox_"+member.ID] = &Service{
-				Image:         constants.SandboxImageName,
+				Image:         s.VersionManifest.Sandbox.GetDockerImageString(),
 				ContainerName: fmt.Sprintf("%s_sandbox_%s", s.Name, member.ID),
 				Ports:         []string{fmt.Sprintf("%d:3001", member.ExposedSandboxPort)},
 				Environment: map[string]interface{}{
-					"FF_ENDPOINT": fmt.Sprintf("http://firefly_core_%d:%d", *member.Index, member.ExposedFireflyPort),
+					"FF_ENDPOINT": fmt.Sprintf("%s://%sfirefly_core_%d:%d", s.Scheme(), userInfo, *member.Index, member.ExposedFireflyPort),
 				},
 			}
+			TrustStackCA(s, compose.Services["sandbox_"+member.ID])
 		}
 	}
 
 	if s.PrometheusEnabled {
 		compose.Services["prometheus"] = &Service{
-			Image:         constants.PrometheusImageName,
+			Image:         s.VersionManifest.Prometheus.GetDockerImageString(),
 			ContainerName: fmt.Sprintf("%s_prometheus", s.Name),
 			Ports:         []string{fmt.Sprintf("%d:9090", s.ExposedPrometheusPort)},
 			Volumes:       []string{"prometheus_data:/prometheus", "prometheus_config:/etc/prometheus"},
@@ -185,5 +236,85 @@ func CreateDockerCompose(s *types.Stack) *DockerComposeConfig {
 		compose.Volumes["prometheus_config"] = struct{}{}
 	}
 
+	if s.GrafanaEnabled {
+		// The datasource and dashboards are provisioned from the config directory. Grafana is only
+		// published on localhost, where it can be used without logging in to view the dashboards.
+		compose.Services["grafana"] = &Service{
+			Image:         s.VersionManifest.Grafana.GetDockerImageString(),
+			ContainerName: fmt.Sprintf("%s_grafana", s.Name),
+			Ports:         []string{fmt.Sprintf("127.0.0.1:%d:3000", s.ExposedGrafanaPort)},
+			Environment: map[string]interface{}{
+				"GF_AUTH_ANONYMOUS_ENABLED":  "true",
+				"GF_AUTH_ANONYMOUS_ORG_ROLE": "Viewer",
+				"GF_AUTH_DISABLE_LOGIN_FORM": "true",
+			},
+			Volumes: []string{
+				"grafana_data:/var/lib/grafana",
+				fmt.Sprintf("%s:/etc/grafana/provisioning:ro", filepath.Join(s.RuntimeDir, "config", "grafana")),
+			},
+			DependsOn: map[string]map[string]string{"prometheus": {"condition": "service_started"}},
+			Logging:   StandardLogOptions,
+		}
+		compose.Volumes["grafana_data"] = struct{}{}
+	}
+
+	if s.TracingEnabled {
+		compose.Services["otel_collector"] = &Service{
+			Image:         s.VersionManifest.OTelCollector.GetDockerImageString(),
+			ContainerName: fmt.Sprintf("%s_otel_collector", s.Name),
+			Command:       "--config=/etc/otelcol/config.yaml",
+			Volumes:       []string{fmt.Sprintf("%s:/etc/otelcol/config.yaml:ro", filepath.Join(s.RuntimeDir, "config", "otel-collector.yaml"))},
+			DependsOn:     map[string]map[string]string{"jaeger": {"condition": "service_started"}},
+			Logging:       StandardLogOptions,
+		}
+		compose.Services["jaeger"] = &Service{
+			Image:         s.VersionManifest.Jaeger.GetDockerImageString(),
+			ContainerName: fmt.Sprintf("%s_jaeger", s.Name),
+			Ports:         []string{fmt.Sprintf("%d:16686", s.ExposedJaegerPort)},
+			Environment:   map[string]interface{}{"COLLECTOR_OTLP_ENABLED": "true"},
+			Logging:       StandardLogOptions,
+		}
+	}
+
 	return compose
 }
+
+// TrustStackCA makes a Node.js based service trust the stack CA when TLS is enabled,
+// so that it can call the HTTPS endpoints of the stack
+func TrustStackCA(s *types.Stack, service *Service) {
+	if !s.TLSEnabled {
+		return
+	}
+	if service.Environment == nil {
+		service.Environment = make(map[string]interface{})
+	}
+	service.Environment["NODE_EXTRA_CA_CERTS"] = filepath.Join(types.TLSContainerDir, "ca.pem")
+	service.Volumes = append(service.Volumes, s.TLSCAVolume())
+}
+
+// TLSProxyPort is the port that the TLS proxy of a service that cannot serve HTTPS itself listens on
+const TLSProxyPort = 3443
+
+// ServeTLS puts a TLS proxy in front of a service that cannot serve HTTPS itself, when TLS is enabled. The
+// proxy shares the network of the service, so that it is reached by the name in the certificate of the
+// service, and the service publishes the port of the proxy in place of its own.
+func ServeTLS(s *types.Stack, serviceDefinition *ServiceDefinition, exposedPort, port int) *ServiceDefinition {
+	if !s.TLSEnabled {
+		return nil
+	}
+	serviceName := serviceDefinition.ServiceName
+	serviceDefinition.Service.Ports = []string{fmt.Sprintf("%d:%d", exposedPort, TLSProxyPort)}
+	return &ServiceDefinition{
+		ServiceName: serviceName + "_tls",
+		Service: &Service{
+			Image:         s.VersionManifest.TLSProxy.GetDockerImageString(),
+			ContainerName: serviceDefinition.Service.ContainerName + "_tls",
+			Command: fmt.Sprintf("server --listen 0.0.0.0:%d --target 127.0.0.1:%d --cert %s --key %s --disable-authentication",
+				TLSProxyPort, port, filepath.Join(types.TLSContainerDir, "cert.pem"), filepath.Join(types.TLSContainerDir, "key.pem")),
+			NetworkMode: "service:" + serviceName,
+			Volumes:     []string{s.TLSVolume(serviceName)},
+			DependsOn:   map[string]map[string]string{serviceName: {"condition": "service_started"}},
+			Logging:     StandardLogOptions,
+		},
+	}
+}
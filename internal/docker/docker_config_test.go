@@ -0,0 +1,120 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayModeStackIsTrimmed(T *testing.T) {
+	index := 0
+	newStack := func(sharedServices *bool) *types.Stack {
+		return &types.Stack{
+			Name:              "gateway",
+			Database:          types.DatabaseSelectionSQLite,
+			IPFSMode:          types.IPFSModePrivate,
+			SharedServicesPtr: sharedServices,
+			Members:           []*types.Organization{{ID: "0", Index: &index}},
+			VersionManifest: &types.VersionManifest{
+				FireFly:      &types.ManifestEntry{Image: "firefly"},
+				DataExchange: &types.ManifestEntry{Image: "dataexchange"},
+				IPFS:         &types.ManifestEntry{Image: "ipfs"},
+			},
+		}
+	}
+
+	disabled := false
+	compose := CreateDockerCompose(newStack(&disabled))
+	assert.Contains(T, compose.Services, "firefly_core_0")
+	assert.NotContains(T, compose.Services, "ipfs_0")
+	assert.NotContains(T, compose.Services, "dataexchange_0")
+	assert.Empty(T, compose.Services["firefly_core_0"].DependsOn)
+
+	// Stacks from before gateway mode was trimmed keep running IPFS and data exchange
+	compose = CreateDockerCompose(newStack(nil))
+	assert.Contains(T, compose.Services, "ipfs_0")
+	assert.Contains(T, compose.Services, "dataexchange_0")
+	assert.Contains(T, compose.Services["firefly_core_0"].DependsOn, "ipfs_0")
+}
+
+func TestSharedInfrastructure(T *testing.T) {
+	index0, index1 := 0, 1
+	stack := &types.Stack{
+		Name:                 "shared",
+		Database:             types.DatabaseSelectionPostgres,
+		IPFSMode:             types.IPFSModePublic,
+		SharedInfrastructure: true,
+		Members: []*types.Organization{
+			{ID: "0", Index: &index0, ExposedDatabasePort: 5104},
+			{ID: "1", Index: &index1, ExposedDatabasePort: 5104},
+		},
+		VersionManifest: &types.VersionManifest{
+			FireFly:      &types.ManifestEntry{Image: "firefly"},
+			DataExchange: &types.ManifestEntry{Image: "dataexchange"},
+			IPFS:         &types.ManifestEntry{Image: "ipfs"},
+			Postgres:     &types.ManifestEntry{Image: "postgres"},
+		},
+	}
+	compose := CreateDockerCompose(stack)
+	assert.Contains(T, compose.Services, "postgres")
+	assert.Contains(T, compose.Services, "ipfs")
+	assert.NotContains(T, compose.Services, "postgres_1")
+	assert.NotContains(T, compose.Services, "ipfs_1")
+	assert.Contains(T, compose.Services, "dataexchange_1")
+	assert.Contains(T, compose.Volumes, "ipfs_data")
+	assert.Contains(T, compose.Services["firefly_core_1"].DependsOn, "postgres")
+	assert.Contains(T, compose.Services["firefly_core_1"].DependsOn, "ipfs")
+
+	// In private mode each member keeps its own IPFS node
+	stack.IPFSMode = types.IPFSModePrivate
+	compose = CreateDockerCompose(stack)
+	assert.Contains(T, compose.Services, "ipfs_1")
+	assert.Contains(T, compose.Services, "postgres")
+}
+
+func TestGrafanaIsReadOnlyOnLocalhost(T *testing.T) {
+	index := 0
+	stack := &types.Stack{
+		Name:                  "metrics",
+		Database:              types.DatabaseSelectionSQLite,
+		RuntimeDir:            "/stacks/metrics/runtime",
+		PrometheusEnabled:     true,
+		GrafanaEnabled:        true,
+		ExposedPrometheusPort: 9090,
+		ExposedGrafanaPort:    3000,
+		Members:               []*types.Organization{{ID: "0", Index: &index}},
+		VersionManifest: &types.VersionManifest{
+			FireFly:      &types.ManifestEntry{Image: "firefly"},
+			DataExchange: &types.ManifestEntry{Image: "dataexchange"},
+			IPFS:         &types.ManifestEntry{Image: "ipfs"},
+			Prometheus:   &types.ManifestEntry{Image: "prometheus"},
+			Grafana:      &types.ManifestEntry{Image: "grafana", Tag: "9.1.0"},
+		},
+	}
+	compose := CreateDockerCompose(stack)
+	grafana := compose.Services["grafana"]
+	assert.Equal(T, "grafana:9.1.0", grafana.Image)
+	assert.Equal(T, []string{"127.0.0.1:3000:3000"}, grafana.Ports)
+	assert.Equal(T, "true", grafana.Environment["GF_AUTH_ANONYMOUS_ENABLED"])
+	assert.Equal(T, "Viewer", grafana.Environment["GF_AUTH_ANONYMOUS_ORG_ROLE"])
+	assert.Contains(T, grafana.Volumes, "/stacks/metrics/runtime/config/grafana:/etc/grafana/provisioning:ro")
+	assert.Contains(T, grafana.DependsOn, "prometheus")
+	assert.Contains(T, compose.Volumes, "grafana_data")
+}
@@ -0,0 +1,98 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const keyringService = "firefly-cli"
+
+// The OS keyring is accessed through the command line tools that ship with
+// each OS: "security" on macOS, and "secret-tool" (libsecret) on Linux
+
+func SetKeyringSecret(account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security only takes the password as an argument, so the command is given to it on stdin in
+		// interactive mode, to keep the secret out of the process list
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(securityCommandLine("add-generic-password", "-U", "-s", keyringService, "-a", account, "-w", secret))
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", fmt.Sprintf("FireFly CLI stack '%s'", account), "service", keyringService, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return keyringUnsupported()
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store secret in the OS keyring: %s %s", err, out)
+	}
+	if runtime.GOOS == "darwin" {
+		// security does not fail when a command it reads in interactive mode fails, so check that the secret was stored
+		if stored, err := GetKeyringSecret(account); err != nil || stored != secret {
+			return fmt.Errorf("failed to store secret in the OS keyring")
+		}
+	}
+	return nil
+}
+
+// securityCommandLine quotes the arguments of a command for the interactive mode of security
+func securityCommandLine(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+	}
+	return strings.Join(quoted, " ") + "\n"
+}
+
+func GetKeyringSecret(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", account)
+	default:
+		return "", keyringUnsupported()
+	}
+	out, err := cmd.Output()
+	if err != nil || len(out) == 0 {
+		return "", fmt.Errorf("failed to read the key for stack '%s' from the OS keyring", account)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func DeleteKeyringSecret(account string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", account)
+	case "linux":
+		cmd = exec.Command("secret-tool", "clear", "service", keyringService, "account", account)
+	default:
+		return keyringUnsupported()
+	}
+	return cmd.Run()
+}
+
+func keyringUnsupported() error {
+	return fmt.Errorf("the OS keyring is not supported on %s - please use passphrase encryption instead", runtime.GOOS)
+}
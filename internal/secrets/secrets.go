@@ -0,0 +1,191 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnvVar can be set to avoid being prompted for the passphrase of a stack
+const PassphraseEnvVar = "FF_SECRETS_PASSPHRASE"
+
+const encryptedPrefix = "enc:v1:"
+
+// SecretFields are the names of the JSON fields in stack.json and stackState.json
+// that are encrypted at rest
+var SecretFields = map[string]bool{
+	"privateKey":       true,
+	"swarmKey":         true,
+	"keystorePassword": true,
+	"databasePassword": true,
+	"apiPassword":      true,
+}
+
+// PassphrasePrompt is called to ask the user for the passphrase of a stack when it is not
+// set in the environment. The CLI replaces it with an interactive prompt.
+var PassphrasePrompt = func(stackName string, confirm bool) (string, error) {
+	return "", fmt.Errorf("stack '%s' has encrypted secrets - please set %s to the passphrase for the stack", stackName, PassphraseEnvVar)
+}
+
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+func RandomBytes(length int) ([]byte, error) {
+	b := make([]byte, length)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// GeneratePassword returns a random password, hex encoded so it is safe to use in URLs and config files
+func GeneratePassword() (string, error) {
+	b, err := RandomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetPassphrase returns the passphrase from the environment, or prompts for it
+func GetPassphrase(stackName string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := PassphrasePrompt(stackName, confirm)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return passphrase, nil
+}
+
+// DeriveKey derives a 256 bit AES key from a passphrase
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, 32)
+}
+
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, encryptedPrefix)
+}
+
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+	nonce, err := RandomBytes(c.aead.NonceSize())
+	if err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("invalid encrypted value")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt secrets - is the passphrase correct?")
+	}
+	return string(plaintext), nil
+}
+
+// MarshalEncrypted serializes v to indented JSON, with every secret field encrypted
+func (c *Cipher) MarshalEncrypted(v interface{}, indent string) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if err := c.walk(doc, c.Encrypt); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", indent)
+}
+
+// DecryptJSON returns the JSON document with every encrypted secret field decrypted
+func (c *Cipher) DecryptJSON(b []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if err := c.walk(doc, c.Decrypt); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func (c *Cipher) walk(v interface{}, fn func(string) (string, error)) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && SecretFields[key] {
+				result, err := fn(s)
+				if err != nil {
+					return err
+				}
+				v[key] = result
+			} else if err := c.walk(value, fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if err := c.walk(value, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
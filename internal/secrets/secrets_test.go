@@ -0,0 +1,65 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAccount struct {
+	Address    string `json:"address"`
+	PrivateKey string `json:"privateKey"`
+}
+
+func TestEncryptDecryptJSON(T *testing.T) {
+	key, err := DeriveKey("correct horse", []byte("salt"))
+	assert.NoError(T, err)
+	c, err := NewCipher(key)
+	assert.NoError(T, err)
+
+	accounts := []*testAccount{{Address: "0x1234", PrivateKey: "0xabcd"}}
+	b, err := c.MarshalEncrypted(accounts, "  ")
+	assert.NoError(T, err)
+	assert.NotContains(T, string(b), "0xabcd")
+	assert.Contains(T, string(b), "0x1234")
+
+	b, err = c.DecryptJSON(b)
+	assert.NoError(T, err)
+	var decrypted []*testAccount
+	assert.NoError(T, json.Unmarshal(b, &decrypted))
+	assert.Equal(T, accounts, decrypted)
+}
+
+func TestDecryptWrongKey(T *testing.T) {
+	key, _ := DeriveKey("correct horse", []byte("salt"))
+	c, _ := NewCipher(key)
+	encrypted, err := c.Encrypt("secret")
+	assert.NoError(T, err)
+
+	wrongKey, _ := DeriveKey("battery staple", []byte("salt"))
+	wrong, _ := NewCipher(wrongKey)
+	_, err = wrong.Decrypt(encrypted)
+	assert.Regexp(T, "is the passphrase correct", err)
+}
+
+func TestSecurityCommandLine(T *testing.T) {
+	assert.Equal(T, `"add-generic-password" "-a" "dev stack" "-w" "a\"b\\c="`+"\n",
+		securityCommandLine("add-generic-password", "-a", "dev stack", "-w", `a"b\c=`))
+}
@@ -0,0 +1,125 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/blockchain"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// addBlockchains adds the additional blockchains of a new stack, giving each a block of ports
+// above those of the members, and an account for each member
+func (s *StackManager) addBlockchains(options *types.InitOptions) error {
+	for i, input := range options.AdditionalBlockchains {
+		b, err := types.ParseBlockchainSelection(s.ctx, input)
+		if err != nil {
+			return err
+		}
+		chainID := options.ChainID + int64(i) + 1
+		b.ChainIDPtr = &chainID
+		nextPort := s.highestExposedPort() + 1
+		b.ExposedBlockchainPort = nextPort
+		nextPort++
+		b.Members = make([]*types.BlockchainMember, len(s.Stack.Members))
+		for j := range s.Stack.Members {
+			b.Members[j] = &types.BlockchainMember{ExposedConnectorPort: nextPort}
+			nextPort++
+			if options.PrometheusEnabled {
+				b.Members[j].ExposedConnectorMetricsPort = nextPort
+				nextPort++
+			}
+		}
+		s.Stack.Blockchains = append(s.Stack.Blockchains, b)
+
+		index := len(s.Stack.Blockchains)
+		provider := newBlockchainProvider(s.ctx, s.Stack.BlockchainStack(index))
+		for j, member := range s.Stack.Members {
+			if b.Members[j].Account, err = provider.CreateAccount([]string{member.OrgName, member.OrgName}); err != nil {
+				return err
+			}
+		}
+	}
+	s.loadBlockchainProviders()
+	return s.checkBlockchainServices()
+}
+
+// loadBlockchainProviders creates a provider for each blockchain of the stack, each working with its
+// own view of the stack. It must be called again whenever the state of the stack is replaced.
+func (s *StackManager) loadBlockchainProviders() {
+	s.blockchainStacks = []*types.Stack{s.Stack}
+	s.blockchainProviders = []blockchain.IBlockchainProvider{s.blockchainProvider}
+	for i := 1; i < s.Stack.BlockchainCount(); i++ {
+		stack := s.Stack.BlockchainStack(i)
+		s.blockchainStacks = append(s.blockchainStacks, stack)
+		s.blockchainProviders = append(s.blockchainProviders, newBlockchainProvider(s.ctx, stack))
+	}
+}
+
+// parseBlockchainAccounts converts the accounts of the members on the additional blockchains, as read from
+// stack.json, into the account types of their providers
+func (s *StackManager) parseBlockchainAccounts() {
+	for i, b := range s.Stack.Blockchains {
+		provider := newBlockchainProvider(s.ctx, s.Stack.BlockchainStack(i+1))
+		for _, member := range b.Members {
+			if account, ok := member.Account.(map[string]interface{}); ok {
+				member.Account = provider.ParseAccount(account)
+			}
+		}
+	}
+}
+
+// tokensStack returns the view of the stack for the token connectors on a blockchain, which unlike the view
+// of its blockchain provider shares the state of the stack, so that they find the contracts deployed for them
+func (s *StackManager) tokensStack(blockchainIndex int) *types.Stack {
+	if blockchainIndex == 0 {
+		return s.Stack
+	}
+	stack := *s.blockchainStacks[blockchainIndex]
+	stack.State = s.Stack.State
+	return &stack
+}
+
+// blockchainServiceDefinitions returns the services of every blockchain of the stack
+func (s *StackManager) blockchainServiceDefinitions() []*docker.ServiceDefinition {
+	serviceDefinitions := []*docker.ServiceDefinition{}
+	for _, provider := range s.blockchainProviders {
+		serviceDefinitions = append(serviceDefinitions, provider.GetDockerServiceDefinitions()...)
+	}
+	return serviceDefinitions
+}
+
+// checkBlockchainServices makes sure that no two blockchains of the stack need a service or volume with the
+// same name. The ethereum services of additional blockchains are named per blockchain, but a fabric network
+// is not, so a stack can only run one of them.
+func (s *StackManager) checkBlockchainServices() error {
+	owners := map[string]int{}
+	for i, provider := range s.blockchainProviders {
+		for _, serviceDefinition := range provider.GetDockerServiceDefinitions() {
+			names := append([]string{serviceDefinition.ServiceName}, serviceDefinition.VolumeNames...)
+			for _, name := range names {
+				if owner, ok := owners[name]; ok && owner != i {
+					return fmt.Errorf("%s and %s both need a service or volume named '%s'", types.BlockchainPluginName(owner), types.BlockchainPluginName(i), name)
+				}
+				owners[name] = i
+			}
+		}
+	}
+	return nil
+}
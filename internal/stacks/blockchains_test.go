@@ -0,0 +1,101 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/internal/blockchain/ethereum"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockchainStackView(T *testing.T) {
+	b, err := types.ParseBlockchainSelection(context.Background(), "ethereum:besu")
+	assert.NoError(T, err)
+	assert.Equal(T, types.BlockchainNodeProviderBesu, b.BlockchainNodeProvider)
+	assert.Equal(T, types.BlockchainConnectorEvmconnect, b.BlockchainConnector)
+	b.ExposedBlockchainPort = 5400
+	b.Members = []*types.BlockchainMember{{ExposedConnectorPort: 5401, Account: "besu_account"}}
+
+	stack := &types.Stack{
+		BlockchainProvider:     types.BlockchainProviderEthereum,
+		BlockchainNodeProvider: types.BlockchainNodeProviderGeth,
+		ContractAddress:        "0x1234",
+		Members:                []*types.Organization{{ID: "0", ExposedConnectorPort: 5102, Account: "geth_account"}},
+		Blockchains:            []*types.StackBlockchain{b},
+		State:                  &types.StackState{Accounts: []interface{}{"geth_account"}},
+	}
+	assert.Equal(T, 2, stack.BlockchainCount())
+	assert.Equal(T, stack, stack.BlockchainStack(0))
+
+	view := stack.BlockchainStack(1)
+	assert.Equal(T, "blockchain1", view.BlockchainDirName())
+	assert.Equal(T, types.BlockchainNodeProviderBesu, view.BlockchainNodeProvider)
+	assert.Empty(T, view.ContractAddress)
+	assert.Equal(T, 5401, view.Members[0].ExposedConnectorPort)
+	assert.Equal(T, []interface{}{"besu_account"}, view.State.Accounts)
+	// The view must not change the members of the stack itself
+	assert.Equal(T, 5102, stack.Members[0].ExposedConnectorPort)
+	assert.Equal(T, "blockchain", stack.BlockchainDirName())
+
+	_, err = types.ParseBlockchainSelection(context.Background(), "ethereum:remote-rpc")
+	assert.Error(T, err)
+	_, err = types.ParseBlockchainSelection(context.Background(), "fabric:geth")
+	assert.Error(T, err)
+}
+
+func TestAdditionalBlockchainServiceNames(T *testing.T) {
+	b, err := types.ParseBlockchainSelection(context.Background(), "ethereum:besu")
+	assert.NoError(T, err)
+	b.ExposedBlockchainPort = 5400
+	b.Members = []*types.BlockchainMember{{ExposedConnectorPort: 5401, Account: &ethereum.Account{Address: "0x5678"}}}
+
+	s := newTestStackManager(1, func(stack *types.Stack) {
+		stack.ExposedBlockchainPort = 5100
+		stack.Members[0].ExposedConnectorPort = 5102
+		stack.Blockchains = []*types.StackBlockchain{b}
+	})
+	assert.NoError(T, s.checkBlockchainServices())
+
+	// The services of the first blockchain keep their names, and those of the second are named per blockchain
+	compose := s.buildDockerCompose()
+	for _, name := range []string{"geth", "evmconnect_0", "besu_1", "ethsigner_1", "evmconnect_1_0"} {
+		assert.Contains(T, compose.Services, name)
+	}
+	for _, name := range []string{"geth", "evmconnect_config_0", "besu_1", "ethsigner_1", "ethsigner_config_1", "evmconnect_config_1_0", "evmconnect_leveldb_1_0"} {
+		assert.Contains(T, compose.Volumes, name)
+	}
+	assert.Equal(T, "dev_evmconnect_0", compose.Services["evmconnect_0"].ContainerName)
+	assert.Equal(T, "dev_evmconnect_1_0", compose.Services["evmconnect_1_0"].ContainerName)
+	assert.Equal(T, "dev_ethsigner_1", compose.Services["ethsigner_1"].ContainerName)
+	assert.Contains(T, compose.Services["evmconnect_0"].DependsOn, "geth")
+	assert.Contains(T, compose.Services["evmconnect_1_0"].DependsOn, "ethsigner_1")
+	assert.Equal(T, "http://evmconnect_1_0:5008", s.blockchainProviders[1].GetConnectorURL(s.blockchainStacks[1].Members[0]))
+
+	// Services of the additional blockchain are found by their logical names
+	services, err := s.FindServices(nil, []string{"signer"})
+	assert.NoError(T, err)
+	assert.Len(T, services, 1)
+	assert.Equal(T, "ethsigner_1", services[0].ServiceName)
+	assert.True(T, services[0].IsShared())
+	services, err = s.FindServices([]string{"0"}, []string{"connector"})
+	assert.NoError(T, err)
+	assert.Len(T, services, 2)
+	assert.Equal(T, "0", services[1].MemberID)
+}
@@ -0,0 +1,297 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/firefly-cli/internal/certs"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+type CertInfo struct {
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+	Path     string    `json:"path"`
+}
+
+// The CA lives outside of the init and runtime directories, so that it survives a reset
+// and certificates issued before and after a reset share the same root
+func (s *StackManager) caCertPath() string {
+	return filepath.Join(s.Stack.StackDir, "ca", "ca.pem")
+}
+
+func (s *StackManager) caKeyPath() string {
+	return filepath.Join(s.Stack.StackDir, "ca", "ca-key.pem")
+}
+
+// loadCA loads the root CA of the stack, creating it if the stack does not have one yet
+func (s *StackManager) loadCA() (*certs.CA, error) {
+	if _, err := os.Stat(s.caCertPath()); err == nil {
+		return certs.LoadCA(s.caCertPath(), s.caKeyPath())
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	s.Log.Info(fmt.Sprintf("creating root CA for stack '%s'", s.Stack.Name))
+	ca, err := certs.NewCA(fmt.Sprintf("%s_ca", s.Stack.Name))
+	if err != nil {
+		return nil, err
+	}
+	return ca, ca.Write(s.caCertPath(), s.caKeyPath())
+}
+
+// currentConfigDir is the directory holding the config that the stack will run with
+func (s *StackManager) currentConfigDir() (string, error) {
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil {
+		return "", err
+	}
+	if hasRunBefore {
+		return filepath.Join(s.Stack.RuntimeDir, "config"), nil
+	}
+	return filepath.Join(s.Stack.InitDir, "config"), nil
+}
+
+// issueDataExchangeCert issues the certificate a member's data exchange uses for mutual TLS with its peers,
+// alongside a copy of the CA certificate so that it trusts the certificates of every other member
+func (s *StackManager) issueDataExchangeCert(ca *certs.CA, configDir, memberID string) error {
+	memberDXDir := filepath.Join(configDir, "dataexchange_"+memberID)
+	subject := &certs.Subject{
+		CommonName:   "dataexchange_" + memberID,
+		Organization: s.dataExchangePeerID(memberDXDir, memberID),
+		DNSNames:     []string{"dataexchange_" + memberID, fmt.Sprintf("%s_dataexchange_%s", s.Stack.Name, memberID), "localhost", "host.docker.internal"},
+	}
+	if err := ca.IssueFiles(subject, filepath.Join(memberDXDir, "cert.pem"), filepath.Join(memberDXDir, "key.pem")); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(memberDXDir, "ca.pem"), ca.CertPEM(), 0644)
+}
+
+// dataExchangePeerID returns the organization of the data exchange certificate of a member, which data exchange
+// takes its peer ID from. A new certificate includes the stack name, so that the ID is unique across stacks. A
+// reissued certificate keeps the organization of the one it replaces, as the node registered in FireFly and the
+// peers of other stacks refer to the member by it.
+func (s *StackManager) dataExchangePeerID(memberDXDir, memberID string) string {
+	if cert, err := certs.ReadCertificate(filepath.Join(memberDXDir, "cert.pem")); err == nil && len(cert.Subject.Organization) > 0 {
+		return cert.Subject.Organization[0]
+	}
+	return fmt.Sprintf("%s_member_%s", s.Stack.Name, memberID)
+}
+
+// tlsServiceNames returns the services that serve HTTPS when TLS is enabled: FireFly core for each
+// member (including external ones, which read their certificates from the host), the ethereum
+// connectors, the signer and the token connectors, which serve it through a TLS proxy
+func (s *StackManager) tlsServiceNames() []string {
+	names := []string{}
+	for _, member := range s.Stack.Members {
+		names = append(names, "firefly_core_"+member.ID)
+	}
+	for _, serviceDefinition := range s.blockchainServiceDefinitions() {
+		name := serviceDefinition.ServiceName
+		if name == "ethsigner" || strings.HasPrefix(name, "ethsigner_") || strings.HasPrefix(name, "ethconnect_") || strings.HasPrefix(name, "evmconnect_") {
+			names = append(names, name)
+		}
+	}
+	for i, tp := range s.tokenProviders {
+		for _, serviceDefinition := range tp.GetDockerServiceDefinitions(s.tokenPlugins[i].Index) {
+			if tokensServiceRegex.MatchString(serviceDefinition.ServiceName) {
+				names = append(names, serviceDefinition.ServiceName)
+			}
+		}
+	}
+	return names
+}
+
+// issueTLSCert issues the server certificate for a service, with the names it is reached by from
+// other containers and from the host, alongside a copy of the CA certificate for its clients. The
+// directory is bind mounted into the container of the service, which may run as a different user
+// to the one that owns the files, so the key is readable by any user. The stack directory is only
+// accessible by its owner, so this does not expose the key to other users of the host.
+func (s *StackManager) issueTLSCert(ca *certs.CA, configDir, serviceName string) error {
+	serviceDir := filepath.Join(configDir, "tls", serviceName)
+	subject := &certs.Subject{
+		CommonName:   serviceName,
+		Organization: s.Stack.Name,
+		DNSNames:     []string{serviceName, "localhost", "host.docker.internal"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	keyFile := filepath.Join(serviceDir, "key.pem")
+	if err := ca.IssueFiles(subject, filepath.Join(serviceDir, "cert.pem"), keyFile); err != nil {
+		return err
+	}
+	if err := os.Chmod(keyFile, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(serviceDir, "ca.pem"), ca.CertPEM(), 0644)
+}
+
+// writeTLSCerts issues the server certificates for every service that serves HTTPS, and exports the CA bundle
+func (s *StackManager) writeTLSCerts() error {
+	configDir := filepath.Join(s.Stack.InitDir, "config")
+	ca, err := s.loadCA()
+	if err != nil {
+		return err
+	}
+	for _, serviceName := range s.tlsServiceNames() {
+		if err := s.issueTLSCert(ca, configDir, serviceName); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(filepath.Join(configDir, "tls", "ca.pem"), ca.CertPEM(), 0644)
+}
+
+// CACertPEM returns the CA certificate of the stack, which clients can use to trust its HTTPS endpoints
+func (s *StackManager) CACertPEM() ([]byte, error) {
+	if _, err := os.Stat(s.caCertPath()); os.IsNotExist(err) {
+		return nil, fmt.Errorf("stack '%s' does not have a root CA", s.Stack.Name)
+	}
+	return ioutil.ReadFile(s.caCertPath())
+}
+
+// ListCerts returns the root CA, the data exchange certificate of each member, and the server
+// certificates of the services that serve HTTPS if TLS is enabled
+func (s *StackManager) ListCerts() ([]*CertInfo, error) {
+	configDir, err := s.currentConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]string{}
+	names := []string{}
+	if _, err := os.Stat(s.caCertPath()); err == nil {
+		names = append(names, "ca")
+		paths["ca"] = s.caCertPath()
+	}
+	if s.Stack.SharedServicesEnabled() {
+		for _, member := range s.Stack.Members {
+			name := "dataexchange_" + member.ID
+			names = append(names, name)
+			paths[name] = filepath.Join(configDir, name, "cert.pem")
+		}
+	}
+	if s.Stack.TLSEnabled {
+		for _, serviceName := range s.tlsServiceNames() {
+			name := "tls/" + serviceName
+			names = append(names, name)
+			paths[name] = filepath.Join(configDir, "tls", serviceName, "cert.pem")
+		}
+	}
+
+	certInfos := make([]*CertInfo, 0, len(names))
+	for _, name := range names {
+		cert, err := certs.ReadCertificate(paths[name])
+		if err != nil {
+			return nil, err
+		}
+		certInfos = append(certInfos, &CertInfo{
+			Name:     name,
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+			Path:     paths[name],
+		})
+	}
+	return certInfos, nil
+}
+
+// RotateCerts reissues the data exchange certificates (and the server certificates if TLS is enabled) of
+// every member, or of a single member if one is specified. If the stack has been run, the new data exchange
+// certificates are copied into their volumes and the affected containers are restarted. The data exchange
+// certificates keep their organization, so the peer IDs that FireFly and other stacks know the members by do not change.
+func (s *StackManager) RotateCerts(memberNameOrID string) error {
+	members := s.Stack.Members
+	if !s.Stack.SharedServicesEnabled() {
+		// A gateway mode stack has no data exchange to issue certificates for
+		members = []*types.Organization{}
+	}
+	tlsServices := []string{}
+	if s.Stack.TLSEnabled {
+		tlsServices = s.tlsServiceNames()
+	}
+	if memberNameOrID != "" {
+		memberID, err := s.getMemberID(memberNameOrID)
+		if err != nil {
+			return err
+		}
+		memberDXs := []*types.Organization{}
+		for _, member := range members {
+			if member.ID == memberID {
+				memberDXs = append(memberDXs, member)
+			}
+		}
+		members = memberDXs
+		memberTLSServices := []string{}
+		for _, serviceName := range tlsServices {
+			if member := s.serviceMember(serviceName); member != nil && member.ID == memberID {
+				memberTLSServices = append(memberTLSServices, serviceName)
+			}
+		}
+		tlsServices = memberTLSServices
+	}
+
+	ca, err := s.loadCA()
+	if err != nil {
+		return err
+	}
+	configDir, err := s.currentConfigDir()
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		s.Log.Info(fmt.Sprintf("issuing data exchange certificate for member %s", member.ID))
+		if err := s.issueDataExchangeCert(ca, configDir, member.ID); err != nil {
+			return err
+		}
+	}
+	for _, serviceName := range tlsServices {
+		s.Log.Info(fmt.Sprintf("issuing TLS certificate for %s", serviceName))
+		if err := s.issueTLSCert(ca, configDir, serviceName); err != nil {
+			return err
+		}
+	}
+
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil || !hasRunBefore {
+		return err
+	}
+	services := []string{}
+	for _, member := range members {
+		if err := s.copyDataExchangeConfigToVolume(member); err != nil {
+			return err
+		}
+		services = append(services, "dataexchange_"+member.ID)
+	}
+	// The TLS certificates are bind mounted, so the containers just need to be restarted to pick them up
+	compose := s.buildDockerCompose()
+	for _, serviceName := range tlsServices {
+		if _, ok := compose.Services[serviceName]; ok {
+			services = append(services, serviceName)
+		}
+		if _, ok := compose.Services[serviceName+"_tls"]; ok {
+			services = append(services, serviceName+"_tls")
+		}
+	}
+	s.Log.Info("restarting services")
+	return s.runDockerComposeCommand(append([]string{"restart"}, services...)...)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/internal/certs"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokensServeTLS(T *testing.T) {
+	s := newTestStackManager(2, func(stack *types.Stack) {
+		stack.TokenProviders = []fftypes.FFEnum{types.TokenProviderERC1155}
+		stack.TLSEnabled = true
+		stack.Members[0].ExposedTokensPorts = []int{5108}
+		stack.Members[1].ExposedTokensPorts = []int{5208}
+		stack.Members[1].ExternalComponents = []string{types.ComponentTokens}
+	})
+
+	// The token connectors get a certificate, and those in docker serve it through a proxy on their port
+	assert.Contains(T, s.tlsServiceNames(), "tokens_0_0")
+	compose := s.buildDockerCompose()
+	assert.Equal(T, []string{"5108:3443"}, compose.Services["tokens_0_0"].Ports)
+	proxy := compose.Services["tokens_0_0_tls"]
+	assert.Equal(T, "ghostunnel", proxy.Image)
+	assert.Equal(T, "service:tokens_0_0", proxy.NetworkMode)
+	assert.Contains(T, proxy.Command, "--listen 0.0.0.0:3443 --target 127.0.0.1:3000")
+	assert.Equal(T, []string{"/stacks/dev/runtime/config/tls/tokens_0_0:/etc/firefly-tls:ro"}, proxy.Volumes)
+	assert.Empty(T, proxy.ExtraHosts)
+	assert.NotContains(T, compose.Services, "tokens_1_0")
+	assert.NotContains(T, compose.Services, "tokens_1_0_tls")
+
+	// FireFly core calls them over HTTPS and trusts the stack CA, except for those on the host
+	config := s.tokenProviders[0].GetFireflyConfig(s.Stack.Members[0], 0)
+	assert.Equal(T, "https://tokens_0_0:3443", config.FFTokens.URL)
+	assert.Equal(T, "/etc/firefly-tls/ca.pem", config.FFTokens.TLS.CAFile)
+	config = s.tokenProviders[0].GetFireflyConfig(s.Stack.Members[1], 0)
+	assert.Equal(T, "http://host.docker.internal:5208", config.FFTokens.URL)
+}
+
+func TestReissuedDataExchangeCertKeepsPeerID(T *testing.T) {
+	s := &StackManager{Stack: &types.Stack{Name: "dev"}}
+	ca, err := certs.NewCA("dev_ca")
+	assert.NoError(T, err)
+	configDir := T.TempDir()
+
+	// A new member takes a peer ID that is unique across stacks
+	assert.NoError(T, s.issueDataExchangeCert(ca, configDir, "0"))
+	cert, err := certs.ReadCertificate(filepath.Join(configDir, "dataexchange_0", "cert.pem"))
+	assert.NoError(T, err)
+	assert.Equal(T, []string{"dev_member_0"}, cert.Subject.Organization)
+
+	// A member of a stack created before peer IDs included the stack name keeps its ID when rotated
+	legacyCert := &certs.Subject{CommonName: "dataexchange_1", Organization: "member_1"}
+	memberDXDir := filepath.Join(configDir, "dataexchange_1")
+	assert.NoError(T, ca.IssueFiles(legacyCert, filepath.Join(memberDXDir, "cert.pem"), filepath.Join(memberDXDir, "key.pem")))
+	assert.NoError(T, s.issueDataExchangeCert(ca, configDir, "1"))
+	cert, err = certs.ReadCertificate(filepath.Join(memberDXDir, "cert.pem"))
+	assert.NoError(T, err)
+	assert.Equal(T, []string{"member_1"}, cert.Subject.Organization)
+	assert.Contains(T, cert.DNSNames, "dev_dataexchange_1")
+}
+
+func TestTLSKeysAreReadableInContainers(T *testing.T) {
+	stackDir := T.TempDir()
+	s := newTestStackManager(1, withStackDir(stackDir), func(stack *types.Stack) {
+		stack.BlockchainNodeProvider = types.BlockchainNodeProviderBesu
+		stack.TLSEnabled = true
+	})
+	assert.NoError(T, s.ensureInitDirectories())
+	assert.NoError(T, s.writeTLSCerts())
+
+	// Each service reads its certificate and key from its TLS directory, which is bind mounted read-only.
+	// The containers do not run as the user that owns the files, so the keys are readable by any user, and
+	// are kept private on the host by the stack directory.
+	compose := s.buildDockerCompose()
+	services := s.tlsServiceNames()
+	assert.ElementsMatch(T, []string{"firefly_core_0", "evmconnect_0", "ethsigner"}, services)
+	for _, serviceName := range services {
+		tlsDir := filepath.Join(s.Stack.RuntimeDir, "config", "tls", serviceName)
+		assert.Contains(T, compose.Services[serviceName].Volumes, tlsDir+":/etc/firefly-tls:ro", serviceName)
+		info, err := os.Stat(filepath.Join(s.Stack.InitDir, "config", "tls", serviceName, "key.pem"))
+		assert.NoError(T, err)
+		assert.Equal(T, os.FileMode(0644), info.Mode().Perm(), serviceName)
+	}
+	info, err := os.Stat(stackDir)
+	assert.NoError(T, err)
+	assert.Equal(T, os.FileMode(0700), info.Mode().Perm())
+	// The key of the CA stays readable only by the user
+	info, err = os.Stat(s.caKeyPath())
+	assert.NoError(T, err)
+	assert.Equal(T, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,643 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/configschema"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// The components whose runtime config can be read and changed with ff config
+const (
+	ConfigComponentCore      = "core"
+	ConfigComponentConnector = "connector"
+	ConfigComponentSigner    = "signer"
+	ConfigComponentDX        = "dx"
+)
+
+var ConfigComponents = []string{ConfigComponentCore, ConfigComponentConnector, ConfigComponentSigner, ConfigComponentDX}
+
+// configFile is the config file of a component of the stack. The signer is shared by every member,
+// so it has no member. The connector and the signer belong to one of the blockchains of the stack.
+// Changes made with ff config are also kept as an override, which is applied again whenever the
+// file is regenerated, so that a reset does not lose them.
+type configFile struct {
+	component string
+	member    *types.Organization
+	path      string
+	json      bool
+	service   string
+	volume    string
+	override  string
+	schema    string
+}
+
+// isBlockchainConfigComponent returns true for the components that each blockchain of the stack runs
+func isBlockchainConfigComponent(component string) bool {
+	return component == ConfigComponentConnector || component == ConfigComponentSigner
+}
+
+func (s *StackManager) getConfigFile(configDir, component string, member *types.Organization, blockchainIndex int) (*configFile, error) {
+	if blockchainIndex != 0 && !isBlockchainConfigComponent(component) {
+		return nil, fmt.Errorf("the %s config does not belong to a blockchain", component)
+	}
+	switch component {
+	case ConfigComponentCore:
+		return &configFile{
+			component: component,
+			member:    member,
+			path:      coreConfigPath(configDir, member),
+			service:   "firefly_core_" + member.ID,
+			override:  "core_" + member.ID,
+			schema:    configschema.Core,
+		}, nil
+	case ConfigComponentConnector:
+		view := s.blockchainStacks[blockchainIndex]
+		if !view.BlockchainProvider.Equals(types.BlockchainProviderEthereum) {
+			return nil, fmt.Errorf("the connector config can only be changed for ethereum blockchains")
+		}
+		connectorName := s.blockchainProviders[blockchainIndex].GetConnectorName()
+		service := view.BlockchainMemberServiceName(connectorName, member.ID)
+		return &configFile{
+			component: component,
+			member:    member,
+			path:      filepath.Join(configDir, service+".yaml"),
+			service:   service,
+			volume:    fmt.Sprintf("%s_%s", s.Stack.Name, view.BlockchainMemberServiceName(connectorName+"_config", member.ID)),
+			override:  view.BlockchainMemberServiceName("connector", member.ID),
+			schema:    connectorName,
+		}, nil
+	case ConfigComponentSigner:
+		view := s.blockchainStacks[blockchainIndex]
+		service := view.BlockchainServiceName("ethsigner")
+		hasSigner := false
+		for _, serviceDefinition := range s.blockchainProviders[blockchainIndex].GetDockerServiceDefinitions() {
+			hasSigner = hasSigner || serviceDefinition.ServiceName == service
+		}
+		if !hasSigner {
+			if blockchainIndex != 0 {
+				return nil, fmt.Errorf("blockchain %d of stack '%s' does not run a signer", blockchainIndex, s.Stack.Name)
+			}
+			return nil, fmt.Errorf("stack '%s' does not run a signer", s.Stack.Name)
+		}
+		return &configFile{
+			component: component,
+			path:      filepath.Join(configDir, service+".yaml"),
+			service:   service,
+			volume:    fmt.Sprintf("%s_%s", s.Stack.Name, view.BlockchainServiceName("ethsigner_config")),
+			override:  view.BlockchainServiceName("signer"),
+			schema:    configschema.Ethsigner,
+		}, nil
+	case ConfigComponentDX:
+		if !s.Stack.SharedServicesEnabled() {
+			return nil, fmt.Errorf("stack '%s' does not run data exchange", s.Stack.Name)
+		}
+		return &configFile{
+			component: component,
+			member:    member,
+			path:      filepath.Join(configDir, "dataexchange_"+member.ID, "config.json"),
+			json:      true,
+			service:   "dataexchange_" + member.ID,
+			override:  "dataexchange_" + member.ID,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown component '%s' - options are: %s", component, strings.Join(ConfigComponents, ", "))
+	}
+}
+
+// findConfigFile returns the current config file of a component of a member of the stack, on one of its
+// blockchains for the connector and the signer
+func (s *StackManager) findConfigFile(component, memberNameOrID string, blockchainIndex int) (*configFile, error) {
+	if blockchainIndex < 0 || blockchainIndex >= s.Stack.BlockchainCount() {
+		return nil, fmt.Errorf("blockchain %d does not exist in stack '%s'", blockchainIndex, s.Stack.Name)
+	}
+	configDir, err := s.currentConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.getMember(memberNameOrID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getConfigFile(configDir, component, member, blockchainIndex)
+}
+
+func (f *configFile) read() (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{}
+	if f.json {
+		err = json.Unmarshal(b, &config)
+	} else {
+		err = yaml.Unmarshal(b, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", f.path, err)
+	}
+	return config, nil
+}
+
+func (f *configFile) marshal(config map[string]interface{}) ([]byte, error) {
+	if f.json {
+		return json.MarshalIndent(config, "", "  ")
+	}
+	return yaml.Marshal(config)
+}
+
+// validateConfig checks that a changed config can still be loaded by its component, returning
+// any warnings about settings the component will ignore
+func (s *StackManager) validateConfig(f *configFile, config map[string]interface{}) ([]*configschema.Problem, error) {
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if f.component == ConfigComponentCore {
+		var coreConfig *types.FireflyConfig
+		if err := yaml.Unmarshal(b, &coreConfig); err != nil {
+			return nil, fmt.Errorf("invalid FireFly core config: %s", err)
+		}
+	}
+	if f.schema == "" {
+		return nil, nil
+	}
+	problems, err := configschema.Validate(f.schema, b, f.path, s.schemaVersion(f.schema))
+	if err != nil || !configschema.HasErrors(problems) {
+		return problems, err
+	}
+	messages := []string{}
+	for _, problem := range problems {
+		if problem.Severity == configschema.SeverityError {
+			messages = append(messages, problem.Message)
+		}
+	}
+	return nil, fmt.Errorf("invalid %s config: %s", f.component, strings.Join(messages, "; "))
+}
+
+// schemaVersion returns the version of a component in the version manifest, which selects the schema its config is checked against
+func (s *StackManager) schemaVersion(schema string) string {
+	var entry *types.ManifestEntry
+	if manifest := s.Stack.VersionManifest; manifest != nil {
+		switch schema {
+		case configschema.Core:
+			entry = manifest.FireFly
+		case configschema.Evmconnect:
+			entry = manifest.Evmconnect
+		case configschema.Ethconnect:
+			entry = manifest.Ethconnect
+		case configschema.Ethsigner:
+			entry = manifest.Signer
+		}
+	}
+	if entry == nil {
+		return ""
+	}
+	return entry.Tag
+}
+
+// validateConfigFiles checks the generated config of FireFly core, the ethereum connectors and the signer,
+// after any overlays and overrides are merged in, so that mistakes in them show up before the stack starts
+func (s *StackManager) validateConfigFiles(configDir string) error {
+	files := []*configFile{}
+	for _, member := range s.Stack.Members {
+		files = append(files, &configFile{path: coreConfigPath(configDir, member), schema: configschema.Core})
+	}
+	for i, provider := range s.blockchainProviders {
+		view := s.blockchainStacks[i]
+		if !view.BlockchainProvider.Equals(types.BlockchainProviderEthereum) {
+			continue
+		}
+		connectorName := provider.GetConnectorName()
+		for _, member := range s.Stack.Members {
+			files = append(files, &configFile{path: filepath.Join(configDir, view.BlockchainMemberServiceName(connectorName, member.ID)+".yaml"), schema: connectorName})
+		}
+		for _, serviceDefinition := range provider.GetDockerServiceDefinitions() {
+			if serviceDefinition.ServiceName == view.BlockchainServiceName("ethsigner") {
+				files = append(files, &configFile{path: filepath.Join(configDir, serviceDefinition.ServiceName+".yaml"), schema: configschema.Ethsigner})
+			}
+		}
+	}
+
+	hasErrors := false
+	for _, f := range files {
+		if _, err := os.Stat(f.path); os.IsNotExist(err) {
+			continue
+		}
+		problems, err := configschema.ValidateFile(f.schema, f.path, s.schemaVersion(f.schema))
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			if problem.Severity == configschema.SeverityError {
+				s.Log.Info(problem.String())
+			} else {
+				s.Log.Warn(problem.String())
+			}
+		}
+		hasErrors = hasErrors || configschema.HasErrors(problems)
+	}
+	if hasErrors {
+		return fmt.Errorf("the config of stack '%s' has errors - check the files listed above", s.Stack.Name)
+	}
+	return nil
+}
+
+func (f *configFile) write(config map[string]interface{}) error {
+	b, err := f.marshal(config)
+	if err != nil {
+		return err
+	}
+	// Some config files are bind mounted into containers that do not run as the user, so they stay readable
+	return ioutil.WriteFile(f.path, b, 0644)
+}
+
+func (s *StackManager) configOverridePath(name string) string {
+	return filepath.Join(s.Stack.StackDir, "overrides", name+".yaml")
+}
+
+func (s *StackManager) readConfigOverride(name string) (map[string]interface{}, error) {
+	override := map[string]interface{}{}
+	b, err := ioutil.ReadFile(s.configOverridePath(name))
+	if os.IsNotExist(err) {
+		return override, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &override); err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+func (s *StackManager) writeConfigOverride(name string, override map[string]interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(s.configOverridePath(name)), 0755); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(override)
+	if err != nil {
+		return err
+	}
+	// Overrides are only read by the CLI, and can hold credentials
+	return ioutil.WriteFile(s.configOverridePath(name), b, 0600)
+}
+
+// addConfigOverride merges changes into the override of a config file
+func (s *StackManager) addConfigOverride(name string, changes map[string]interface{}) error {
+	override, err := s.readConfigOverride(name)
+	if err != nil {
+		return err
+	}
+	mergeConfig(override, changes)
+	return s.writeConfigOverride(name, override)
+}
+
+// applyConfigOverrides merges the overrides of the stack into its config files in a directory, for
+// all components or only the ones given
+func (s *StackManager) applyConfigOverrides(configDir string, components ...string) error {
+	if len(components) == 0 {
+		components = ConfigComponents
+	}
+	for _, component := range components {
+		members := s.Stack.Members
+		if component == ConfigComponentSigner {
+			members = members[:1]
+		}
+		blockchainCount := 1
+		if isBlockchainConfigComponent(component) {
+			blockchainCount = s.Stack.BlockchainCount()
+		}
+		for blockchainIndex := 0; blockchainIndex < blockchainCount; blockchainIndex++ {
+			for _, member := range members {
+				f, err := s.getConfigFile(configDir, component, member, blockchainIndex)
+				if err != nil {
+					// The stack (or this blockchain) does not have this component
+					continue
+				}
+				if err := s.applyConfigOverride(f); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *StackManager) applyConfigOverride(f *configFile) error {
+	if _, err := os.Stat(s.configOverridePath(f.override)); os.IsNotExist(err) {
+		return nil
+	}
+	override, err := s.readConfigOverride(f.override)
+	if err != nil {
+		return err
+	}
+	config, err := f.read()
+	if err != nil {
+		return err
+	}
+	mergeConfig(config, override)
+	return f.write(config)
+}
+
+// setMemberCoreConfig keeps the extra core config of a member, given at init, as its override
+func (s *StackManager) setMemberCoreConfig(memberID, configPath string) error {
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	override := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &override); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", configPath, err)
+	}
+	return s.writeConfigOverride("core_"+memberID, override)
+}
+
+// GetConfig returns the config of a component of a member, or a single value from it if a key
+// (a dot separated path, with numbers indexing lists) is given
+func (s *StackManager) GetConfig(component, memberNameOrID string, blockchainIndex int, key string) ([]byte, error) {
+	f, err := s.findConfigFile(component, memberNameOrID, blockchainIndex)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return ioutil.ReadFile(f.path)
+	}
+	config, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	value, err := getConfigValue(config, key)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// SetConfig sets a value, parsed as YAML, in the config of a component of a member
+func (s *StackManager) SetConfig(component, memberNameOrID string, blockchainIndex int, key, value string) error {
+	f, err := s.findConfigFile(component, memberNameOrID, blockchainIndex)
+	if err != nil {
+		return err
+	}
+	var parsedValue interface{}
+	if err := yaml.Unmarshal([]byte(value), &parsedValue); err != nil {
+		return fmt.Errorf("invalid value '%s': %s", value, err)
+	}
+	config, err := f.read()
+	if err != nil {
+		return err
+	}
+	if err := setConfigValue(config, key, parsedValue); err != nil {
+		return err
+	}
+	// A list is kept in the override as a whole, as there is no way to merge a single item into it
+	overrideKey := listPrefix(config, key)
+	overrideValue, _ := getConfigValue(config, overrideKey)
+	changes := map[string]interface{}{}
+	if err := setConfigValue(changes, overrideKey, overrideValue); err != nil {
+		return err
+	}
+	return s.saveConfig(f, config, changes)
+}
+
+// ReplaceConfig replaces the config of a component of a member with an edited copy. The settings that
+// were added or changed are kept as an override, but removed settings are not, so their names are returned.
+func (s *StackManager) ReplaceConfig(component, memberNameOrID string, blockchainIndex int, content []byte) (removed []string, err error) {
+	f, err := s.findConfigFile(component, memberNameOrID, blockchainIndex)
+	if err != nil {
+		return nil, err
+	}
+	oldConfig, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	newConfig := map[string]interface{}{}
+	if f.json {
+		err = json.Unmarshal(content, &newConfig)
+	} else {
+		err = yaml.Unmarshal(content, &newConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the edited config: %s", err)
+	}
+	changes, removed := configChanges(oldConfig, newConfig, "")
+	return removed, s.saveConfig(f, newConfig, changes)
+}
+
+// ConfigFilePath returns the path of the current config file of a component of a member
+func (s *StackManager) ConfigFilePath(component, memberNameOrID string, blockchainIndex int) (string, error) {
+	f, err := s.findConfigFile(component, memberNameOrID, blockchainIndex)
+	if err != nil {
+		return "", err
+	}
+	return f.path, nil
+}
+
+func (s *StackManager) saveConfig(f *configFile, config, changes map[string]interface{}) error {
+	problems, err := s.validateConfig(f, config)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		s.Log.Warn(problem.Message)
+	}
+	if err := f.write(config); err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		if err := s.addConfigOverride(f.override, changes); err != nil {
+			return err
+		}
+	}
+	return s.reloadConfig(f)
+}
+
+// reloadConfig makes the container of a component pick up its changed config, by copying it into
+// the volume it reads it from if it does not read it from the stack directory, and restarting it
+func (s *StackManager) reloadConfig(f *configFile) error {
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil || !hasRunBefore {
+		// The config will be used when the stack is first started
+		return err
+	}
+	switch f.component {
+	case ConfigComponentCore:
+		if f.member.External {
+			s.Log.Info(fmt.Sprintf("please restart your firefly core for member %s to pick up the new config", f.member.ID))
+			return nil
+		}
+	case ConfigComponentConnector:
+		if err := docker.CopyFileToVolume(s.ctx, f.volume, f.path, "config.yaml"); err != nil {
+			return err
+		}
+	case ConfigComponentSigner:
+		if err := docker.CopyFileToVolume(s.ctx, f.volume, f.path, "firefly.ffsigner"); err != nil {
+			return err
+		}
+	case ConfigComponentDX:
+		if err := s.copyDataExchangeConfigToVolume(f.member); err != nil {
+			return err
+		}
+	}
+	s.Log.Info(fmt.Sprintf("restarting %s", f.service))
+	return s.runDockerComposeCommand("restart", f.service)
+}
+
+func splitConfigKey(key string) []string {
+	return strings.Split(key, ".")
+}
+
+func getConfigValue(config interface{}, key string) (interface{}, error) {
+	if key == "" {
+		return config, nil
+	}
+	value := config
+	for _, part := range splitConfigKey(key) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("config key '%s' is not set", key)
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("config key '%s' is not set", key)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("config key '%s' is not set", key)
+		}
+	}
+	return value, nil
+}
+
+// setConfigValue sets a value in a config, creating any maps along the way. Lists can only be indexed within their length.
+func setConfigValue(config map[string]interface{}, key string, value interface{}) error {
+	if key == "" {
+		return fmt.Errorf("a config key is required")
+	}
+	parts := splitConfigKey(key)
+	var parent interface{} = config
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if last {
+				p[part] = value
+				return nil
+			}
+			child, ok := p[part].(map[string]interface{})
+			if list, isList := p[part].([]interface{}); isList {
+				parent = list
+				continue
+			}
+			if !ok {
+				child = map[string]interface{}{}
+				p[part] = child
+			}
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(p) {
+				return fmt.Errorf("'%s' is not a valid index in config key '%s'", part, key)
+			}
+			if last {
+				p[index] = value
+				return nil
+			}
+			if _, ok := p[index].(map[string]interface{}); !ok {
+				if _, ok := p[index].([]interface{}); !ok {
+					p[index] = map[string]interface{}{}
+				}
+			}
+			parent = p[index]
+		}
+	}
+	return nil
+}
+
+// listPrefix returns the part of a key before the first list it indexes, or the whole key if it indexes no list
+func listPrefix(config map[string]interface{}, key string) string {
+	parts := splitConfigKey(key)
+	var value interface{} = config
+	for i, part := range parts {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return strings.Join(parts[:i], ".")
+		}
+		value = m[part]
+		if _, isList := value.([]interface{}); isList {
+			return strings.Join(parts[:i+1], ".")
+		}
+	}
+	return key
+}
+
+// mergeConfig merges an override into a config. Maps are merged, anything else is replaced.
+func mergeConfig(config, override map[string]interface{}) {
+	for k, v := range override {
+		if overrideMap, ok := v.(map[string]interface{}); ok {
+			if configMap, ok := config[k].(map[string]interface{}); ok {
+				mergeConfig(configMap, overrideMap)
+				continue
+			}
+		}
+		config[k] = v
+	}
+}
+
+// configChanges returns the settings that were added or changed between two configs, as an override, and the
+// keys of the settings that were removed
+func configChanges(oldConfig, newConfig map[string]interface{}, prefix string) (changes map[string]interface{}, removed []string) {
+	changes = map[string]interface{}{}
+	for k, newValue := range newConfig {
+		oldValue, ok := oldConfig[k]
+		oldMap, oldIsMap := oldValue.(map[string]interface{})
+		newMap, newIsMap := newValue.(map[string]interface{})
+		switch {
+		case ok && oldIsMap && newIsMap:
+			childChanges, childRemoved := configChanges(oldMap, newMap, prefix+k+".")
+			if len(childChanges) > 0 {
+				changes[k] = childChanges
+			}
+			removed = append(removed, childRemoved...)
+		case !ok || !reflect.DeepEqual(oldValue, newValue):
+			changes[k] = newValue
+		}
+	}
+	for k := range oldConfig {
+		if _, ok := newConfig[k]; !ok {
+			removed = append(removed, prefix+k)
+		}
+	}
+	sort.Strings(removed)
+	return changes, removed
+}
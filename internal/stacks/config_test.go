@@ -0,0 +1,134 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/internal/blockchain/ethereum"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigOverrides(T *testing.T) {
+	config := map[string]interface{}{}
+	assert.NoError(T, yaml.Unmarshal([]byte(`log:
+  level: debug
+http:
+  port: 5000
+plugins:
+  blockchain:
+  - name: blockchain0
+    type: ethereum
+`), &config))
+
+	assert.NoError(T, setConfigValue(config, "log.level", "info"))
+	assert.NoError(T, setConfigValue(config, "plugins.blockchain.0.type", "fabric"))
+	assert.Error(T, setConfigValue(config, "plugins.blockchain.1.type", "fabric"))
+	value, err := getConfigValue(config, "plugins.blockchain.0.type")
+	assert.NoError(T, err)
+	assert.Equal(T, "fabric", value)
+	assert.Equal(T, "plugins.blockchain", listPrefix(config, "plugins.blockchain.0.type"))
+	assert.Equal(T, "log.level", listPrefix(config, "log.level"))
+
+	edited := map[string]interface{}{
+		"log":     map[string]interface{}{"level": "info"},
+		"http":    map[string]interface{}{"port": 5010},
+		"plugins": config["plugins"],
+		"ui":      map[string]interface{}{"enabled": false},
+	}
+	changes, removed := configChanges(map[string]interface{}{
+		"log":     map[string]interface{}{"level": "info", "filename": "firefly.log"},
+		"http":    map[string]interface{}{"port": 5000},
+		"plugins": config["plugins"],
+	}, edited, "")
+	assert.Equal(T, map[string]interface{}{
+		"http": map[string]interface{}{"port": 5010},
+		"ui":   map[string]interface{}{"enabled": false},
+	}, changes)
+	assert.Equal(T, []string{"log.filename"}, removed)
+
+	// A regenerated config gets the overrides merged back in
+	regenerated := map[string]interface{}{"http": map[string]interface{}{"port": 5000, "address": "0.0.0.0"}}
+	mergeConfig(regenerated, changes)
+	assert.Equal(T, map[string]interface{}{
+		"http": map[string]interface{}{"port": 5010, "address": "0.0.0.0"},
+		"ui":   map[string]interface{}{"enabled": false},
+	}, regenerated)
+}
+
+func TestConfigFilesPerBlockchain(T *testing.T) {
+	b, err := types.ParseBlockchainSelection(context.Background(), "ethereum:besu")
+	assert.NoError(T, err)
+	b.Members = []*types.BlockchainMember{{Account: &ethereum.Account{Address: "0x5678"}}}
+	s := newTestStackManager(1, withStackDir(T.TempDir()), func(stack *types.Stack) {
+		stack.Blockchains = []*types.StackBlockchain{b}
+	})
+	member := s.Stack.Members[0]
+	configDir := filepath.Join(s.Stack.StackDir, "init", "config")
+
+	// The files of the first blockchain keep their names and overrides, and those of the second are named per blockchain
+	f, err := s.getConfigFile(configDir, ConfigComponentConnector, member, 0)
+	assert.NoError(T, err)
+	assert.Equal(T, filepath.Join(configDir, "evmconnect_0.yaml"), f.path)
+	assert.Equal(T, "evmconnect_0", f.service)
+	assert.Equal(T, "dev_evmconnect_config_0", f.volume)
+	assert.Equal(T, "connector_0", f.override)
+	f, err = s.getConfigFile(configDir, ConfigComponentConnector, member, 1)
+	assert.NoError(T, err)
+	assert.Equal(T, filepath.Join(configDir, "evmconnect_1_0.yaml"), f.path)
+	assert.Equal(T, "evmconnect_1_0", f.service)
+	assert.Equal(T, "dev_evmconnect_config_1_0", f.volume)
+	assert.Equal(T, "connector_1_0", f.override)
+
+	_, err = s.getConfigFile(configDir, ConfigComponentSigner, member, 0)
+	assert.Regexp(T, "stack 'dev' does not run a signer", err)
+	f, err = s.getConfigFile(configDir, ConfigComponentSigner, member, 1)
+	assert.NoError(T, err)
+	assert.Equal(T, filepath.Join(configDir, "ethsigner_1.yaml"), f.path)
+	assert.Equal(T, "ethsigner_1", f.service)
+	assert.Equal(T, "dev_ethsigner_config_1", f.volume)
+	assert.Equal(T, "signer_1", f.override)
+
+	_, err = s.getConfigFile(configDir, ConfigComponentCore, member, 1)
+	assert.Regexp(T, "the core config does not belong to a blockchain", err)
+	_, err = s.findConfigFile(ConfigComponentConnector, "0", 2)
+	assert.Regexp(T, "blockchain 2 does not exist in stack 'dev'", err)
+
+	// Overrides of the connector of the second blockchain are applied to its regenerated config
+	assert.NoError(T, os.MkdirAll(configDir, 0755))
+	connectorConfig := filepath.Join(configDir, "evmconnect_1_0.yaml")
+	assert.NoError(T, ioutil.WriteFile(connectorConfig, []byte("log:\n  level: info\n"), 0644))
+	assert.NoError(T, s.addConfigOverride("connector_1_0", map[string]interface{}{"log": map[string]interface{}{"level": "debug"}}))
+	assert.NoError(T, s.applyConfigOverrides(configDir, ConfigComponentConnector))
+	content, err := ioutil.ReadFile(connectorConfig)
+	assert.NoError(T, err)
+	assert.Equal(T, "log:\n    level: debug\n", string(content))
+
+	// The overrides can hold credentials, so only the user can read them
+	info, err := os.Stat(s.configOverridePath("connector_1_0"))
+	assert.NoError(T, err)
+	assert.Equal(T, os.FileMode(0600), info.Mode().Perm())
+	info, err = os.Stat(connectorConfig)
+	assert.NoError(T, err)
+	assert.Equal(T, os.FileMode(0644), info.Mode().Perm())
+}
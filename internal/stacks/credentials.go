@@ -0,0 +1,295 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/secrets"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// generateCredentials creates random keystore and database passwords for the stack, and for
+// each member if perMember is set. Keystore passwords for each account are generated as the
+// accounts are created. If basic auth is enabled, each member also gets a user for its APIs.
+func (s *StackManager) generateCredentials(perMember bool) (*types.StackCredentials, error) {
+	keystorePassword, err := secrets.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+	databasePassword, err := secrets.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+	credentials := &types.StackCredentials{
+		Credentials: types.Credentials{
+			KeystorePassword: keystorePassword,
+			DatabasePassword: databasePassword,
+		},
+		PerMember: perMember,
+	}
+	if perMember {
+		credentials.Accounts = make(map[string]*types.Credentials)
+	}
+	if perMember || s.Stack.BasicAuthEnabled {
+		credentials.Members = make(map[string]*types.Credentials)
+		for i := range s.Stack.Members {
+			memberCredentials := &types.Credentials{}
+			if perMember {
+				if memberCredentials.DatabasePassword, err = secrets.GeneratePassword(); err != nil {
+					return nil, err
+				}
+			}
+			if s.Stack.BasicAuthEnabled {
+				memberCredentials.APIUsername = "firefly"
+				if memberCredentials.APIPassword, err = secrets.GeneratePassword(); err != nil {
+					return nil, err
+				}
+			}
+			credentials.Members[fmt.Sprint(i)] = memberCredentials
+		}
+	}
+	return credentials, nil
+}
+
+// writeBasicAuthPasswordFiles writes the htpasswd file of each member, which FireFly core and the
+// connector check the credentials of incoming requests against
+func (s *StackManager) writeBasicAuthPasswordFiles() error {
+	authDir := filepath.Join(s.Stack.InitDir, "config", "auth")
+	if err := os.MkdirAll(authDir, 0755); err != nil {
+		return err
+	}
+	for _, member := range s.Stack.Members {
+		auth := s.Stack.APICredentials(member.ID)
+		if auth == nil {
+			return fmt.Errorf("no API credentials were generated for member %s", member.ID)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(auth.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		entry := fmt.Sprintf("%s:%s\n", auth.Username, hash)
+		if err := ioutil.WriteFile(filepath.Join(authDir, fmt.Sprintf("passwords_%s", member.ID)), []byte(entry), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setHTTPBasicAuth gives the HTTP client of the stack the credentials for the FireFly and connector APIs of
+// each member, so that identity registration and contract deployment are authorized
+func (s *StackManager) setHTTPBasicAuth() {
+	if !s.Stack.BasicAuthEnabled {
+		return
+	}
+	client := core.HTTPClientFromContext(s.ctx)
+	for _, member := range s.Stack.Members {
+		auth := s.Stack.APICredentials(member.ID)
+		client.SetBasicAuth(fmt.Sprintf("127.0.0.1:%d", member.ExposedFireflyPort), auth)
+		client.SetBasicAuth(fmt.Sprintf("127.0.0.1:%d", member.ExposedFireflyAdminSPIPort), auth)
+		if connectorAuth := s.Stack.ConnectorCredentials(member.ID); connectorAuth != nil {
+			client.SetBasicAuth(fmt.Sprintf("127.0.0.1:%d", member.ExposedConnectorPort), connectorAuth)
+		}
+	}
+}
+
+// loadInitCredentials reads the credentials that were generated at init time, for a stack that has not been run yet
+func (s *StackManager) loadInitCredentials() error {
+	b, err := ioutil.ReadFile(filepath.Join(s.Stack.InitDir, "stackState.json"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if b, err = s.decryptStackFile(b); err != nil {
+		return err
+	}
+	var stackState *types.StackState
+	if err := json.Unmarshal(b, &stackState); err != nil {
+		return err
+	}
+	s.Stack.State.Credentials = stackState.Credentials
+	return nil
+}
+
+// RotateCredentials generates new keystore and database passwords for a stack, re-encrypts the
+// keystores, changes the postgres passwords, updates the configs and restarts the stack. The new
+// credentials are saved as pending before anything is changed, so if a step fails, running it again
+// completes the rotation with the same credentials.
+func (s *StackManager) RotateCredentials() error {
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil {
+		return err
+	}
+	if !hasRunBefore {
+		return fmt.Errorf("stack '%s' has not been started yet - credentials can be rotated once it has been run for the first time", s.Stack.Name)
+	}
+
+	oldStack := &types.Stack{
+		Members:              s.Stack.Members,
+		SharedInfrastructure: s.Stack.SharedInfrastructure,
+		State:                &types.StackState{Credentials: s.Stack.State.Credentials},
+	}
+	newCredentials := s.Stack.State.PendingCredentials
+	if newCredentials != nil {
+		s.Log.Info("completing a previous rotation of the credentials that did not finish")
+	} else {
+		if newCredentials, err = s.newRotatedCredentials(oldStack.State.Credentials); err != nil {
+			return err
+		}
+		s.Stack.State.PendingCredentials = newCredentials
+		if err := s.writeStackStateJSON(s.Stack.RuntimeDir); err != nil {
+			return err
+		}
+	}
+	if err := s.applyRotatedCredentials(oldStack, newCredentials); err != nil {
+		return fmt.Errorf("%s - run 'ff credentials rotate %s' again to complete the rotation", err, s.Stack.Name)
+	}
+
+	s.Log.Info("restarting containers")
+	if err := s.runDockerComposeCommand("stop"); err != nil {
+		return err
+	}
+	if err := s.runStartupSequence(false); err != nil {
+		return err
+	}
+	return s.ensureFireflyNodesUp(false)
+}
+
+// newRotatedCredentials generates the credentials a stack is rotated to, including a keystore password for
+// each account that has its own
+func (s *StackManager) newRotatedCredentials(oldCredentials *types.StackCredentials) (*types.StackCredentials, error) {
+	perMember := oldCredentials != nil && oldCredentials.PerMember
+	newCredentials, err := s.generateCredentials(perMember)
+	if err != nil {
+		return nil, err
+	}
+	if oldCredentials == nil {
+		return newCredentials, nil
+	}
+	if perMember {
+		for address := range oldCredentials.Accounts {
+			password, err := secrets.GeneratePassword()
+			if err != nil {
+				return nil, err
+			}
+			newCredentials.Accounts[address] = &types.Credentials{KeystorePassword: password}
+		}
+	}
+	// API users are handed out to applications, so rotating the internal passwords leaves them as they are
+	for memberID, member := range oldCredentials.Members {
+		if newMember, ok := newCredentials.Members[memberID]; ok && member.APIPassword != "" {
+			newMember.APIUsername = member.APIUsername
+			newMember.APIPassword = member.APIPassword
+		}
+	}
+	return newCredentials, nil
+}
+
+// applyRotatedCredentials changes the passwords of the databases and keystores of the stack to the new
+// credentials, and saves them once every step has succeeded. Each step can be run again after a failure.
+func (s *StackManager) applyRotatedCredentials(oldStack *types.Stack, newCredentials *types.StackCredentials) error {
+	s.Stack.State.Credentials = newCredentials
+	defer func() {
+		if s.Stack.State.PendingCredentials != nil {
+			// Until the rotation completes, the stack keeps the credentials that were saved
+			s.Stack.State.Credentials = oldStack.State.Credentials
+		}
+	}()
+
+	if s.Stack.Database.Equals(types.DatabaseSelectionPostgres) {
+		if err := s.rotateDatabasePasswords(); err != nil {
+			return err
+		}
+		for _, member := range s.Stack.Members {
+			if err := s.replaceInCoreConfig(member, core.GetPostgresURL(oldStack, member), core.GetPostgresURL(s.Stack, member)); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.Log.Info("re-encrypting keystores")
+	// The views of the additional blockchains need to see the new credentials
+	s.loadBlockchainProviders()
+	for _, provider := range s.blockchainProviders {
+		if err := provider.RotateKeystorePasswords(oldStack.KeystorePassword); err != nil {
+			return err
+		}
+	}
+
+	if err := s.writeDockerCompose(s.buildDockerCompose()); err != nil {
+		return err
+	}
+	s.Stack.State.PendingCredentials = nil
+	if err := s.writeStackStateJSON(s.Stack.RuntimeDir); err != nil {
+		s.Stack.State.PendingCredentials = newCredentials
+		return err
+	}
+	return nil
+}
+
+func (s *StackManager) rotateDatabasePasswords() error {
+	services := []string{}
+	for _, member := range s.Stack.Members {
+		if service := s.Stack.PostgresServiceName(member.ID); len(services) == 0 || services[len(services)-1] != service {
+			services = append(services, service)
+		}
+	}
+	if err := s.runDockerComposeCommand(append([]string{"up", "-d"}, services...)...); err != nil {
+		return err
+	}
+
+	if s.Stack.SharedInfrastructure {
+		s.Log.Info("changing password of the shared database")
+		statement := fmt.Sprintf("ALTER USER postgres WITH PASSWORD '%s'", s.Stack.PostgresSuperuserPassword(""))
+		if _, err := s.runPostgresStatement(services[0], statement); err != nil {
+			return fmt.Errorf("unable to change the password of the shared database: %s", err)
+		}
+	}
+	for _, member := range s.Stack.Members {
+		s.Log.Info(fmt.Sprintf("changing database password for member %s", member.ID))
+		statement := fmt.Sprintf("ALTER USER %s WITH PASSWORD '%s'", s.Stack.PostgresUser(member.ID), s.Stack.DatabasePassword(member.ID))
+		if _, err := s.runPostgresStatement(s.Stack.PostgresServiceName(member.ID), statement); err != nil {
+			return fmt.Errorf("unable to change the database password for member %s: %s", member.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *StackManager) replaceInCoreConfig(member *types.Organization, oldValue, newValue string) error {
+	configFile := filepath.Join(s.Stack.RuntimeDir, "config", fmt.Sprintf("firefly_core_%s.yml", member.ID))
+	b, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte(newValue)) {
+		// A rotation that is being completed has updated this config already
+		return nil
+	}
+	if !bytes.Contains(b, []byte(oldValue)) {
+		s.Log.Info(fmt.Sprintf("WARNING: the database URL in %s was not generated by the CLI - please update it manually", configFile))
+		return nil
+	}
+	return ioutil.WriteFile(configFile, bytes.ReplaceAll(b, []byte(oldValue), []byte(newValue)), 0644)
+}
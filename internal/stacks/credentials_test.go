@@ -0,0 +1,63 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRotatedCredentials(T *testing.T) {
+	index := 0
+	s := &StackManager{Stack: &types.Stack{
+		Name:             "dev",
+		BasicAuthEnabled: true,
+		Members:          []*types.Organization{{ID: "0", Index: &index}},
+	}}
+	oldCredentials := &types.StackCredentials{
+		Credentials: types.Credentials{KeystorePassword: "keystore", DatabasePassword: "database"},
+		PerMember:   true,
+		Members:     map[string]*types.Credentials{"0": {DatabasePassword: "member", APIUsername: "firefly", APIPassword: "api"}},
+		Accounts:    map[string]*types.Credentials{"0x1234": {KeystorePassword: "account"}},
+	}
+	newCredentials, err := s.newRotatedCredentials(oldCredentials)
+	assert.NoError(T, err)
+	assert.True(T, newCredentials.PerMember)
+	assert.NotEqual(T, "keystore", newCredentials.KeystorePassword)
+	assert.NotEqual(T, "database", newCredentials.DatabasePassword)
+	assert.NotEqual(T, "member", newCredentials.Members["0"].DatabasePassword)
+	// The keystore password of every account is generated up front, so that it is saved before the keystores are changed
+	assert.NotEmpty(T, newCredentials.Accounts["0x1234"].KeystorePassword)
+	assert.NotEqual(T, "account", newCredentials.Accounts["0x1234"].KeystorePassword)
+	// The API users are kept
+	assert.Equal(T, "firefly", newCredentials.Members["0"].APIUsername)
+	assert.Equal(T, "api", newCredentials.Members["0"].APIPassword)
+}
+
+func TestStackDirIsPrivate(T *testing.T) {
+	stackDir := filepath.Join(T.TempDir(), "dev")
+	assert.NoError(T, os.MkdirAll(stackDir, 0755))
+	s := &StackManager{Stack: &types.Stack{Name: "dev", StackDir: stackDir}}
+	assert.NoError(T, s.ensureStackDir())
+	info, err := os.Stat(stackDir)
+	assert.NoError(T, err)
+	assert.Equal(T, os.FileMode(0700), info.Mode().Perm())
+}
@@ -18,6 +18,8 @@ package stacks
 
 import (
 	"fmt"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
 )
 
 type DataExchangeListenerConfig struct {
@@ -37,7 +39,24 @@ type DataExchangePeerConfig struct {
 	Peers []*PeerConfig               `json:"peers"`
 }
 
-func (s *StackManager) GenerateDataExchangeHTTPSConfig(memberId string) *DataExchangePeerConfig {
+// GenerateDataExchangeHTTPSConfig advertises the container name of the data exchange as its endpoint, since
+// that is unique across stacks that share a network, whereas the service name is the same in every stack.
+// An external data exchange listens on the exposed ports of its member, and is reached through the host.
+func (s *StackManager) GenerateDataExchangeHTTPSConfig(member *types.Organization) *DataExchangePeerConfig {
+	if member.IsExternal(types.ComponentDataExchange) {
+		return &DataExchangePeerConfig{
+			API: &DataExchangeListenerConfig{
+				Hostname: "0.0.0.0",
+				Port:     member.ExposedDataexchangePort,
+			},
+			P2P: &DataExchangeListenerConfig{
+				Hostname: "0.0.0.0",
+				Port:     member.ExposedDataexchangeP2PPort,
+				Endpoint: fmt.Sprintf("https://host.docker.internal:%d", member.ExposedDataexchangeP2PPort),
+			},
+			Peers: []*PeerConfig{},
+		}
+	}
 	return &DataExchangePeerConfig{
 		API: &DataExchangeListenerConfig{
 			Hostname: "0.0.0.0",
@@ -46,7 +65,7 @@ func (s *StackManager) GenerateDataExchangeHTTPSConfig(memberId string) *DataExc
 		P2P: &DataExchangeListenerConfig{
 			Hostname: "0.0.0.0",
 			Port:     3001,
-			Endpoint: fmt.Sprintf("https://dataexchange_%s:3001", memberId),
+			Endpoint: fmt.Sprintf("https://%s_dataexchange_%s:3001", s.Stack.Name, member.ID),
 		},
 		Peers: []*PeerConfig{},
 	}
@@ -0,0 +1,233 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// memberDatabase is where the database of a member lives: a database in one of the postgres containers
+// of the stack, a sqlite file inside the FireFly core container, or a sqlite file in the runtime
+// directory for an external member
+type memberDatabase struct {
+	member    *types.Organization
+	postgres  bool
+	container string
+	service   string
+	database  string
+	path      string
+}
+
+func (s *StackManager) getMemberDatabase(memberNameOrID string) (*memberDatabase, error) {
+	member, err := s.getMember(memberNameOrID)
+	if err != nil {
+		return nil, err
+	}
+	db := &memberDatabase{member: member}
+	switch {
+	case s.Stack.Database.Equals(types.DatabaseSelectionPostgres):
+		db.postgres = true
+		db.service = s.Stack.PostgresServiceName(member.ID)
+		db.database = s.Stack.PostgresDatabase(member.ID)
+		if db.database == "" {
+			db.database = "postgres"
+		}
+	case member.External:
+		db.path = strings.SplitN(core.GetSQLitePath(member, s.Stack.RuntimeDir), "?", 2)[0]
+		return db, nil
+	default:
+		db.service = "firefly_core_" + member.ID
+		db.path = strings.SplitN(core.GetSQLitePath(member, s.Stack.RuntimeDir), "?", 2)[0]
+	}
+	db.container = fmt.Sprintf("%s_%s", s.Stack.Name, db.service)
+	return db, nil
+}
+
+// prepareMemberDatabase makes sure the container holding the database is up, starting it if the
+// stack is stopped, and waits for postgres to accept connections
+func (s *StackManager) prepareMemberDatabase(db *memberDatabase) error {
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil {
+		return err
+	}
+	if !hasRunBefore {
+		return fmt.Errorf("stack '%s' has not been started yet, so it does not have any databases", s.Stack.Name)
+	}
+	if db.service == "" {
+		return nil
+	}
+	if err := s.runDockerComposeCommand("up", "-d", db.service); err != nil {
+		return err
+	}
+	if db.postgres {
+		_, err = s.runPostgresStatement(db.service, "SELECT 1")
+	}
+	return err
+}
+
+// DumpDatabase writes a backup of the database of a member to a file, which RestoreDatabase can load
+// again. Postgres databases are dumped as SQL with pg_dump, and sqlite databases are copied.
+func (s *StackManager) DumpDatabase(memberNameOrID, filename string) error {
+	db, err := s.getMemberDatabase(memberNameOrID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareMemberDatabase(db); err != nil {
+		return err
+	}
+	if db.container == "" {
+		b, err := ioutil.ReadFile(db.path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filename, b, 0644)
+	}
+
+	tmpFile := fmt.Sprintf("/tmp/firefly_%s.dump", db.member.ID)
+	s.Log.Info(fmt.Sprintf("dumping database of member %s", db.member.ID))
+	if db.postgres {
+		err = docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "exec", db.container, "pg_dump", "-U", "postgres", "--clean", "--if-exists", "-f", tmpFile, db.database)
+	} else {
+		// The sqlite backup API gives a consistent copy while FireFly is writing to the database
+		err = docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "exec", db.container, "sqlite3", db.path, fmt.Sprintf(".backup %s", tmpFile))
+	}
+	if err != nil {
+		return err
+	}
+	defer s.removeFromContainer(db.container, tmpFile)
+	return docker.CopyFromContainer(s.ctx, db.container, tmpFile, filename)
+}
+
+// RestoreDatabase replaces the database of a member with a backup written by DumpDatabase, and restarts
+// its FireFly core so that it picks up the restored state
+func (s *StackManager) RestoreDatabase(memberNameOrID, filename string) (err error) {
+	db, err := s.getMemberDatabase(memberNameOrID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filename); err != nil {
+		return err
+	}
+	if err := s.prepareMemberDatabase(db); err != nil {
+		return err
+	}
+	coreService := "firefly_core_" + db.member.ID
+	coreStopped := false
+
+	s.Log.Info(fmt.Sprintf("restoring database of member %s", db.member.ID))
+	switch {
+	case db.container == "":
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(db.path, b, 0644); err != nil {
+			return err
+		}
+	case db.postgres:
+		if !db.member.External {
+			if err := s.runDockerComposeCommand("stop", coreService); err != nil {
+				return err
+			}
+			// FireFly core is started again even if the restore fails, rather than being left stopped
+			coreStopped = true
+			defer func() {
+				s.Log.Info(fmt.Sprintf("starting firefly core for member %s", db.member.ID))
+				if startErr := s.runDockerComposeCommand("start", coreService); err == nil {
+					err = startErr
+				}
+			}()
+		}
+		if err := s.copyToContainer(db, filename); err != nil {
+			return err
+		}
+		defer s.removeFromContainer(db.container, s.restoreFile(db))
+		if err := docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "exec", db.container, "psql", "-U", "postgres", "-q", "-v", "ON_ERROR_STOP=1", "-d", db.database, "-f", s.restoreFile(db)); err != nil {
+			return err
+		}
+	default:
+		// The sqlite file is owned by the user FireFly runs as, so it is restored in place through sqlite
+		// rather than replaced, before FireFly is restarted to drop any state it holds from before
+		if err := s.copyToContainer(db, filename); err != nil {
+			return err
+		}
+		defer s.removeFromContainer(db.container, s.restoreFile(db))
+		if err := docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "exec", db.container, "sqlite3", db.path, fmt.Sprintf(".restore %s", s.restoreFile(db))); err != nil {
+			return err
+		}
+	}
+
+	if db.member.External {
+		s.Log.Info(fmt.Sprintf("please restart your firefly core for member %s to pick up the restored database", db.member.ID))
+		return nil
+	}
+	if coreStopped {
+		return nil
+	}
+	s.Log.Info(fmt.Sprintf("restarting firefly core for member %s", db.member.ID))
+	return s.runDockerComposeCommand("restart", coreService)
+}
+
+func (s *StackManager) restoreFile(db *memberDatabase) string {
+	return fmt.Sprintf("/tmp/firefly_%s.restore", db.member.ID)
+}
+
+func (s *StackManager) copyToContainer(db *memberDatabase, filename string) error {
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	return docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "cp", absPath, fmt.Sprintf("%s:%s", db.container, s.restoreFile(db)))
+}
+
+func (s *StackManager) removeFromContainer(container, path string) {
+	if err := docker.RunDockerCommand(s.ctx, s.Stack.StackDir, "exec", container, "rm", "-f", path); err != nil {
+		s.Log.Warn(fmt.Sprintf("unable to remove %s from %s: %s", path, container, err))
+	}
+}
+
+// DatabaseShell opens psql or sqlite3 on the database of a member, attached to the terminal
+func (s *StackManager) DatabaseShell(memberNameOrID string) error {
+	db, err := s.getMemberDatabase(memberNameOrID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareMemberDatabase(db); err != nil {
+		return err
+	}
+	switch {
+	case db.container == "":
+		// The FireFly core image has sqlite3, so it is used to open the file of an external member too
+		return docker.RunDockerCommandInteractive(s.ctx, s.Stack.StackDir, "run", "--rm", "-i",
+			"-v", fmt.Sprintf("%s:/data", filepath.Dir(db.path)),
+			"--entrypoint", "sqlite3",
+			s.Stack.VersionManifest.FireFly.GetDockerImageString(),
+			"/data/"+filepath.Base(db.path))
+	case db.postgres:
+		return docker.RunDockerCommandInteractive(s.ctx, s.Stack.StackDir, "exec", "-i", db.container, "psql", "-U", "postgres", "-d", db.database)
+	default:
+		return docker.RunDockerCommandInteractive(s.ctx, s.Stack.StackDir, "exec", "-i", db.container, "sqlite3", db.path)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMemberDatabase(T *testing.T) {
+	s := &StackManager{Stack: &types.Stack{
+		Name:       "dev",
+		Database:   types.DatabaseSelectionPostgres,
+		RuntimeDir: "/stacks/dev/runtime",
+		Members: []*types.Organization{
+			{ID: "0", OrgName: "org_0"},
+			{ID: "1", OrgName: "org_1", External: true},
+		},
+	}}
+
+	db, err := s.getMemberDatabase("org_1")
+	assert.NoError(T, err)
+	assert.Equal(T, "dev_postgres_1", db.container)
+	assert.Equal(T, "postgres", db.database)
+
+	s.Stack.SharedInfrastructure = true
+	db, err = s.getMemberDatabase("1")
+	assert.NoError(T, err)
+	assert.Equal(T, "dev_postgres", db.container)
+	assert.Equal(T, "firefly_1", db.database)
+
+	s.Stack.Database = types.DatabaseSelectionSQLite
+	db, err = s.getMemberDatabase("0")
+	assert.NoError(T, err)
+	assert.Equal(T, "dev_firefly_core_0", db.container)
+	assert.Equal(T, "/etc/firefly/db", db.path)
+
+	// An external member keeps its sqlite database in the runtime directory
+	db, err = s.getMemberDatabase("1")
+	assert.NoError(T, err)
+	assert.Equal(T, "", db.container)
+	assert.Equal(T, "/stacks/dev/runtime/1.db", db.path)
+
+	_, err = s.getMemberDatabase("2")
+	assert.Error(T, err)
+}
+
+func TestMemberDatabaseStatements(T *testing.T) {
+	member := &types.Organization{ID: "1"}
+	s := &StackManager{Stack: &types.Stack{
+		Name:                 "dev",
+		Database:             types.DatabaseSelectionPostgres,
+		SharedInfrastructure: true,
+		Members:              []*types.Organization{member},
+		State: &types.StackState{Credentials: &types.StackCredentials{
+			Credentials: types.Credentials{DatabasePassword: "secret"},
+		}},
+	}}
+
+	assert.Equal(T, []string{
+		"CREATE USER firefly_1 WITH PASSWORD 'secret'",
+		"CREATE DATABASE firefly_1 OWNER firefly_1",
+		"REVOKE ALL ON DATABASE firefly_1 FROM PUBLIC",
+	}, s.memberDatabaseStatements(member, false, false))
+
+	// A start that failed after creating the user creates only the database, rather than failing on the user
+	assert.Equal(T, []string{
+		"ALTER USER firefly_1 WITH PASSWORD 'secret'",
+		"CREATE DATABASE firefly_1 OWNER firefly_1",
+		"REVOKE ALL ON DATABASE firefly_1 FROM PUBLIC",
+	}, s.memberDatabaseStatements(member, true, false))
+}
@@ -0,0 +1,150 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// devImage returns the manifest entry of the image that is built for a component of the stack from a
+// linked source directory. The tag is the name of the stack, so that stacks can link different checkouts.
+func (s *StackManager) devImage(component string) *types.ManifestEntry {
+	return &types.ManifestEntry{
+		Image: fmt.Sprintf("ff-dev/%s", component),
+		Tag:   s.Stack.Name,
+		Local: true,
+	}
+}
+
+func (s *StackManager) buildDevImage(component string) error {
+	dir := s.Stack.DevLinks[component]
+	image := s.devImage(component).GetDockerImageString()
+	s.Log.Info(fmt.Sprintf("building '%s' from %s", image, dir))
+	return docker.RunDockerCommand(s.ctx, dir, "build", "-t", image, ".")
+}
+
+// parseDevLinks parses links of components to local source directories, given as "<component>=<dir>", where
+// the component is a key of the manifest and the directory has a Dockerfile, returning the absolute directories
+func parseDevLinks(links []string) (map[string]string, error) {
+	dirs := map[string]string{}
+	for _, link := range links {
+		parts := strings.SplitN(link, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid link '%s' - use <component>=<source_dir>", link)
+		}
+		if err := types.CheckManifestKey(parts[0]); err != nil {
+			return nil, err
+		}
+		dir, err := filepath.Abs(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); err != nil {
+			return nil, fmt.Errorf("no Dockerfile found in %s", dir)
+		}
+		dirs[parts[0]] = dir
+	}
+	return dirs, nil
+}
+
+// DevLink builds images for components from local source directories, given as "<component>=<dir>", and
+// switches the stack over to them
+func (s *StackManager) DevLink(links []string) error {
+	dirs, err := parseDevLinks(links)
+	if err != nil {
+		return err
+	}
+
+	before := s.buildDockerCompose()
+	if s.Stack.DevLinks == nil {
+		s.Stack.DevLinks = map[string]string{}
+	}
+	// A component that was already linked is rebuilt under the same image name
+	var relinked []string
+	for component, dir := range dirs {
+		if _, ok := s.Stack.DevLinks[component]; ok {
+			relinked = append(relinked, component)
+		}
+		s.Stack.DevLinks[component] = dir
+		if err := s.buildDevImage(component); err != nil {
+			return err
+		}
+		if err := s.Stack.VersionManifest.SetEntry(component, s.devImage(component)); err != nil {
+			return err
+		}
+	}
+	return s.applyImageChanges(before, relinked)
+}
+
+// DevRebuild rebuilds the images of linked components, or of a single one, and recreates the running containers
+// that use them for every member
+func (s *StackManager) DevRebuild(component string) error {
+	components, err := s.devComponents(component)
+	if err != nil {
+		return err
+	}
+	for _, c := range components {
+		if err := s.buildDevImage(c); err != nil {
+			return err
+		}
+	}
+	compose := s.buildDockerCompose()
+	return s.recreateRunningServices(compose, s.devServices(compose, components), true)
+}
+
+// devComponents returns the linked components to rebuild: the one given, or all of them
+func (s *StackManager) devComponents(component string) ([]string, error) {
+	if component != "" {
+		if _, ok := s.Stack.DevLinks[component]; !ok {
+			return nil, fmt.Errorf("component '%s' is not linked to a source directory in stack '%s'", component, s.Stack.Name)
+		}
+		return []string{component}, nil
+	}
+	var components []string
+	for c := range s.Stack.DevLinks {
+		components = append(components, c)
+	}
+	if len(components) == 0 {
+		return nil, fmt.Errorf("no components are linked to a source directory in stack '%s'", s.Stack.Name)
+	}
+	sort.Strings(components)
+	return components, nil
+}
+
+// devServices returns the services that run the images built for components, which is one per member
+// for most components
+func (s *StackManager) devServices(compose *docker.DockerComposeConfig, components []string) []string {
+	images := map[string]bool{}
+	for _, c := range components {
+		images[s.devImage(c).GetDockerImageString()] = true
+	}
+	var services []string
+	for name, service := range compose.Services {
+		if images[service.Image] {
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
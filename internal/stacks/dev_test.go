@@ -0,0 +1,92 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDevLinks(T *testing.T) {
+	sourceDir := T.TempDir()
+	assert.NoError(T, ioutil.WriteFile(filepath.Join(sourceDir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	emptyDir := T.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(T, err)
+	relativeDir, err := filepath.Rel(wd, sourceDir)
+	assert.NoError(T, err)
+
+	tests := []struct {
+		links []string
+		dirs  map[string]string
+		err   string
+	}{
+		{links: []string{"firefly=" + sourceDir}, dirs: map[string]string{"firefly": sourceDir}},
+		{links: []string{"evmconnect=" + relativeDir, "tokens-erc1155=" + sourceDir}, dirs: map[string]string{"evmconnect": sourceDir, "tokens-erc1155": sourceDir}},
+		{links: []string{"firefly"}, err: "invalid link 'firefly' - use <component>=<source_dir>"},
+		{links: []string{"=" + sourceDir}, err: "invalid link"},
+		{links: []string{"firefly="}, err: "invalid link"},
+		{links: []string{"core=" + sourceDir}, err: "unknown component 'core'"},
+		{links: []string{"firefly=" + emptyDir}, err: "no Dockerfile found in " + emptyDir},
+	}
+	for _, test := range tests {
+		dirs, err := parseDevLinks(test.links)
+		if test.err != "" {
+			assert.Regexp(T, test.err, err, test.links)
+			continue
+		}
+		if assert.NoError(T, err, test.links) {
+			assert.Equal(T, test.dirs, dirs, test.links)
+		}
+	}
+}
+
+func TestDevRebuildServices(T *testing.T) {
+	s := newTestStackManager(2)
+
+	_, err := s.devComponents("")
+	assert.Regexp(T, "no components are linked to a source directory in stack 'dev'", err)
+
+	// The stack runs the images built from the linked source directories
+	s.Stack.DevLinks = map[string]string{"firefly": "/src/firefly", "evmconnect": "/src/firefly-evmconnect"}
+	s.Stack.VersionManifest.FireFly = s.devImage("firefly")
+	s.Stack.VersionManifest.Evmconnect = s.devImage("evmconnect")
+	assert.Equal(T, "ff-dev/firefly:dev", s.Stack.VersionManifest.FireFly.GetDockerImageString())
+	compose := s.buildDockerCompose()
+
+	components, err := s.devComponents("")
+	assert.NoError(T, err)
+	assert.Equal(T, []string{"evmconnect", "firefly"}, components)
+	assert.Equal(T, []string{"evmconnect_0", "evmconnect_1", "firefly_core_0", "firefly_core_1"}, s.devServices(compose, components))
+
+	components, err = s.devComponents("evmconnect")
+	assert.NoError(T, err)
+	assert.Equal(T, []string{"evmconnect"}, components)
+	assert.Equal(T, []string{"evmconnect_0", "evmconnect_1"}, s.devServices(compose, components))
+
+	_, err = s.devComponents("geth")
+	assert.Regexp(T, "component 'geth' is not linked to a source directory in stack 'dev'", err)
+
+	// Linking a component again, to another checkout, rebuilds its image under the same name, so its services
+	// are recreated even though their image did not change
+	assert.Empty(T, s.changedImageServices(compose, compose, nil))
+	assert.Equal(T, []string{"firefly_core_0", "firefly_core_1"}, s.changedImageServices(compose, compose, []string{"firefly"}))
+}
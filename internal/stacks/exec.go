@@ -0,0 +1,162 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+)
+
+// ResolveService finds the service a target refers to. Targets are a logical service name or
+// alias, optionally prefixed with a member, such as "0/connector", "org_1/tokens" or "blockchain",
+// or the name of the service in the docker compose file.
+func (s *StackManager) ResolveService(target string) (*ServiceRef, error) {
+	members := []string{}
+	name := target
+	if i := strings.Index(target, "/"); i >= 0 {
+		members = append(members, target[:i])
+		name = target[i+1:]
+	}
+	matches, err := s.FindServices(members, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("service '%s' does not exist in stack '%s'", target, s.Stack.Name)
+	}
+	if len(matches) > 1 {
+		serviceNames := make([]string, len(matches))
+		for i, match := range matches {
+			serviceNames[i] = match.ServiceName
+		}
+		hint := "use the name of the service"
+		if len(members) == 0 {
+			hint = fmt.Sprintf("specify a member, as in 0/%s, or use the name of the service", name)
+		}
+		return nil, fmt.Errorf("'%s' matches more than one service (%s) - %s", target, strings.Join(serviceNames, ", "), hint)
+	}
+	return matches[0], nil
+}
+
+// Exec runs a command in the container of a service, attached to the terminal, or a shell if no command is given
+func (s *StackManager) Exec(target string, command []string) error {
+	service, err := s.ResolveService(target)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+	return docker.RunDockerCommandInteractive(s.ctx, ".", append([]string{"exec", "-i", service.ContainerName}, command...)...)
+}
+
+// copyEndpoint is one side of a copy, which is a path on the host, a path in the container of a
+// service, or a path in a volume of the stack
+type copyEndpoint struct {
+	container string
+	volume    string
+	path      string
+}
+
+// parseCopyEndpoint parses "<service>:<path>" and "volume:<volume>:<path>" as paths in the stack,
+// and anything else as a path on the host
+func (s *StackManager) parseCopyEndpoint(arg string) (*copyEndpoint, error) {
+	i := strings.Index(arg, ":")
+	// A single letter before the colon is a Windows drive
+	if i <= 1 {
+		return &copyEndpoint{path: arg}, nil
+	}
+	target, p := arg[:i], arg[i+1:]
+	if target == "volume" {
+		j := strings.Index(p, ":")
+		if j < 0 {
+			return nil, fmt.Errorf("'%s' must be of the form volume:<volume>:<path>", arg)
+		}
+		volume, err := s.resolveVolume(p[:j])
+		if err != nil {
+			return nil, err
+		}
+		return &copyEndpoint{volume: volume, path: path.Join("/volume", p[j+1:])}, nil
+	}
+	service, err := s.ResolveService(target)
+	if err != nil {
+		return nil, err
+	}
+	return &copyEndpoint{container: service.ContainerName, path: p}, nil
+}
+
+// resolveVolume finds the docker volume of a volume in the docker compose file of the stack, which
+// docker compose prefixes with the name of the stack
+func (s *StackManager) resolveVolume(name string) (string, error) {
+	compose := s.buildDockerCompose()
+	for volumeName := range compose.Volumes {
+		if name == volumeName || name == fmt.Sprintf("%s_%s", s.Stack.Name, volumeName) {
+			return fmt.Sprintf("%s_%s", s.Stack.Name, volumeName), nil
+		}
+	}
+	return "", fmt.Errorf("volume '%s' does not exist in stack '%s'", name, s.Stack.Name)
+}
+
+// Copy copies files or directories between the host and the containers or volumes of the stack,
+// with the same behavior as docker cp. One side must be on the host, and the other in the stack.
+func (s *StackManager) Copy(src, dst string) error {
+	from, err := s.parseCopyEndpoint(src)
+	if err != nil {
+		return err
+	}
+	to, err := s.parseCopyEndpoint(dst)
+	if err != nil {
+		return err
+	}
+	fromStack := from.container != "" || from.volume != ""
+	toStack := to.container != "" || to.volume != ""
+	if fromStack == toStack {
+		return fmt.Errorf("one of '%s' and '%s' must be a path on the host, and the other a path in the stack", src, dst)
+	}
+
+	// Volumes are reached through a container that only exists for the copy, so that they
+	// can be copied to and from whether or not a service using them is running. The container
+	// is never started, so it uses the FireFly core image, which the stack has already pulled.
+	for _, endpoint := range []*copyEndpoint{from, to} {
+		if endpoint.volume == "" {
+			continue
+		}
+		containerID, err := docker.RunDockerCommandBuffered(s.ctx, ".", "create", "-v", fmt.Sprintf("%s:/volume", endpoint.volume), s.Stack.VersionManifest.FireFly.GetDockerImageString())
+		if err != nil {
+			return err
+		}
+		endpoint.container = strings.TrimSpace(containerID)
+		defer s.removeContainer(endpoint.container)
+	}
+	return docker.RunDockerCommand(s.ctx, ".", "cp", from.String(), to.String())
+}
+
+func (s *StackManager) removeContainer(container string) {
+	if err := docker.RunDockerCommand(s.ctx, ".", "rm", container); err != nil {
+		s.Log.Warn(fmt.Sprintf("unable to remove container %s: %s", container, err))
+	}
+}
+
+func (e *copyEndpoint) String() string {
+	if e.container == "" {
+		return e.path
+	}
+	return fmt.Sprintf("%s:%s", e.container, e.path)
+}
@@ -0,0 +1,97 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExecTestStackManager() *StackManager {
+	return newTestStackManager(2, func(stack *types.Stack) {
+		stack.TokenProviders = []fftypes.FFEnum{types.TokenProviderERC1155}
+		stack.Members[0].ExposedTokensPorts = []int{5108}
+		stack.Members[1].ExposedTokensPorts = []int{5208}
+	})
+}
+
+func TestResolveService(T *testing.T) {
+	s := newExecTestStackManager()
+	tests := []struct {
+		target        string
+		serviceName   string
+		containerName string
+		err           string
+	}{
+		{target: "0/connector", serviceName: "evmconnect_0", containerName: "dev_evmconnect_0"},
+		{target: "org_1/tokens", serviceName: "tokens_1_0", containerName: "dev_tokens_1_0"},
+		{target: "1/core", serviceName: "firefly_core_1", containerName: "dev_firefly_core_1"},
+		{target: "blockchain", serviceName: "geth", containerName: "dev_geth"},
+		{target: "0/blockchain", serviceName: "geth", containerName: "dev_geth"},
+		{target: "dataexchange_1", serviceName: "dataexchange_1", containerName: "dev_dataexchange_1"},
+		{target: "connector", err: "'connector' matches more than one service \\(evmconnect_0, evmconnect_1\\) - specify a member, as in 0/connector"},
+		{target: "0/firefly_core", serviceName: "firefly_core_0", containerName: "dev_firefly_core_0"},
+		{target: "2/core", err: "member '2' does not exist in stack 'dev'"},
+		{target: "ipfs_2", err: "service 'ipfs_2' does not exist in stack 'dev'"},
+	}
+	for _, test := range tests {
+		service, err := s.ResolveService(test.target)
+		if test.err != "" {
+			assert.Regexp(T, test.err, err, test.target)
+			continue
+		}
+		if assert.NoError(T, err, test.target) {
+			assert.Equal(T, test.serviceName, service.ServiceName, test.target)
+			assert.Equal(T, test.containerName, service.ContainerName, test.target)
+		}
+	}
+}
+
+func TestParseCopyEndpoint(T *testing.T) {
+	s := newExecTestStackManager()
+	tests := []struct {
+		arg      string
+		endpoint *copyEndpoint
+		err      string
+	}{
+		{arg: "./config.yaml", endpoint: &copyEndpoint{path: "./config.yaml"}},
+		{arg: "/tmp/config.yaml", endpoint: &copyEndpoint{path: "/tmp/config.yaml"}},
+		{arg: `C:\Users\dev\config.yaml`, endpoint: &copyEndpoint{path: `C:\Users\dev\config.yaml`}},
+		{arg: "c:/config.yaml", endpoint: &copyEndpoint{path: "c:/config.yaml"}},
+		{arg: "0/core:/etc/firefly/firefly.core.yml", endpoint: &copyEndpoint{container: "dev_firefly_core_0", path: "/etc/firefly/firefly.core.yml"}},
+		{arg: "dataexchange_1:/data/peer-certs", endpoint: &copyEndpoint{container: "dev_dataexchange_1", path: "/data/peer-certs"}},
+		{arg: "volume:ipfs_staging_0:/export", endpoint: &copyEndpoint{volume: "dev_ipfs_staging_0", path: "/volume/export"}},
+		{arg: "volume:dev_dataexchange_1:peer-certs", endpoint: &copyEndpoint{volume: "dev_dataexchange_1", path: "/volume/peer-certs"}},
+		{arg: "volume:firefly_core_0", err: "must be of the form volume:<volume>:<path>"},
+		{arg: "volume:missing:/data", err: "volume 'missing' does not exist in stack 'dev'"},
+		{arg: "connector:/data", err: "matches more than one service"},
+		{arg: "missing:/data", err: "service 'missing' does not exist in stack 'dev'"},
+	}
+	for _, test := range tests {
+		endpoint, err := s.parseCopyEndpoint(test.arg)
+		if test.err != "" {
+			assert.Regexp(T, test.err, err, test.arg)
+			continue
+		}
+		if assert.NoError(T, err, test.arg) {
+			assert.Equal(T, test.endpoint, endpoint, test.arg)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// externalProcess is a component of a member that runs on the host instead of in docker compose
+type externalProcess struct {
+	name    string
+	service string
+	port    int
+	command string
+}
+
+// externalProcesses returns the components other than FireFly core that run on the host, with the command to run
+// each of them. Connectors and data exchange read the config files that the CLI generated for them, while token
+// connectors are configured through their environment.
+func (s *StackManager) externalProcesses(includeTokens bool) []*externalProcess {
+	configDir := filepath.Join(s.Stack.RuntimeDir, "config")
+	var processes []*externalProcess
+	for i, provider := range s.blockchainProviders {
+		view := s.Stack.BlockchainStack(i)
+		connector := provider.GetConnectorName()
+		for _, member := range view.Members {
+			if !member.IsExternal(types.ComponentConnector) {
+				continue
+			}
+			service := view.BlockchainMemberServiceName(connector, member.ID)
+			configFile := filepath.Join(configDir, service+".yaml")
+			command := fmt.Sprintf("%s -f %s", connector, configFile)
+			if connector == types.BlockchainConnectorEthconnect.String() {
+				command = fmt.Sprintf("%s server -f %s -d 2", connector, configFile)
+			}
+			name := fmt.Sprintf("%s of member %s", connector, member.ID)
+			if i > 0 {
+				name = fmt.Sprintf("%s on %s", name, types.BlockchainPluginName(i))
+			}
+			processes = append(processes, &externalProcess{
+				name:    name,
+				service: service,
+				port:    member.ExposedConnectorPort,
+				command: command,
+			})
+		}
+	}
+	for _, member := range s.Stack.Members {
+		if s.Stack.SharedServicesEnabled() && member.IsExternal(types.ComponentDataExchange) {
+			processes = append(processes, &externalProcess{
+				name:    fmt.Sprintf("data exchange of member %s", member.ID),
+				service: "dataexchange_" + member.ID,
+				port:    member.ExposedDataexchangePort,
+				command: fmt.Sprintf("DATA_DIRECTORY=%s npm start", filepath.Join(configDir, "dataexchange_"+member.ID)),
+			})
+		}
+	}
+	if includeTokens {
+		for i, tp := range s.tokenProviders {
+			for _, serviceDefinition := range tp.GetDockerServiceDefinitions(s.tokenPlugins[i].Index) {
+				member := s.serviceMember(serviceDefinition.ServiceName)
+				if member == nil || !member.IsExternal(types.ComponentTokens) {
+					continue
+				}
+				port := member.ExposedTokensPorts[s.tokenPlugins[i].Index]
+				processes = append(processes, &externalProcess{
+					name:    fmt.Sprintf("%s token connector of member %s", tp.GetName(), member.ID),
+					service: serviceDefinition.ServiceName,
+					port:    port,
+					command: s.externalTokensCommand(serviceDefinition.Service.Environment, port),
+				})
+			}
+		}
+	}
+	return processes
+}
+
+// externalTokensCommand returns the command to run a token connector from its source checkout, with the
+// environment its container would have, adjusted to run on the host
+func (s *StackManager) externalTokensCommand(environment map[string]interface{}, port int) string {
+	env := map[string]string{"PORT": fmt.Sprint(port)}
+	for key, value := range environment {
+		env[key] = fmt.Sprint(value)
+	}
+	if _, ok := env["NODE_EXTRA_CA_CERTS"]; ok {
+		env["NODE_EXTRA_CA_CERTS"] = s.Stack.TLSCAFile()
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys)+2)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, env[key]))
+	}
+	return strings.Join(append(parts, "npm", "start"), " ")
+}
+
+// externalServices returns the names of the docker compose services that members run on the host instead
+func (s *StackManager) externalServices() map[string]bool {
+	services := map[string]bool{}
+	for _, process := range s.externalProcesses(true) {
+		services[process.service] = true
+	}
+	return services
+}
+
+// detachExternalServices removes the services that run on the host from the docker compose file, along with
+// any dependencies on them, and lets containers reach the host through host.docker.internal on every platform
+func (s *StackManager) detachExternalServices(compose *docker.DockerComposeConfig) {
+	external := false
+	for _, member := range s.Stack.Members {
+		external = external || member.HasExternalComponents()
+	}
+	if !external {
+		return
+	}
+	externalServices := s.externalServices()
+	for name := range externalServices {
+		delete(compose.Services, name)
+	}
+	for _, service := range compose.Services {
+		for name := range service.DependsOn {
+			if externalServices[name] {
+				delete(service.DependsOn, name)
+			}
+		}
+		// Services that share the network of another service, such as TLS proxies, share its hosts too
+		if service.NetworkMode == "" {
+			service.ExtraHosts = append(service.ExtraHosts, "host.docker.internal:host-gateway")
+		}
+	}
+}
+
+// ensureExternalProcessesUp waits for the components that run on the host to be reachable, after telling the
+// user how to start each of them. Token connectors are only started once their contracts have been deployed.
+func (s *StackManager) ensureExternalProcessesUp(includeTokens bool) error {
+	for _, process := range s.externalProcesses(includeTokens) {
+		available, err := checkPortAvailable(process.port)
+		if err != nil {
+			return err
+		}
+		if available {
+			s.Log.Info(fmt.Sprintf("please start the %s on port %d: %s", process.name, process.port, process.command))
+			if err := s.waitForExternalProcess(process.name, process.port); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
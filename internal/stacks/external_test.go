@@ -0,0 +1,60 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetachExternalServices(T *testing.T) {
+	sharedServices := true
+	s := &StackManager{Stack: &types.Stack{
+		Name:              "dev",
+		RuntimeDir:        "/stacks/dev/runtime",
+		SharedServicesPtr: &sharedServices,
+		Members: []*types.Organization{
+			{ID: "0", ExposedDataexchangePort: 5105},
+			{ID: "1", ExposedDataexchangePort: 5205, ExternalComponents: []string{types.ComponentDataExchange}},
+		},
+	}}
+	compose := &docker.DockerComposeConfig{Services: map[string]*docker.Service{
+		"dataexchange_0": {},
+		"dataexchange_1": {},
+		"firefly_core_1": {DependsOn: map[string]map[string]string{"dataexchange_1": {"condition": "service_started"}}},
+	}}
+
+	s.detachExternalServices(compose)
+	assert.Contains(T, compose.Services, "dataexchange_0")
+	assert.NotContains(T, compose.Services, "dataexchange_1")
+	assert.Empty(T, compose.Services["firefly_core_1"].DependsOn)
+	assert.Equal(T, []string{"host.docker.internal:host-gateway"}, compose.Services["firefly_core_1"].ExtraHosts)
+
+	processes := s.externalProcesses(true)
+	assert.Len(T, processes, 1)
+	assert.Equal(T, 5205, processes[0].port)
+	assert.Equal(T, "DATA_DIRECTORY=/stacks/dev/runtime/config/dataexchange_1 npm start", processes[0].command)
+
+	// Core of a member in docker reaches the data exchange on the host, and an external core reaches it on localhost
+	member := s.Stack.Members[1]
+	assert.Equal(T, "http://host.docker.internal:5205", member.ComponentURL(types.ComponentCore, types.ComponentDataExchange, "http://dataexchange_1:3000", "http://127.0.0.1:5205"))
+	member.External = true
+	assert.Equal(T, "http://127.0.0.1:5205", member.ComponentURL(types.ComponentCore, types.ComponentDataExchange, "http://dataexchange_1:3000", "http://127.0.0.1:5205"))
+}
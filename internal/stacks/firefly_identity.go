@@ -23,12 +23,13 @@ import (
 	"github.com/hyperledger/firefly-cli/internal/core"
 )
 
-func (s *StackManager) registerFireflyIdentities() error {
+// registerFireflyIdentities registers the org and node of each member in a multiparty namespace
+func (s *StackManager) registerFireflyIdentities(namespace string) error {
 	emptyObject := make(map[string]interface{})
 
 	for _, member := range s.Stack.Members {
-		ffURL := fmt.Sprintf("http://127.0.0.1:%d/api/v1", member.ExposedFireflyPort)
-		s.Log.Info(fmt.Sprintf("registering org and node for member %s", member.ID))
+		ffURL := fmt.Sprintf("%s://127.0.0.1:%d/api/v1/namespaces/%s", s.Stack.Scheme(), member.ExposedFireflyPort, namespace)
+		s.Log.Info(fmt.Sprintf("registering org and node for member %s in namespace '%s'", member.ID, namespace))
 
 		registerOrgURL := fmt.Sprintf("%s/network/organizations/self?confirm=true", ffURL)
 		err := core.RequestWithRetry(s.ctx, http.MethodPost, registerOrgURL, emptyObject, nil)
@@ -39,7 +40,7 @@ func (s *StackManager) registerFireflyIdentities() error {
 		registerNodeURL := fmt.Sprintf("%s/network/nodes/self?confirm=true", ffURL)
 		err = core.RequestWithRetry(s.ctx, http.MethodPost, registerNodeURL, emptyObject, nil)
 		if err != nil {
-			return nil
+			return err
 		}
 	}
 	return nil
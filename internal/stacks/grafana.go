@@ -0,0 +1,47 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"embed"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//go:embed grafana
+var grafanaFiles embed.FS
+
+// writeGrafanaConfig writes the Grafana provisioning files for the Prometheus datasource and the
+// FireFly dashboards into the config directory, which Grafana mounts as its provisioning directory
+func (s *StackManager) writeGrafanaConfig(configDir string) error {
+	return fs.WalkDir(grafanaFiles, "grafana", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(configDir, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		b, err := grafanaFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dest, b, 0644)
+	})
+}
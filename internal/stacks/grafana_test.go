@@ -0,0 +1,68 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteGrafanaConfig(T *testing.T) {
+	configDir := T.TempDir()
+	s := &StackManager{}
+	assert.NoError(T, s.writeGrafanaConfig(configDir))
+
+	// The Prometheus datasource is provisioned read-only, with the uid the dashboards refer to
+	b, err := ioutil.ReadFile(filepath.Join(configDir, "grafana", "datasources", "prometheus.yaml"))
+	assert.NoError(T, err)
+	var datasources struct {
+		Datasources []map[string]interface{} `yaml:"datasources"`
+	}
+	assert.NoError(T, yaml.Unmarshal(b, &datasources))
+	assert.Len(T, datasources.Datasources, 1)
+	assert.Equal(T, "prometheus", datasources.Datasources[0]["uid"])
+	assert.Equal(T, "http://prometheus:9090", datasources.Datasources[0]["url"])
+	assert.Equal(T, false, datasources.Datasources[0]["editable"])
+
+	// The dashboards are loaded from where the provisioning directory is mounted in the container
+	b, err = ioutil.ReadFile(filepath.Join(configDir, "grafana", "dashboards", "firefly.yaml"))
+	assert.NoError(T, err)
+	var providers struct {
+		Providers []struct {
+			Options map[string]string `yaml:"options"`
+		} `yaml:"providers"`
+	}
+	assert.NoError(T, yaml.Unmarshal(b, &providers))
+	assert.Equal(T, "/etc/grafana/provisioning/dashboards/firefly", providers.Providers[0].Options["path"])
+
+	dashboards, err := filepath.Glob(filepath.Join(configDir, "grafana", "dashboards", "firefly", "*.json"))
+	assert.NoError(T, err)
+	assert.Len(T, dashboards, 4)
+	for _, dashboard := range dashboards {
+		b, err := ioutil.ReadFile(dashboard)
+		assert.NoError(T, err)
+		var parsed map[string]interface{}
+		assert.NoError(T, json.Unmarshal(b, &parsed), dashboard)
+		assert.NotEmpty(T, parsed["title"], dashboard)
+		assert.Contains(T, string(b), `"uid": "prometheus"`, dashboard)
+	}
+}
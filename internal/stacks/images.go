@@ -0,0 +1,120 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// setImages applies image overrides in the form "<component>=<image>" to the manifest of the stack,
+// on top of any image from a manifest file, the release manifest or the defaults of the CLI
+func (s *StackManager) setImages(overrides []string) error {
+	for _, override := range overrides {
+		key, entry, err := types.ParseImageOverride(override)
+		if err != nil {
+			return err
+		}
+		entry.Local = s.isLocalImage(entry.GetDockerImageString())
+		s.Log.Info(fmt.Sprintf("using image '%s' for %s (local=%t)", entry.GetDockerImageString(), key, entry.Local))
+		if err := s.Stack.VersionManifest.SetEntry(key, entry); err != nil {
+			return err
+		}
+		// An explicit image replaces a build from a linked source directory
+		delete(s.Stack.DevLinks, key)
+	}
+	return nil
+}
+
+// isLocalImage returns whether an image is in the local docker image store but cannot be found in a
+// registry, like a custom build, in which case it must not be pulled
+func (s *StackManager) isLocalImage(image string) bool {
+	if _, err := docker.RunDockerCommandBuffered(s.ctx, "", "image", "inspect", image); err != nil {
+		return false
+	}
+	_, err := docker.GetImageDigest(image)
+	return err != nil
+}
+
+// SetImages overrides images of an existing stack, regenerates its docker compose file, and recreates
+// the running containers of the services whose image changed
+func (s *StackManager) SetImages(overrides []string) error {
+	before := s.buildDockerCompose()
+	if err := s.setImages(overrides); err != nil {
+		return err
+	}
+	return s.applyImageChanges(before, nil)
+}
+
+// applyImageChanges saves the manifest of the stack and regenerates its docker compose file, then recreates the
+// running containers of services whose image differs from the compose file before the change. Rebuilt components
+// keep the name of their image, so the services that run them are always recreated.
+func (s *StackManager) applyImageChanges(before *docker.DockerComposeConfig, rebuilt []string) error {
+	after := s.buildDockerCompose()
+	if err := s.writeStackJSON(); err != nil {
+		return err
+	}
+	if err := s.writeDockerCompose(after); err != nil {
+		return err
+	}
+	return s.recreateRunningServices(after, s.changedImageServices(before, after, rebuilt), len(rebuilt) > 0)
+}
+
+// changedImageServices returns the services whose image changed, or was rebuilt, between two compose files
+func (s *StackManager) changedImageServices(before, after *docker.DockerComposeConfig, rebuilt []string) []string {
+	rebuiltServices := map[string]bool{}
+	for _, name := range s.devServices(after, rebuilt) {
+		rebuiltServices[name] = true
+	}
+	var changed []string
+	for name, service := range after.Services {
+		if previous, ok := before.Services[name]; rebuiltServices[name] || !ok || previous.Image != service.Image {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// recreateRunningServices recreates the containers of the given services that are running, without touching their
+// dependencies. A forced recreate is needed when the image was rebuilt under the same name.
+func (s *StackManager) recreateRunningServices(compose *docker.DockerComposeConfig, services []string, force bool) error {
+	var running []string
+	for _, name := range services {
+		out, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "ps", "-q", "--filter", fmt.Sprintf("name=^%s$", compose.Services[name].ContainerName))
+		if err != nil {
+			return err
+		}
+		if out != "" {
+			running = append(running, name)
+		}
+	}
+	if len(running) == 0 {
+		s.Log.Info("no running containers use the changed images")
+		return nil
+	}
+	sort.Strings(running)
+	s.Log.Info(fmt.Sprintf("recreating %v", running))
+	command := []string{"up", "-d", "--no-deps"}
+	if force {
+		command = append(command, "--force-recreate")
+	}
+	return s.runDockerComposeCommand(append(command, running...)...)
+}
@@ -0,0 +1,284 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+type LogEntry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Service   string                 `json:"service"`
+	Member    string                 `json:"member,omitempty"`
+	Level     string                 `json:"level,omitempty"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+var logLevels = map[string]int{
+	"trace": 0,
+	"debug": 1,
+	"info":  2,
+	"warn":  3,
+	"error": 4,
+	"fatal": 5,
+}
+
+var logLevelAliases = map[string]string{
+	"verbose":  "debug",
+	"log":      "info",
+	"notice":   "info",
+	"warning":  "warn",
+	"err":      "error",
+	"crit":     "fatal",
+	"critical": "fatal",
+	"panic":    "fatal",
+}
+
+var (
+	ansiRegex         = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	logrusLevelRegex  = regexp.MustCompile(`\blevel=(\w+)`)
+	fireflyLevelRegex = regexp.MustCompile(`^\[[^\]]*\]\s+(\w+)\b`)
+	genericLevelRegex = regexp.MustCompile(`\b(TRACE|DEBUG|VERBOSE|INFO|LOG|WARN|WARNING|ERROR|FATAL|PANIC)\b`)
+	logColors         = []string{"32", "33", "34", "35", "36", "92", "93", "94", "95", "96"}
+)
+
+type logFilter struct {
+	grep     *regexp.Regexp
+	minLevel int
+}
+
+func newLogFilter(options *types.LogsOptions) (*logFilter, error) {
+	f := &logFilter{minLevel: -1}
+	if options.Grep != "" {
+		grep, err := regexp.Compile(options.Grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grep expression: %s", err)
+		}
+		f.grep = grep
+	}
+	if options.Level != "" {
+		level := normalizeLogLevel(options.Level)
+		l, ok := logLevels[level]
+		if !ok {
+			return nil, fmt.Errorf("invalid log level '%s' - options are: [trace debug info warn error fatal]", options.Level)
+		}
+		f.minLevel = l
+	}
+	return f, nil
+}
+
+// accept returns true if the entry should be displayed. When filtering by level,
+// entries where no level could be determined are not displayed.
+func (f *logFilter) accept(entry *LogEntry) bool {
+	if f.grep != nil && !f.grep.MatchString(entry.Message) {
+		return false
+	}
+	if f.minLevel >= 0 {
+		l, ok := logLevels[entry.Level]
+		if !ok || l < f.minLevel {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeLogLevel(level string) string {
+	level = strings.ToLower(level)
+	if alias, ok := logLevelAliases[level]; ok {
+		return alias
+	}
+	return level
+}
+
+// parseLogLine parses a line of output from "docker logs --timestamps", extracting the
+// log level from JSON, logrus and FireFly style log lines
+func parseLogLine(service *ServiceRef, line string) *LogEntry {
+	entry := &LogEntry{
+		Service: service.ServiceName,
+		Member:  service.MemberID,
+	}
+	line = ansiRegex.ReplaceAllString(line, "")
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			entry.Timestamp = t
+			line = line[i+1:]
+		}
+	}
+	entry.Message = line
+
+	var level string
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			entry.Fields = fields
+			for _, key := range []string{"level", "lvl", "severity", "@level"} {
+				if l, ok := fields[key].(string); ok {
+					level = l
+					break
+				}
+			}
+		}
+	}
+	if level == "" {
+		if m := logrusLevelRegex.FindStringSubmatch(line); m != nil {
+			level = m[1]
+		} else if m := fireflyLevelRegex.FindStringSubmatch(line); m != nil && isLogLevel(m[1]) {
+			level = m[1]
+		} else if m := genericLevelRegex.FindStringSubmatch(line); m != nil {
+			level = m[1]
+		}
+	}
+	if level != "" {
+		entry.Level = normalizeLogLevel(level)
+	}
+	return entry
+}
+
+func isLogLevel(s string) bool {
+	_, ok := logLevels[normalizeLogLevel(s)]
+	return ok
+}
+
+type logPrinter struct {
+	mux   sync.Mutex
+	json  bool
+	color bool
+	width int
+}
+
+func (p *logPrinter) print(entry *LogEntry) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.json {
+		b, _ := json.Marshal(entry)
+		fmt.Println(string(b))
+		return
+	}
+	prefix := fmt.Sprintf("%-*s |", p.width, entry.Service)
+	if p.color {
+		prefix = fmt.Sprintf("\u001b[%sm%s\u001b[0m", serviceColor(entry.Service), prefix)
+	}
+	timestamp := ""
+	if !entry.Timestamp.IsZero() {
+		timestamp = entry.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z") + " "
+	}
+	fmt.Printf("%s %s%s\n", prefix, timestamp, entry.Message)
+}
+
+// serviceColor picks a color for a service based on its name, so it is the same every time
+func serviceColor(serviceName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(serviceName))
+	return logColors[h.Sum32()%uint32(len(logColors))]
+}
+
+// Logs prints the logs of the selected services in the stack. Unless following the output,
+// logs are merged across services and printed in timestamp order. Services whose logs cannot
+// be read are skipped with a warning, unless none of them can be read.
+func (s *StackManager) Logs(options *types.LogsOptions) error {
+	services, err := s.FindServices(options.Members, options.Services)
+	if err != nil {
+		return err
+	}
+	filter, err := newLogFilter(options)
+	if err != nil {
+		return err
+	}
+
+	printer := &logPrinter{
+		json:  options.JSON,
+		color: options.Color,
+	}
+	for _, service := range services {
+		if len(service.ServiceName) > printer.width {
+			printer.width = len(service.ServiceName)
+		}
+	}
+
+	var entriesMux sync.Mutex
+	entries := make([]*LogEntry, 0)
+	errs := make([]string, 0)
+	var wg sync.WaitGroup
+	for _, service := range services {
+		wg.Add(1)
+		go func(service *ServiceRef) {
+			defer wg.Done()
+			err := docker.StreamDockerCommand(s.ctx, s.Stack.StackDir, func(line string) {
+				entry := parseLogLine(service, line)
+				if !filter.accept(entry) {
+					return
+				}
+				if options.Follow {
+					printer.print(entry)
+				} else {
+					entriesMux.Lock()
+					entries = append(entries, entry)
+					entriesMux.Unlock()
+				}
+			}, s.dockerLogsArgs(service, options)...)
+			if err != nil {
+				entriesMux.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %s", service.ServiceName, err))
+				entriesMux.Unlock()
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	for _, entry := range entries {
+		printer.print(entry)
+	}
+	if len(errs) > 0 && len(errs) == len(services) {
+		return fmt.Errorf("unable to get logs for any service of stack '%s' - is it running?\n%s", s.Stack.Name, strings.Join(errs, "\n"))
+	}
+	for _, e := range errs {
+		s.Log.Warn(fmt.Sprintf("unable to get logs for %s", e))
+	}
+	return nil
+}
+
+func (s *StackManager) dockerLogsArgs(service *ServiceRef, options *types.LogsOptions) []string {
+	args := []string{"logs", "--timestamps"}
+	if options.Follow {
+		args = append(args, "--follow")
+	}
+	if options.Tail != "" {
+		args = append(args, "--tail", options.Tail)
+	}
+	if options.Since != "" {
+		args = append(args, "--since", options.Since)
+	}
+	if options.Until != "" {
+		args = append(args, "--until", options.Until)
+	}
+	return append(args, service.ContainerName)
+}
@@ -0,0 +1,54 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogLineLevels(T *testing.T) {
+	service := &ServiceRef{ServiceName: "firefly_core_0", Name: "firefly_core", MemberID: "0"}
+	tests := map[string]string{
+		`2022-08-10T14:23:35.123456789Z [2022-08-10T14:23:35.123Z]  INFO Starting FireFly pid=1`:  "info",
+		`2022-08-10T14:23:35.123456789Z time="2022-08-10T14:23:35Z" level=warning msg="slow"`:     "warn",
+		`2022-08-10T14:23:35.123456789Z {"level":"error","msg":"failed"}`:                         "error",
+		`2022-08-10T14:23:35.123456789Z [Nest] 1  - 08/10/2022, 2:23:35 PM     LOG [App] started`: "info",
+		`2022-08-10T14:23:35.123456789Z DEBUG[08-10|14:23:35.123] Served eth_blockNumber reqid=1`: "debug",
+		`2022-08-10T14:23:35.123456789Z a line with no level`:                                     "",
+	}
+	for line, level := range tests {
+		entry := parseLogLine(service, line)
+		assert.Equal(T, level, entry.Level, line)
+		assert.Equal(T, 2022, entry.Timestamp.Year())
+		assert.Equal(T, "0", entry.Member)
+	}
+}
+
+func TestLogFilter(T *testing.T) {
+	filter, err := newLogFilter(&types.LogsOptions{Level: "warning", Grep: "tx"})
+	assert.NoError(T, err)
+	assert.True(T, filter.accept(&LogEntry{Level: "error", Message: "tx failed"}))
+	assert.False(T, filter.accept(&LogEntry{Level: "info", Message: "tx sent"}))
+	assert.False(T, filter.accept(&LogEntry{Level: "error", Message: "failed"}))
+	assert.False(T, filter.accept(&LogEntry{Message: "tx"}))
+
+	_, err = newLogFilter(&types.LogsOptions{Level: "loud"})
+	assert.Regexp(T, "invalid log level", err)
+}
@@ -0,0 +1,485 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hyperledger/firefly-cli/internal/tokens"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"gopkg.in/yaml.v3"
+)
+
+// NamespaceInfo describes a predefined namespace in the core config of a stack
+type NamespaceInfo struct {
+	Name       string
+	Multiparty bool
+	Plugins    []string
+}
+
+// ListNamespaces returns the predefined namespaces in the core config of the first member
+func (s *StackManager) ListNamespaces() ([]*NamespaceInfo, error) {
+	configDir, err := s.currentConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	config, err := readCoreConfig(configDir, s.Stack.Members[0])
+	if err != nil {
+		return nil, err
+	}
+	return namespacesFromConfig(config), nil
+}
+
+// AddNamespace adds a predefined namespace to the core config of every member. It starts token
+// connectors for the namespace, deploys their contracts and a FireFly contract for the namespace
+// if it is multiparty (unless an existing contract address is given), restarts FireFly core and
+// registers the identities of each member in the new namespace. If any of this fails, the namespace
+// is removed again.
+func (s *StackManager) AddNamespace(options *types.NamespaceOptions) (messages []string, err error) {
+	if err := s.checkCanAddNamespace(options); err != nil {
+		return nil, err
+	}
+	tokenProviders, err := types.FFEnumArray(s.ctx, options.TokenProviders)
+	if err != nil {
+		return nil, err
+	}
+	ns := &types.StackNamespace{
+		Name:            options.Name,
+		Blockchain:      options.Blockchain,
+		Multiparty:      options.Multiparty,
+		ContractAddress: options.ContractAddress,
+	}
+	tokensPorts := len(s.Stack.Members[0].ExposedTokensPorts)
+	defer func() {
+		if err != nil {
+			s.rollbackNamespace(ns.Name, tokensPorts)
+		}
+	}()
+	for _, tp := range tokenProviders {
+		if tp.Equals(types.TokenProviderNone) {
+			continue
+		}
+		index, err := s.allocateTokenPorts()
+		if err != nil {
+			return nil, err
+		}
+		ns.TokenProviders = append(ns.TokenProviders, tp)
+		ns.TokenIndexes = append(ns.TokenIndexes, index)
+	}
+	s.Stack.Namespaces = append(s.Stack.Namespaces, ns)
+	s.loadTokenProviders()
+	plugins, providers := s.namespaceTokenProviders(ns.Name)
+	// Record the namespace before its token connectors start, so that their containers and ports belong to the stack
+	if err := s.writeStackJSON(); err != nil {
+		return messages, err
+	}
+
+	s.Log.Info(fmt.Sprintf("starting token connectors for namespace '%s'", ns.Name))
+	if err := s.writeDockerCompose(s.buildDockerCompose()); err != nil {
+		return messages, err
+	}
+	if err := s.runDockerComposeCommand("up", "-d"); err != nil {
+		return messages, err
+	}
+
+	var contractLocation interface{}
+	if ns.Multiparty {
+		if ns.ContractAddress != "" {
+			contractLocation = map[string]interface{}{"address": ns.ContractAddress}
+		} else {
+			s.Log.Info(fmt.Sprintf("deploying FireFly smart contract for namespace '%s'", ns.Name))
+			result, err := s.blockchainProviders[ns.Blockchain].DeployFireFlyContract()
+			if err != nil {
+				return messages, err
+			}
+			if result == nil {
+				return messages, fmt.Errorf("no FireFly smart contract was deployed for namespace '%s'", ns.Name)
+			}
+			if result.Message != "" {
+				messages = append(messages, result.Message)
+			}
+			result.DeployedContract.Name = fmt.Sprintf("FireFly_%s", ns.Name)
+			s.Stack.State.DeployedContracts = append(s.Stack.State.DeployedContracts, result.DeployedContract)
+			contractLocation = result.DeployedContract.Location
+		}
+	}
+
+	if !s.blockchainStacks[ns.Blockchain].DisableTokenFactories {
+		for i, tp := range plugins {
+			result, err := providers[i].DeploySmartContracts(tp.Index)
+			if err != nil {
+				return messages, err
+			}
+			if result != nil {
+				if result.Message != "" {
+					messages = append(messages, result.Message)
+				}
+				s.Stack.State.DeployedContracts = append(s.Stack.State.DeployedContracts, result.DeployedContract)
+			}
+		}
+	}
+
+	configDir := filepath.Join(s.Stack.RuntimeDir, "config")
+	for _, member := range s.Stack.Members {
+		blockchainStack := s.blockchainStacks[ns.Blockchain]
+		orgConfig := s.blockchainProviders[ns.Blockchain].GetOrgConfig(blockchainStack, blockchainStack.Members[*member.Index])
+		namespace := &types.Namespace{
+			Name:       ns.Name,
+			Plugins:    []string{"database0", types.BlockchainPluginName(ns.Blockchain)},
+			DefaultKey: orgConfig.Key,
+		}
+		if ns.Multiparty {
+			namespace.Plugins = append(namespace.Plugins, "dataexchange0", "sharedstorage0")
+			namespace.Multiparty = &types.MultipartyConfig{
+				Enabled:  true,
+				Org:      orgConfig,
+				Contract: []*types.ContractConfig{{Location: contractLocation}},
+			}
+		}
+		tokensConfigs := make([]*types.TokensConfig, len(plugins))
+		for i, tp := range plugins {
+			tokensConfigs[i] = providers[i].GetFireflyConfig(member, tp.Index)
+			tokensConfigs[i].Name = tp.Name
+			namespace.Plugins = append(namespace.Plugins, tp.Name)
+		}
+		s.Log.Info(fmt.Sprintf("adding namespace '%s' to the config of member %s", ns.Name, member.ID))
+		if err := updateCoreConfig(configDir, member, func(config map[string]interface{}) error {
+			return addNamespaceToConfig(config, namespace, tokensConfigs)
+		}); err != nil {
+			return messages, err
+		}
+	}
+
+	if err := s.writeStackStateJSON(s.Stack.RuntimeDir); err != nil {
+		return messages, err
+	}
+
+	// Re-write the docker-compose config again, now that the token connectors have their contracts
+	if err := s.writeDockerCompose(s.buildDockerCompose()); err != nil {
+		return messages, err
+	}
+	if err := s.runDockerComposeCommand("up", "-d"); err != nil {
+		return messages, err
+	}
+	if err := s.restartFireflyCore(); err != nil {
+		return messages, err
+	}
+
+	if ns.Multiparty {
+		if ns.ContractAddress == "" {
+			if err := s.registerFireflyIdentities(ns.Name); err != nil {
+				return messages, err
+			}
+		} else {
+			messages = append(messages, fmt.Sprintf("NOTE: You have selected to use a pre-existing FireFly smart contract, so you will need to register your org by calling the /namespaces/%s/network/organizations/self and the /namespaces/%s/network/nodes/self endpoints", ns.Name, ns.Name))
+		}
+	}
+
+	for i, tp := range plugins {
+		if err := providers[i].FirstTimeSetup(tp.Index); err != nil {
+			return messages, err
+		}
+	}
+	return messages, nil
+}
+
+// RemoveNamespace removes a namespace that was added to the stack from the core config of every
+// member, stops its token connectors and restarts FireFly core. Its contracts stay on the chain.
+func (s *StackManager) RemoveNamespace(name string) error {
+	ns := s.Stack.GetNamespace(name)
+	if ns == nil {
+		return fmt.Errorf("namespace '%s' was not added to stack '%s'", name, s.Stack.Name)
+	}
+	plugins, _ := s.namespaceTokenProviders(name)
+	tokenPlugins := []string{}
+	for _, tp := range plugins {
+		tokenPlugins = append(tokenPlugins, tp.Name)
+	}
+
+	configDir := filepath.Join(s.Stack.RuntimeDir, "config")
+	for _, member := range s.Stack.Members {
+		s.Log.Info(fmt.Sprintf("removing namespace '%s' from the config of member %s", name, member.ID))
+		if err := updateCoreConfig(configDir, member, func(config map[string]interface{}) error {
+			removeNamespaceFromConfig(config, name, tokenPlugins)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	namespaces := make([]*types.StackNamespace, 0, len(s.Stack.Namespaces))
+	for _, ns := range s.Stack.Namespaces {
+		if ns.Name != name {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	s.Stack.Namespaces = namespaces
+	s.loadTokenProviders()
+	if err := s.writeStackJSON(); err != nil {
+		return err
+	}
+	if err := s.writeDockerCompose(s.buildDockerCompose()); err != nil {
+		return err
+	}
+	if err := s.runDockerComposeCommand("up", "-d", "--remove-orphans"); err != nil {
+		return err
+	}
+	return s.restartFireflyCore()
+}
+
+// rollbackNamespace removes a namespace that could not be added, with its token connectors, and frees the
+// ports that were allocated for them. Contracts that were already deployed for it stay on the chain.
+func (s *StackManager) rollbackNamespace(name string, tokensPorts int) {
+	s.Log.Warn(fmt.Sprintf("removing namespace '%s' again, as it could not be added", name))
+	if s.Stack.GetNamespace(name) != nil {
+		if err := s.RemoveNamespace(name); err != nil {
+			s.Log.Error(fmt.Errorf("failed to remove namespace '%s' - remove it with 'ff namespaces remove %s %s': %s", name, s.Stack.Name, name, err))
+		}
+	}
+	if s.Stack.GetNamespace(name) != nil {
+		return
+	}
+	for _, member := range s.Stack.Members {
+		if len(member.ExposedTokensPorts) > tokensPorts {
+			member.ExposedTokensPorts = member.ExposedTokensPorts[:tokensPorts]
+		}
+	}
+	if err := s.writeStackJSON(); err != nil {
+		s.Log.Error(err)
+	}
+}
+
+func (s *StackManager) checkCanAddNamespace(options *types.NamespaceOptions) error {
+	hasRunBefore, err := s.Stack.HasRunBefore()
+	if err != nil {
+		return err
+	}
+	if !hasRunBefore {
+		return fmt.Errorf("stack '%s' has not been started yet - namespaces can be added once it has been run for the first time", s.Stack.Name)
+	}
+	if err := fftypes.ValidateFFNameField(s.ctx, options.Name, "name"); err != nil {
+		return err
+	}
+	namespaces, err := s.ListNamespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range namespaces {
+		if ns.Name == options.Name {
+			return fmt.Errorf("namespace '%s' already exists in stack '%s'", options.Name, s.Stack.Name)
+		}
+	}
+	if options.ContractAddress != "" && !options.Multiparty {
+		return fmt.Errorf("a contract address can only be set for a multiparty namespace")
+	}
+	if options.Multiparty && !s.Stack.MultipartyEnabled {
+		return fmt.Errorf("stack '%s' does not have multiparty enabled", s.Stack.Name)
+	}
+	if options.Blockchain < 0 || options.Blockchain >= s.Stack.BlockchainCount() {
+		return fmt.Errorf("stack '%s' does not have a blockchain with index %d", s.Stack.Name, options.Blockchain)
+	}
+	blockchainProvider := s.blockchainStacks[options.Blockchain].BlockchainProvider
+	if options.Multiparty && options.ContractAddress == "" && !blockchainProvider.Equals(types.BlockchainProviderEthereum) {
+		return fmt.Errorf("a FireFly contract can only be deployed for a new namespace on ethereum - set the address of an existing contract instead")
+	}
+	if len(options.TokenProviders) > 0 && blockchainProvider.Equals(types.BlockchainProviderFabric) {
+		return fmt.Errorf("token providers are not supported on fabric")
+	}
+	return nil
+}
+
+// namespaceTokenProviders returns the token connectors of an added namespace, with their tokens providers
+func (s *StackManager) namespaceTokenProviders(name string) ([]*types.TokenPlugin, []tokens.ITokensProvider) {
+	plugins := []*types.TokenPlugin{}
+	providers := []tokens.ITokensProvider{}
+	for i, tp := range s.tokenPlugins {
+		if tp.Namespace == name {
+			plugins = append(plugins, tp)
+			providers = append(providers, s.tokenProviders[i])
+		}
+	}
+	return plugins, providers
+}
+
+// allocateTokenPorts allocates a port on every member for a new token connector, and returns its index
+func (s *StackManager) allocateTokenPorts() (int, error) {
+	index := len(s.Stack.Members[0].ExposedTokensPorts)
+	port := s.highestExposedPort()
+	for _, member := range s.Stack.Members {
+		for {
+			port++
+			available, err := checkPortAvailable(port)
+			if err != nil {
+				return 0, err
+			}
+			if available {
+				break
+			}
+		}
+		member.ExposedTokensPorts = append(member.ExposedTokensPorts, port)
+	}
+	return index, nil
+}
+
+// restartFireflyCore restarts FireFly core for every member, so that it picks up changes to its config
+func (s *StackManager) restartFireflyCore() error {
+	services := []string{}
+	for _, member := range s.Stack.Members {
+		if member.External {
+			s.Log.Info(fmt.Sprintf("please restart your firefly core for member %s to pick up the new config", member.ID))
+		} else {
+			services = append(services, "firefly_core_"+member.ID)
+		}
+	}
+	if len(services) > 0 {
+		s.Log.Info("restarting FireFly core")
+		if err := s.runDockerComposeCommand(append([]string{"restart"}, services...)...); err != nil {
+			return err
+		}
+	}
+	return s.ensureFireflyNodesUp(false)
+}
+
+func coreConfigPath(configDir string, member *types.Organization) string {
+	return filepath.Join(configDir, fmt.Sprintf("firefly_core_%s.yml", member.ID))
+}
+
+// readCoreConfig reads the core config of a member without a schema, so that any
+// settings the CLI does not know about are kept when it is written back
+func readCoreConfig(configDir string, member *types.Organization) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(coreConfigPath(configDir, member))
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func updateCoreConfig(configDir string, member *types.Organization, update func(config map[string]interface{}) error) error {
+	config, err := readCoreConfig(configDir, member)
+	if err != nil {
+		return err
+	}
+	if err := update(config); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(coreConfigPath(configDir, member), b, 0755)
+}
+
+// toYAMLValue converts a config struct into the generic form that a config read by readCoreConfig has
+func toYAMLValue(v interface{}) (interface{}, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = yaml.Unmarshal(b, &value)
+	return value, err
+}
+
+func childMap(config map[string]interface{}, key string) map[string]interface{} {
+	child, ok := config[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		config[key] = child
+	}
+	return child
+}
+
+func addNamespaceToConfig(config map[string]interface{}, namespace *types.Namespace, tokensConfigs []*types.TokensConfig) error {
+	namespaces := childMap(config, "namespaces")
+	predefined, _ := namespaces["predefined"].([]interface{})
+	value, err := toYAMLValue(namespace)
+	if err != nil {
+		return err
+	}
+	namespaces["predefined"] = append(predefined, value)
+
+	if len(tokensConfigs) > 0 {
+		plugins := childMap(config, "plugins")
+		tokensPlugins, _ := plugins["tokens"].([]interface{})
+		for _, tokensConfig := range tokensConfigs {
+			value, err := toYAMLValue(tokensConfig)
+			if err != nil {
+				return err
+			}
+			tokensPlugins = append(tokensPlugins, value)
+		}
+		plugins["tokens"] = tokensPlugins
+	}
+	return nil
+}
+
+func removeNamespaceFromConfig(config map[string]interface{}, name string, tokenPlugins []string) {
+	namespaces := childMap(config, "namespaces")
+	predefined, _ := namespaces["predefined"].([]interface{})
+	keptNamespaces := []interface{}{}
+	for _, ns := range predefined {
+		if nsMap, ok := ns.(map[string]interface{}); !ok || nsMap["name"] != name {
+			keptNamespaces = append(keptNamespaces, ns)
+		}
+	}
+	namespaces["predefined"] = keptNamespaces
+
+	removed := map[string]bool{}
+	for _, tp := range tokenPlugins {
+		removed[tp] = true
+	}
+	plugins := childMap(config, "plugins")
+	tokensPlugins, _ := plugins["tokens"].([]interface{})
+	keptTokens := []interface{}{}
+	for _, tokensPlugin := range tokensPlugins {
+		if tokensMap, ok := tokensPlugin.(map[string]interface{}); !ok || !removed[fmt.Sprint(tokensMap["name"])] {
+			keptTokens = append(keptTokens, tokensPlugin)
+		}
+	}
+	plugins["tokens"] = keptTokens
+}
+
+func namespacesFromConfig(config map[string]interface{}) []*NamespaceInfo {
+	namespaces := []*NamespaceInfo{}
+	predefined, _ := childMap(config, "namespaces")["predefined"].([]interface{})
+	for _, ns := range predefined {
+		nsMap, ok := ns.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		info := &NamespaceInfo{
+			Name:    fmt.Sprint(nsMap["name"]),
+			Plugins: []string{},
+		}
+		if multiparty, ok := nsMap["multiparty"].(map[string]interface{}); ok {
+			info.Multiparty = multiparty["enabled"] == true
+		}
+		plugins, _ := nsMap["plugins"].([]interface{})
+		for _, plugin := range plugins {
+			info.Plugins = append(info.Plugins, fmt.Sprint(plugin))
+		}
+		namespaces = append(namespaces, info)
+	}
+	return namespaces
+}
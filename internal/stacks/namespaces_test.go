@@ -0,0 +1,73 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndRemoveNamespaceInConfig(T *testing.T) {
+	configDir := T.TempDir()
+	member := &types.Organization{ID: "0"}
+	configYAML := `log:
+  level: debug
+plugins:
+  tokens:
+  - name: erc20_erc721
+    type: fftokens
+namespaces:
+  default: default
+  predefined:
+  - name: default
+    plugins: [database0, blockchain0, dataexchange0, sharedstorage0, erc20_erc721]
+    multiparty:
+      enabled: true
+`
+	assert.NoError(T, ioutil.WriteFile(filepath.Join(configDir, "firefly_core_0.yml"), []byte(configYAML), 0644))
+
+	namespace := &types.Namespace{
+		Name:    "ns1",
+		Plugins: []string{"database0", "blockchain0", "erc1155_ns1"},
+	}
+	tokensConfigs := []*types.TokensConfig{{Type: "fftokens", Name: "erc1155_ns1"}}
+	assert.NoError(T, updateCoreConfig(configDir, member, func(config map[string]interface{}) error {
+		return addNamespaceToConfig(config, namespace, tokensConfigs)
+	}))
+
+	config, err := readCoreConfig(configDir, member)
+	assert.NoError(T, err)
+	namespaces := namespacesFromConfig(config)
+	assert.Len(T, namespaces, 2)
+	assert.True(T, namespaces[0].Multiparty)
+	assert.Equal(T, "ns1", namespaces[1].Name)
+	assert.False(T, namespaces[1].Multiparty)
+	assert.Equal(T, []string{"database0", "blockchain0", "erc1155_ns1"}, namespaces[1].Plugins)
+	assert.Len(T, config["plugins"].(map[string]interface{})["tokens"], 2)
+	// Settings the CLI does not know about are kept
+	assert.Equal(T, "debug", config["log"].(map[string]interface{})["level"])
+
+	removeNamespaceFromConfig(config, "ns1", []string{"erc1155_ns1"})
+	namespaces = namespacesFromConfig(config)
+	assert.Len(T, namespaces, 1)
+	assert.Equal(T, "default", namespaces[0].Name)
+	assert.Len(T, config["plugins"].(map[string]interface{})["tokens"], 1)
+}
@@ -0,0 +1,458 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/certs"
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+type dataExchangePeer struct {
+	config *PeerConfig
+	cert   []byte
+}
+
+// sharedNetworkServices are the services that talk to the other stacks on a shared network: data exchange
+// and IPFS for each member, and the blockchain node (or the signer, when it points at another stack's node)
+func (s *StackManager) sharedNetworkServices(compose *docker.DockerComposeConfig) []string {
+	services := []string{}
+	for _, member := range s.Stack.Members {
+		services = append(services, "dataexchange_"+member.ID, "ipfs_"+member.ID)
+	}
+	for _, name := range []string{"geth", "besu", "ethsigner"} {
+		if _, ok := compose.Services[name]; ok {
+			services = append(services, name)
+		}
+	}
+	return services
+}
+
+// attachSharedNetwork connects the services that talk to other stacks to the shared docker network. Only these services
+// are attached, and they address the other stacks by container name, so that service names which are the same in every
+// stack (firefly_core_0, geth etc.) still resolve to the services of their own stack.
+func (s *StackManager) attachSharedNetwork(compose *docker.DockerComposeConfig) {
+	if s.Stack.Network == nil {
+		return
+	}
+	networkName := s.Stack.Network.DockerNetwork
+	compose.Networks = map[string]*docker.Network{
+		networkName: {External: true},
+	}
+	for _, serviceName := range s.sharedNetworkServices(compose) {
+		if service, ok := compose.Services[serviceName]; ok {
+			service.Networks = []string{"default", networkName}
+		}
+	}
+}
+
+// JoinNetwork federates this stack with a host stack, so that the members of both stacks form one multiparty
+// network. This stack must not have been run yet, as it adopts the blockchain and FireFly contract of the host.
+func (s *StackManager) JoinNetwork(host *StackManager) error {
+	if err := s.checkCanJoin(host); err != nil {
+		return err
+	}
+	contractAddress, err := host.fireflyContractAddress()
+	if err != nil {
+		return err
+	}
+
+	networkName := fmt.Sprintf("%s_network", host.Stack.Name)
+	if host.Stack.Network != nil {
+		networkName = host.Stack.Network.DockerNetwork
+	}
+	if _, err := docker.RunDockerCommandBuffered(s.ctx, host.Stack.StackDir, "network", "inspect", networkName); err != nil {
+		s.Log.Info(fmt.Sprintf("creating docker network '%s'", networkName))
+		if err := docker.RunDockerCommand(s.ctx, host.Stack.StackDir, "network", "create", networkName); err != nil {
+			return err
+		}
+	}
+
+	hostNodeService := host.Stack.BlockchainNodeProvider.String()
+	s.Stack.Network = &types.NetworkConfig{
+		DockerNetwork:      networkName,
+		HostStack:          host.Stack.Name,
+		HostBlockchainNode: fmt.Sprintf("%s_%s", host.Stack.Name, hostNodeService),
+	}
+	chainID := host.Stack.ChainID()
+	s.Stack.ChainIDPtr = &chainID
+	s.Stack.ContractAddress = contractAddress
+	s.Stack.SwarmKey = host.Stack.SwarmKey
+
+	switch s.Stack.BlockchainNodeProvider {
+	case types.BlockchainNodeProviderGeth:
+		// The nodes can only peer if they start from the same genesis block
+		s.Log.Info(fmt.Sprintf("copying the genesis block of stack '%s'", host.Stack.Name))
+		genesis, err := ioutil.ReadFile(filepath.Join(host.Stack.RuntimeDir, "blockchain", "genesis.json"))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(s.Stack.InitDir, "blockchain", "genesis.json"), genesis, 0755); err != nil {
+			return err
+		}
+	case types.BlockchainNodeProviderRemoteRPC:
+		s.Stack.RemoteNodeURL = fmt.Sprintf("http://%s:8545", s.Stack.Network.HostBlockchainNode)
+		s.Log.Info(fmt.Sprintf("pointing the signer at %s", s.Stack.RemoteNodeURL))
+		if err := s.blockchainProvider.WriteConfig(&types.InitOptions{RemoteNodeURL: s.Stack.RemoteNodeURL, ChainID: chainID}); err != nil {
+			return err
+		}
+	}
+
+	// Reissue the data exchange certificates, in case they were issued before peer IDs included the stack name
+	if err := s.writeDataExchangeCerts(); err != nil {
+		return err
+	}
+	if err := s.exchangeDataExchangePeers(host); err != nil {
+		return err
+	}
+
+	if err := s.writeStackConfig(); err != nil {
+		return err
+	}
+	if err := s.writeDockerCompose(s.buildDockerCompose()); err != nil {
+		return err
+	}
+
+	if host.Stack.Network == nil {
+		host.Stack.Network = &types.NetworkConfig{DockerNetwork: networkName}
+		if err := host.writeStackJSON(); err != nil {
+			return err
+		}
+	}
+	if err := host.writeDockerCompose(host.buildDockerCompose()); err != nil {
+		return err
+	}
+	for _, member := range host.Stack.Members {
+		if err := host.copyDataExchangeConfigToVolume(member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StackManager) checkCanJoin(host *StackManager) error {
+	if s.Stack.Name == host.Stack.Name {
+		return fmt.Errorf("a stack cannot join its own network")
+	}
+	if hasRunBefore, err := host.Stack.HasRunBefore(); err != nil {
+		return err
+	} else if !hasRunBefore {
+		return fmt.Errorf("stack '%s' has not been started yet - start it to deploy its FireFly contract before other stacks join it", host.Stack.Name)
+	}
+	if hasRunBefore, err := s.Stack.HasRunBefore(); err != nil {
+		return err
+	} else if hasRunBefore {
+		return fmt.Errorf("stack '%s' has already been run - reset it with 'ff reset %s' before it joins another network", s.Stack.Name, s.Stack.Name)
+	}
+	if s.Stack.JoinedNetwork() {
+		return fmt.Errorf("stack '%s' has already joined the network of stack '%s'", s.Stack.Name, s.Stack.Network.HostStack)
+	}
+	if host.Stack.JoinedNetwork() {
+		return fmt.Errorf("stack '%s' has joined the network of stack '%s' - join that stack instead", host.Stack.Name, host.Stack.Network.HostStack)
+	}
+	if !s.Stack.MultipartyEnabled || !host.Stack.MultipartyEnabled {
+		return fmt.Errorf("both stacks must have multiparty mode enabled")
+	}
+	if !s.Stack.BlockchainProvider.Equals(types.BlockchainProviderEthereum) || !host.Stack.BlockchainProvider.Equals(types.BlockchainProviderEthereum) {
+		return fmt.Errorf("joining networks is only supported for ethereum stacks")
+	}
+	if !host.Stack.BlockchainNodeProvider.Equals(types.BlockchainNodeProviderGeth) && !host.Stack.BlockchainNodeProvider.Equals(types.BlockchainNodeProviderBesu) {
+		return fmt.Errorf("stack '%s' must run its own geth or besu node for other stacks to join it", host.Stack.Name)
+	}
+	switch s.Stack.BlockchainNodeProvider {
+	case types.BlockchainNodeProviderGeth:
+		if !host.Stack.BlockchainNodeProvider.Equals(types.BlockchainNodeProviderGeth) {
+			return fmt.Errorf("a geth node can only peer with another geth node, and besu nodes are not peered - initialize stack '%s' with the remote-rpc blockchain node provider to use the besu node of stack '%s'", s.Stack.Name, host.Stack.Name)
+		}
+	case types.BlockchainNodeProviderRemoteRPC:
+	default:
+		// Peering besu nodes is not supported, a besu stack can only host a network
+		return fmt.Errorf("stack '%s' must use the geth or remote-rpc blockchain node provider to join another network - besu nodes are not peered", s.Stack.Name)
+	}
+	if !s.Stack.IPFSMode.Equals(host.Stack.IPFSMode) {
+		return fmt.Errorf("both stacks must use the same IPFS mode")
+	}
+	if s.Stack.SharedIPFS() || host.Stack.SharedIPFS() {
+		return fmt.Errorf("stacks with a single shared IPFS node cannot join other networks")
+	}
+	for _, stack := range []*types.Stack{s.Stack, host.Stack} {
+		for _, member := range stack.Members {
+			if member.HasExternalComponents() {
+				return fmt.Errorf("stack '%s' has external members, which cannot be attached to a shared network", stack.Name)
+			}
+		}
+	}
+	// Stacks created before data exchange advertised its container name are not reachable from other stacks
+	for _, member := range host.Stack.Members {
+		peer, err := host.readDataExchangePeer(filepath.Join(host.Stack.RuntimeDir, "config"), member)
+		if err != nil {
+			return err
+		}
+		if peer.config.Endpoint != host.GenerateDataExchangeHTTPSConfig(member).P2P.Endpoint {
+			return fmt.Errorf("the data exchange of stack '%s' advertises the endpoint %s, which other stacks cannot reach - recreate the stack with this version of the CLI", host.Stack.Name, peer.config.Endpoint)
+		}
+	}
+	return nil
+}
+
+// fireflyContractAddress returns the address of the FireFly contract that the members of the stack use
+func (s *StackManager) fireflyContractAddress() (string, error) {
+	if s.Stack.ContractAddress != "" {
+		return s.Stack.ContractAddress, nil
+	}
+	for _, contract := range s.Stack.State.DeployedContracts {
+		if contract.Name != "FireFly" {
+			continue
+		}
+		switch location := contract.Location.(type) {
+		case map[string]string:
+			return location["address"], nil
+		case map[string]interface{}:
+			if address, ok := location["address"].(string); ok {
+				return address, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unable to find the FireFly contract of stack '%s'", s.Stack.Name)
+}
+
+func (s *StackManager) readDataExchangePeer(configDir string, member *types.Organization) (*dataExchangePeer, error) {
+	memberDXDir := filepath.Join(configDir, "dataexchange_"+member.ID)
+	config, err := readDataExchangeConfig(memberDXDir)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := ioutil.ReadFile(filepath.Join(memberDXDir, "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := certs.ReadCertificate(filepath.Join(memberDXDir, "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(cert.Subject.Organization) == 0 {
+		return nil, fmt.Errorf("the data exchange certificate of member %s in stack '%s' has no organization", member.ID, s.Stack.Name)
+	}
+	return &dataExchangePeer{
+		config: &PeerConfig{ID: cert.Subject.Organization[0], Endpoint: config.P2P.Endpoint},
+		cert:   certPEM,
+	}, nil
+}
+
+func readDataExchangeConfig(memberDXDir string) (*DataExchangePeerConfig, error) {
+	b, err := ioutil.ReadFile(filepath.Join(memberDXDir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	var config *DataExchangePeerConfig
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	if config.P2P == nil {
+		return nil, fmt.Errorf("no p2p endpoint found in %s", filepath.Join(memberDXDir, "config.json"))
+	}
+	return config, nil
+}
+
+// exchangeDataExchangePeers adds the data exchange of every member of each stack as a peer of every member
+// of the other, with its certificate, and makes each data exchange trust the CA of the other stack
+func (s *StackManager) exchangeDataExchangePeers(host *StackManager) error {
+	configDir := filepath.Join(s.Stack.InitDir, "config")
+	hostConfigDir := filepath.Join(host.Stack.RuntimeDir, "config")
+	peers := []*dataExchangePeer{}
+	for _, member := range s.Stack.Members {
+		peer, err := s.readDataExchangePeer(configDir, member)
+		if err != nil {
+			return err
+		}
+		peers = append(peers, peer)
+	}
+	hostPeers := []*dataExchangePeer{}
+	for _, member := range host.Stack.Members {
+		peer, err := host.readDataExchangePeer(hostConfigDir, member)
+		if err != nil {
+			return err
+		}
+		hostPeers = append(hostPeers, peer)
+	}
+	caPEM, err := s.CACertPEM()
+	if err != nil {
+		return err
+	}
+	hostCAPEM, err := host.CACertPEM()
+	if err != nil {
+		return err
+	}
+
+	s.Log.Info(fmt.Sprintf("exchanging data exchange certificates with stack '%s'", host.Stack.Name))
+	for _, member := range s.Stack.Members {
+		if err := addDataExchangePeers(filepath.Join(configDir, "dataexchange_"+member.ID), hostPeers, hostCAPEM); err != nil {
+			return err
+		}
+	}
+	for _, member := range host.Stack.Members {
+		if err := addDataExchangePeers(filepath.Join(hostConfigDir, "dataexchange_"+member.ID), peers, caPEM); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addDataExchangePeers(memberDXDir string, peers []*dataExchangePeer, caPEM []byte) error {
+	config, err := readDataExchangeConfig(memberDXDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(memberDXDir, "peer-certs"), 0755); err != nil {
+		return err
+	}
+	for _, peer := range peers {
+		exists := false
+		for _, existing := range config.Peers {
+			exists = exists || existing.ID == peer.config.ID
+		}
+		if !exists {
+			config.Peers = append(config.Peers, peer.config)
+		}
+		if err := ioutil.WriteFile(filepath.Join(memberDXDir, "peer-certs", peer.config.ID+".pem"), peer.cert, 0644); err != nil {
+			return err
+		}
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(memberDXDir, "config.json"), configBytes, 0755); err != nil {
+		return err
+	}
+
+	// Stacks created before certificates were issued from a stack CA do not have a CA bundle to add to
+	caPath := filepath.Join(memberDXDir, "ca.pem")
+	bundle, err := ioutil.ReadFile(caPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if bytes.Contains(bundle, caPEM) {
+		return nil
+	}
+	return ioutil.WriteFile(caPath, append(bundle, caPEM...), 0644)
+}
+
+// connectToHostStack peers the geth node of this stack with the node of the host stack, and adds the IPFS
+// nodes of the host to the bootstrap list of each IPFS node in this stack. The host stack must be running.
+func (s *StackManager) connectToHostStack() error {
+	network := s.Stack.Network
+	s.Log.Info(fmt.Sprintf("connecting to the network of stack '%s'", network.HostStack))
+	hostIPFSContainers, err := s.hostIPFSContainers()
+	if err != nil {
+		return err
+	}
+
+	if s.Stack.BlockchainNodeProvider.Equals(types.BlockchainNodeProviderGeth) {
+		output, err := s.runDockerCommandWithRetry("exec", network.HostBlockchainNode, "geth", "attach", "--exec", "admin.nodeInfo.enode", "/data/geth.ipc")
+		if err != nil {
+			return fmt.Errorf("unable to read the enode of stack '%s' - is it running? %s", network.HostStack, err)
+		}
+		enode := strings.Trim(strings.TrimSpace(output), `"`)
+		if !strings.HasPrefix(enode, "enode://") || !strings.Contains(enode, "@") {
+			return fmt.Errorf("unexpected enode '%s' from %s", enode, network.HostBlockchainNode)
+		}
+		ip, err := s.containerIP(network.HostBlockchainNode)
+		if err != nil {
+			return err
+		}
+		peer := fmt.Sprintf("%s@%s:30311", enode[:strings.Index(enode, "@")], ip)
+		if _, err := s.runDockerCommandWithRetry("exec", fmt.Sprintf("%s_geth", s.Stack.Name), "geth", "attach", "--exec", fmt.Sprintf(`admin.addPeer("%s")`, peer), "/data/geth.ipc"); err != nil {
+			return err
+		}
+	}
+
+	hostIPFSNodes := []string{}
+	for _, container := range hostIPFSContainers {
+		if _, err := s.containerIP(container); err != nil {
+			return err
+		}
+		peerID, err := s.runDockerCommandWithRetry("exec", container, "ipfs", "id", "-f", "<id>")
+		if err != nil {
+			return fmt.Errorf("unable to read the IPFS peer ID of %s - is stack '%s' running? %s", container, network.HostStack, err)
+		}
+		hostIPFSNodes = append(hostIPFSNodes, fmt.Sprintf("/dns4/%s/tcp/4001/p2p/%s", container, strings.TrimSpace(peerID)))
+	}
+	for _, member := range s.Stack.Members {
+		container := fmt.Sprintf("%s_ipfs_%s", s.Stack.Name, member.ID)
+		for _, address := range hostIPFSNodes {
+			if _, err := s.runDockerCommandWithRetry("exec", container, "ipfs", "bootstrap", "add", address); err != nil {
+				return err
+			}
+			if _, err := s.runDockerCommandWithRetry("exec", container, "ipfs", "swarm", "connect", address); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hostIPFSContainers lists the running IPFS containers of the host stack
+func (s *StackManager) hostIPFSContainers() ([]string, error) {
+	prefix := s.Stack.Network.HostStack + "_ipfs_"
+	output, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "ps", "--format", "{{.Names}}", "--filter", "name="+prefix)
+	if err != nil {
+		return nil, err
+	}
+	containers := []string{}
+	for _, name := range strings.Split(output, "\n") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, prefix) {
+			containers = append(containers, name)
+		}
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("stack '%s' is not running - start it before stack '%s'", s.Stack.Network.HostStack, s.Stack.Name)
+	}
+	return containers, nil
+}
+
+func (s *StackManager) containerIP(containerName string) (string, error) {
+	format := fmt.Sprintf(`{{(index .NetworkSettings.Networks "%s").IPAddress}}`, s.Stack.Network.DockerNetwork)
+	output, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "inspect", "-f", format, containerName)
+	ip := strings.TrimSpace(output)
+	if err != nil || ip == "" || ip == "<no value>" {
+		return "", fmt.Errorf("%s is not attached to the network '%s' - restart stack '%s' to attach it", containerName, s.Stack.Network.DockerNetwork, s.Stack.Network.HostStack)
+	}
+	return ip, nil
+}
+
+// runDockerCommandWithRetry gives containers that have just been started some time to be ready, with the
+// retry policy of the stack
+func (s *StackManager) runDockerCommandWithRetry(command ...string) (output string, err error) {
+	err = core.Retry(s.ctx, "docker "+command[0], func() error {
+		output, err = docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, command...)
+		return err
+	})
+	return output, err
+}
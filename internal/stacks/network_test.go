@@ -0,0 +1,55 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddDataExchangePeers(T *testing.T) {
+	memberDXDir := T.TempDir()
+	config := &DataExchangePeerConfig{
+		P2P:   &DataExchangeListenerConfig{Endpoint: "https://stackb_dataexchange_0:3001"},
+		Peers: []*PeerConfig{},
+	}
+	configBytes, _ := json.Marshal(config)
+	assert.NoError(T, ioutil.WriteFile(filepath.Join(memberDXDir, "config.json"), configBytes, 0644))
+	assert.NoError(T, ioutil.WriteFile(filepath.Join(memberDXDir, "ca.pem"), []byte("stackb ca\n"), 0644))
+
+	peers := []*dataExchangePeer{
+		{config: &PeerConfig{ID: "stacka_member_0", Endpoint: "https://stacka_dataexchange_0:3001"}, cert: []byte("cert0")},
+	}
+	// Adding the same peers twice does not duplicate them
+	assert.NoError(T, addDataExchangePeers(memberDXDir, peers, []byte("stacka ca\n")))
+	assert.NoError(T, addDataExchangePeers(memberDXDir, peers, []byte("stacka ca\n")))
+
+	config, err := readDataExchangeConfig(memberDXDir)
+	assert.NoError(T, err)
+	assert.Len(T, config.Peers, 1)
+	assert.Equal(T, "https://stacka_dataexchange_0:3001", config.Peers[0].Endpoint)
+	cert, err := ioutil.ReadFile(filepath.Join(memberDXDir, "peer-certs", "stacka_member_0.pem"))
+	assert.NoError(T, err)
+	assert.Equal(T, "cert0", string(cert))
+	bundle, err := ioutil.ReadFile(filepath.Join(memberDXDir, "ca.pem"))
+	assert.NoError(T, err)
+	assert.Equal(T, "stackb ca\nstacka ca\n", string(bundle))
+}
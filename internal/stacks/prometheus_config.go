@@ -1,6 +1,10 @@
 package stacks
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/docker"
+)
 
 type GlobalConfig struct {
 	ScrapeInterval string `yaml:"scrape_interval,omitempty"`
@@ -14,7 +18,8 @@ type ScrapeConfig struct {
 }
 
 type StaticConfig struct {
-	Targets []string `yaml:"targets,omitempty"`
+	Targets []string          `yaml:"targets,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
 }
 
 type PrometheusConfig struct {
@@ -22,30 +27,71 @@ type PrometheusConfig struct {
 	ScrapeConfigs []*ScrapeConfig `yaml:"scrape_configs,omitempty"`
 }
 
+// GeneratePrometheusConfig creates a scrape job for each type of component in the stack that serves
+// metrics, with a target for each of its services labelled with the service, and the member and org
+// it belongs to, if any. Ethconnect, the signer, the token connectors and data exchange do not
+// serve metrics, so they are not scraped.
 func (s *StackManager) GeneratePrometheusConfig() *PrometheusConfig {
 	config := &PrometheusConfig{
 		Global: &GlobalConfig{
 			ScrapeInterval: "5s",
 			ScrapeTimeout:  "5s",
 		},
-		ScrapeConfigs: []*ScrapeConfig{
-			{
-				JobName:     "fireflies",
-				MetricsPath: "/metrics",
-				StaticConfigs: []*StaticConfig{
-					{
-						Targets: []string{},
-					},
-				},
-			},
-		},
+		ScrapeConfigs: []*ScrapeConfig{},
+	}
+	jobs := map[string]*ScrapeConfig{}
+	addTarget := func(endpoint *docker.MetricsEndpoint, host, serviceName string) {
+		job, ok := jobs[endpoint.Job]
+		if !ok {
+			job = &ScrapeConfig{JobName: endpoint.Job, MetricsPath: endpoint.Path}
+			jobs[endpoint.Job] = job
+			config.ScrapeConfigs = append(config.ScrapeConfigs, job)
+		}
+		labels := map[string]string{"service": serviceName}
+		if member := s.serviceMember(serviceName); member != nil {
+			labels["member"] = member.ID
+			labels["org"] = member.OrgName
+		}
+		job.StaticConfigs = append(job.StaticConfigs, &StaticConfig{
+			Targets: []string{fmt.Sprintf("%s:%d", host, endpoint.Port)},
+			Labels:  labels,
+		})
 	}
 
-	for i, member := range s.Stack.Members {
-		config.ScrapeConfigs[0].StaticConfigs[0].Targets = append(config.ScrapeConfigs[0].StaticConfigs[0].Targets, fmt.Sprintf("firefly_core_%d:%d", i, member.ExposedFireflyMetricsPort))
+	for _, member := range s.Stack.Members {
+		// FireFly core of an external member runs outside of docker, where Prometheus cannot reach it
+		if !member.External {
+			serviceName := "firefly_core_" + member.ID
+			addTarget(&docker.MetricsEndpoint{Job: "firefly_core", Port: member.ExposedFireflyMetricsPort, Path: "/metrics"}, serviceName, serviceName)
+		}
+	}
+
+	// Neither can it reach components that members run on the host
+	externalServices := s.externalServices()
+	for _, serviceDefinition := range s.blockchainServiceDefinitions() {
+		if serviceDefinition.Metrics != nil && !externalServices[serviceDefinition.ServiceName] {
+			addTarget(serviceDefinition.Metrics, serviceDefinition.ServiceName, serviceDefinition.ServiceName)
+		}
+	}
+
+	if s.Stack.SharedServicesEnabled() {
+		added := map[string]bool{}
+		for _, member := range s.Stack.Members {
+			if serviceName := s.Stack.IPFSServiceName(member.ID); !added[serviceName] {
+				added[serviceName] = true
+				addTarget(&docker.MetricsEndpoint{Job: "ipfs", Port: 5001, Path: "/debug/metrics/prometheus"}, serviceName, serviceName)
+			}
+		}
+	}
 
-		if s.blockchainProvider.GetConnectorName() == "evmconnect" {
-			config.ScrapeConfigs[0].StaticConfigs[0].Targets = append(config.ScrapeConfigs[0].StaticConfigs[0].Targets, fmt.Sprintf("evmconnect_%d:%d", i, member.ExposedConnectorMetricsPort))
+	if s.Stack.Database == "postgres" {
+		// Postgres is scraped through an exporter alongside each postgres service
+		added := map[string]bool{}
+		for _, member := range s.Stack.Members {
+			if serviceName := s.Stack.PostgresServiceName(member.ID); !added[serviceName] {
+				added[serviceName] = true
+				addTarget(&docker.MetricsEndpoint{Job: "postgres", Port: 9187, Path: "/metrics"}, s.Stack.PostgresExporterServiceName(member.ID), serviceName)
+			}
 		}
 	}
 
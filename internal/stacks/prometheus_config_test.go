@@ -0,0 +1,54 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePrometheusConfig(T *testing.T) {
+	s := &StackManager{Stack: &types.Stack{
+		Name:                 "dev",
+		Database:             types.DatabaseSelectionPostgres,
+		IPFSMode:             types.IPFSModePublic,
+		SharedInfrastructure: true,
+		Members: []*types.Organization{
+			{ID: "0", OrgName: "org_0", ExposedFireflyMetricsPort: 5100},
+			{ID: "1", OrgName: "org_1", ExposedFireflyMetricsPort: 5200, External: true},
+		},
+	}}
+
+	config := s.GeneratePrometheusConfig()
+	jobs := map[string]*ScrapeConfig{}
+	for _, job := range config.ScrapeConfigs {
+		jobs[job.JobName] = job
+	}
+	assert.Len(T, jobs, 3)
+
+	// The core of the external member cannot be reached from Prometheus
+	assert.Len(T, jobs["firefly_core"].StaticConfigs, 1)
+	assert.Equal(T, []string{"firefly_core_0:5100"}, jobs["firefly_core"].StaticConfigs[0].Targets)
+	assert.Equal(T, map[string]string{"member": "0", "org": "org_0", "service": "firefly_core_0"}, jobs["firefly_core"].StaticConfigs[0].Labels)
+
+	// Shared infrastructure is scraped once, without a member
+	assert.Len(T, jobs["ipfs"].StaticConfigs, 1)
+	assert.Equal(T, map[string]string{"service": "ipfs"}, jobs["ipfs"].StaticConfigs[0].Labels)
+	assert.Equal(T, []string{"postgres_exporter:9187"}, jobs["postgres"].StaticConfigs[0].Targets)
+}
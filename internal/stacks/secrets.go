@@ -0,0 +1,126 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/firefly-cli/internal/secrets"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+)
+
+func (s *StackManager) secretsEncrypted() bool {
+	return s.Stack.SecretsEncryption != "" && !s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionNone)
+}
+
+// initSecretsEncryption creates the key used to encrypt the secrets of a new stack
+func (s *StackManager) initSecretsEncryption(mode string) error {
+	s.Stack.SecretsEncryption = fftypes.FFEnum(mode)
+	if !s.secretsEncrypted() {
+		s.Stack.SecretsEncryption = ""
+		return nil
+	}
+
+	var key []byte
+	var err error
+	switch {
+	case s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionPassphrase):
+		salt, err := secrets.RandomBytes(16)
+		if err != nil {
+			return err
+		}
+		s.Stack.SecretsSalt = hex.EncodeToString(salt)
+		passphrase, err := secrets.GetPassphrase(s.Stack.Name, true)
+		if err != nil {
+			return err
+		}
+		if key, err = secrets.DeriveKey(passphrase, salt); err != nil {
+			return err
+		}
+	case s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionKeyring):
+		if key, err = secrets.RandomBytes(32); err != nil {
+			return err
+		}
+		if err := secrets.SetKeyringSecret(s.Stack.Name, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid secrets encryption mode '%s'", mode)
+	}
+
+	s.cipher, err = secrets.NewCipher(key)
+	return err
+}
+
+// loadSecretsCipher recreates the key used to encrypt the secrets of an existing stack
+func (s *StackManager) loadSecretsCipher() error {
+	var key []byte
+	switch {
+	case s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionPassphrase):
+		salt, err := hex.DecodeString(s.Stack.SecretsSalt)
+		if err != nil {
+			return err
+		}
+		passphrase, err := secrets.GetPassphrase(s.Stack.Name, false)
+		if err != nil {
+			return err
+		}
+		if key, err = secrets.DeriveKey(passphrase, salt); err != nil {
+			return err
+		}
+	case s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionKeyring):
+		encodedKey, err := secrets.GetKeyringSecret(s.Stack.Name)
+		if err != nil {
+			return err
+		}
+		if key, err = base64.StdEncoding.DecodeString(encodedKey); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("stack '%s' uses an unknown secrets encryption mode '%s'", s.Stack.Name, s.Stack.SecretsEncryption)
+	}
+
+	var err error
+	s.cipher, err = secrets.NewCipher(key)
+	return err
+}
+
+// marshalStackFile serializes stack.json or stackState.json, encrypting secrets if enabled
+func (s *StackManager) marshalStackFile(v interface{}, indent string) ([]byte, error) {
+	if s.cipher == nil {
+		return json.MarshalIndent(v, "", indent)
+	}
+	return s.cipher.MarshalEncrypted(v, indent)
+}
+
+// decryptStackFile decrypts the secrets in the contents of stack.json or stackState.json
+func (s *StackManager) decryptStackFile(b []byte) ([]byte, error) {
+	if s.cipher == nil {
+		return b, nil
+	}
+	return s.cipher.DecryptJSON(b)
+}
+
+func (s *StackManager) removeSecretsKey() {
+	if s.Stack != nil && s.Stack.SecretsEncryption.Equals(types.SecretsEncryptionKeyring) {
+		secrets.DeleteKeyringSecret(s.Stack.Name)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// ServiceRef maps a service in the docker compose file to the logical name
+// of the component it runs, and the member it belongs to
+type ServiceRef struct {
+	ServiceName   string
+	ContainerName string
+	Name          string
+	MemberID      string
+}
+
+// Aliases for logical service names, so users don't need to know exactly
+// which connector or node type a stack was created with
+var serviceAliases = map[string][]string{
+	"firefly_core": {"firefly", "core"},
+	"dataexchange": {"dx"},
+	"ethconnect":   {"connector"},
+	"evmconnect":   {"connector"},
+	"fabconnect":   {"connector"},
+	"ethsigner":    {"signer"},
+	"geth":         {"blockchain"},
+	"besu":         {"blockchain"},
+	"fabric_peer":  {"blockchain"},
+}
+
+var tokensServiceRegex = regexp.MustCompile(`^tokens_(.+)_[0-9]+$`)
+
+// Services returns a reference to every service in the stack's docker compose file
+func (s *StackManager) Services() []*ServiceRef {
+	compose := s.buildDockerCompose()
+	services := make([]*ServiceRef, 0, len(compose.Services))
+	for serviceName, service := range compose.Services {
+		ref := &ServiceRef{
+			ServiceName:   serviceName,
+			ContainerName: service.ContainerName,
+			Name:          serviceName,
+		}
+		if ref.ContainerName == "" {
+			ref.ContainerName = fmt.Sprintf("%s_%s", s.Stack.Name, serviceName)
+		}
+		if m := tokensServiceRegex.FindStringSubmatch(serviceName); m != nil {
+			// Token connectors are named tokens_<member>_<index>
+			ref.Name = "tokens"
+			ref.MemberID = m[1]
+		} else if name, member, ok := s.additionalBlockchainService(serviceName); ok {
+			// The services of additional blockchains are named <name>_<blockchain>[_<member>]
+			ref.Name = name
+			if member != nil {
+				ref.MemberID = member.ID
+			}
+		} else if member := s.serviceMember(serviceName); member != nil {
+			ref.Name = strings.TrimSuffix(serviceName, "_"+member.ID)
+			ref.MemberID = member.ID
+		}
+		services = append(services, ref)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ServiceName < services[j].ServiceName
+	})
+	return services
+}
+
+// additionalBlockchainService returns the logical name of a service of one of the additional blockchains
+// of the stack, and the member it runs for, if any
+func (s *StackManager) additionalBlockchainService(serviceName string) (string, *types.Organization, bool) {
+	for i := 1; i < len(s.blockchainProviders); i++ {
+		view := s.blockchainStacks[i]
+		for _, serviceDefinition := range s.blockchainProviders[i].GetDockerServiceDefinitions() {
+			if serviceDefinition.ServiceName != serviceName {
+				continue
+			}
+			for _, member := range s.Stack.Members {
+				if suffix := fmt.Sprintf("_%d_%s", view.BlockchainIndex, member.ID); strings.HasSuffix(serviceName, suffix) {
+					return strings.TrimSuffix(serviceName, suffix), member, true
+				}
+			}
+			if suffix := fmt.Sprintf("_%d", view.BlockchainIndex); strings.HasSuffix(serviceName, suffix) {
+				return strings.TrimSuffix(serviceName, suffix), nil, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// serviceMember returns the member that a service belongs to, from the suffix of its name
+func (s *StackManager) serviceMember(serviceName string) *types.Organization {
+	if _, member, ok := s.additionalBlockchainService(serviceName); ok {
+		return member
+	}
+	if m := tokensServiceRegex.FindStringSubmatch(serviceName); m != nil {
+		serviceName = "tokens_" + m[1]
+	}
+	for _, member := range s.Stack.Members {
+		if strings.HasSuffix(serviceName, "_"+member.ID) {
+			return member
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the name is the compose service name, the logical
+// name, or an alias of the logical name of this service
+func (r *ServiceRef) Matches(name string) bool {
+	if name == r.ServiceName || name == r.Name {
+		return true
+	}
+	for _, alias := range serviceAliases[r.Name] {
+		if name == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// IsShared returns true for services that are not specific to one member, such as the blockchain node
+func (r *ServiceRef) IsShared() bool {
+	return r.MemberID == ""
+}
+
+func (s *StackManager) getMemberID(nameOrID string) (string, error) {
+	for _, member := range s.Stack.Members {
+		if nameOrID == member.ID || nameOrID == member.OrgName || nameOrID == member.NodeName {
+			return member.ID, nil
+		}
+	}
+	return "", fmt.Errorf("member '%s' does not exist in stack '%s'", nameOrID, s.Stack.Name)
+}
+
+func (s *StackManager) getMember(nameOrID string) (*types.Organization, error) {
+	memberID, err := s.getMemberID(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range s.Stack.Members {
+		if member.ID == memberID {
+			return member, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindServices returns the services matching the given members and logical service names.
+// Shared services are only included when no members are specified, or when they are
+// explicitly requested by name.
+func (s *StackManager) FindServices(members, names []string) ([]*ServiceRef, error) {
+	memberIDs := make(map[string]bool)
+	for _, m := range members {
+		id, err := s.getMemberID(m)
+		if err != nil {
+			return nil, err
+		}
+		memberIDs[id] = true
+	}
+
+	all := s.Services()
+	for _, name := range names {
+		found := false
+		for _, ref := range all {
+			if ref.Matches(name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("service '%s' does not exist in stack '%s'", name, s.Stack.Name)
+		}
+	}
+
+	matches := make([]*ServiceRef, 0)
+	for _, ref := range all {
+		if len(names) > 0 {
+			nameMatch := false
+			for _, name := range names {
+				if ref.Matches(name) {
+					nameMatch = true
+					break
+				}
+			}
+			if !nameMatch {
+				continue
+			}
+		}
+		if len(memberIDs) > 0 && !memberIDs[ref.MemberID] && !(ref.IsShared() && len(names) > 0) {
+			continue
+		}
+		matches = append(matches, ref)
+	}
+	return matches, nil
+}
@@ -0,0 +1,126 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// shareInfrastructurePorts points the database (and IPFS) ports of every member at those of the first
+// member, which are the ports the shared services are exposed on
+func (s *StackManager) shareInfrastructurePorts() {
+	first := s.Stack.Members[0]
+	for _, member := range s.Stack.Members[1:] {
+		member.ExposedDatabasePort = first.ExposedDatabasePort
+		if s.Stack.SharedIPFS() {
+			member.ExposedIPFSApiPort = first.ExposedIPFSApiPort
+			member.ExposedIPFSGWPort = first.ExposedIPFSGWPort
+		}
+	}
+}
+
+// waitForPostgres waits, with the retry policy of the stack, for a postgres service that has just been started
+// to accept connections
+func (s *StackManager) waitForPostgres(containerName string) error {
+	return core.Retry(s.ctx, "waiting for postgres", func() error {
+		_, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "exec", containerName, "pg_isready", "-U", "postgres")
+		return err
+	})
+}
+
+// runPostgresStatement runs statements against a postgres service of the stack, once it is ready. Connecting
+// over the local socket inside the container does not need a password.
+func (s *StackManager) runPostgresStatement(service string, statements ...string) (string, error) {
+	containerName := fmt.Sprintf("%s_%s", s.Stack.Name, service)
+	if err := s.waitForPostgres(containerName); err != nil {
+		return "", err
+	}
+	args := []string{"exec", containerName, "psql", "-U", "postgres", "-tA", "-v", "ON_ERROR_STOP=1"}
+	for _, statement := range statements {
+		args = append(args, "-c", statement)
+	}
+	return docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, args...)
+}
+
+// postgresRowExists runs a query against a postgres service of the stack, and returns whether it returned a row
+func (s *StackManager) postgresRowExists(service, query string) (bool, error) {
+	output, err := s.runPostgresStatement(service, query)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// createMemberDatabases starts the shared postgres and creates the user and database of each member that
+// does not have them yet, before FireFly core starts and runs its migrations. Each step checks whether it
+// was done already, so that a start that failed part way can be run again.
+func (s *StackManager) createMemberDatabases() error {
+	if !s.Stack.SharedInfrastructure || !s.Stack.Database.Equals(types.DatabaseSelectionPostgres) {
+		return nil
+	}
+	service := s.Stack.PostgresServiceName("")
+	if err := s.runDockerComposeCommand("up", "-d", service); err != nil {
+		return err
+	}
+	for _, member := range s.Stack.Members {
+		user := s.Stack.PostgresUser(member.ID)
+		database := s.Stack.PostgresDatabase(member.ID)
+		userExists, err := s.postgresRowExists(service, fmt.Sprintf("SELECT 1 FROM pg_roles WHERE rolname = '%s'", user))
+		if err != nil {
+			return fmt.Errorf("unable to check the database user of member %s: %s", member.ID, err)
+		}
+		databaseExists, err := s.postgresRowExists(service, fmt.Sprintf("SELECT 1 FROM pg_database WHERE datname = '%s'", database))
+		if err != nil {
+			return fmt.Errorf("unable to check the database of member %s: %s", member.ID, err)
+		}
+		if userExists && databaseExists {
+			continue
+		}
+
+		s.Log.Info(fmt.Sprintf("creating database for member %s", member.ID))
+		statements := s.memberDatabaseStatements(member, userExists, databaseExists)
+		if _, err := s.runPostgresStatement(service, statements...); err != nil {
+			return fmt.Errorf("unable to create the database of member %s: %s", member.ID, err)
+		}
+	}
+	return nil
+}
+
+// memberDatabaseStatements returns the statements that create the parts of the user and database of a member that
+// do not exist yet. CREATE DATABASE cannot run in a transaction, so each statement is run separately.
+func (s *StackManager) memberDatabaseStatements(member *types.Organization, userExists, databaseExists bool) []string {
+	user := s.Stack.PostgresUser(member.ID)
+	database := s.Stack.PostgresDatabase(member.ID)
+	statements := []string{}
+	if userExists {
+		statements = append(statements, fmt.Sprintf("ALTER USER %s WITH PASSWORD '%s'", user, s.Stack.DatabasePassword(member.ID)))
+	} else {
+		statements = append(statements, fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", user, s.Stack.DatabasePassword(member.ID)))
+	}
+	if !databaseExists {
+		statements = append(statements,
+			fmt.Sprintf("CREATE DATABASE %s OWNER %s", database, user),
+			fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", database),
+		)
+	}
+	return statements
+}
@@ -23,9 +23,9 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -39,6 +39,7 @@ import (
 	"github.com/hyperledger/firefly-cli/internal/core"
 	"github.com/hyperledger/firefly-cli/internal/docker"
 	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/internal/secrets"
 	"github.com/hyperledger/firefly-cli/internal/tokens"
 	"github.com/hyperledger/firefly-cli/internal/tokens/erc1155"
 	"github.com/hyperledger/firefly-cli/internal/tokens/erc20erc721"
@@ -56,8 +57,14 @@ type StackManager struct {
 	Log                log.Logger
 	Stack              *types.Stack
 	blockchainProvider blockchain.IBlockchainProvider
-	tokenProviders     []tokens.ITokensProvider
-	IsOldFileStructure bool
+	// blockchainProviders has a provider for each blockchain of the stack, starting with blockchainProvider,
+	// and blockchainStacks has the view of the stack each of them works with
+	blockchainProviders []blockchain.IBlockchainProvider
+	blockchainStacks    []*types.Stack
+	tokenProviders      []tokens.ITokensProvider
+	tokenPlugins        []*types.TokenPlugin
+	cipher              *secrets.Cipher
+	IsOldFileStructure  bool
 }
 
 func ListStacks() ([]string, error) {
@@ -87,6 +94,8 @@ func NewStackManager(ctx context.Context) *StackManager {
 }
 
 func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
+	// Gateway mode does not use IPFS or data exchange, so they are left out unless asked for
+	sharedServices := options.MultipartyEnabled || options.GatewaySharedServices
 	s.Stack = &types.Stack{
 		Name:                   options.StackName,
 		Members:                make([]*types.Organization, options.MemberCount),
@@ -103,14 +112,23 @@ func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
 			DeployedContracts: make([]*types.DeployedContract, 0),
 			Accounts:          make([]interface{}, options.MemberCount),
 		},
-		SandboxEnabled:    options.SandboxEnabled,
-		MultipartyEnabled: options.MultipartyEnabled,
-		ChainIDPtr:        &options.ChainID,
-		RemoteNodeURL:     options.RemoteNodeURL,
-		RequestTimeout:    options.RequestTimeout,
-		IPFSMode:          fftypes.FFEnum(options.IPFSMode),
-		ChannelName:       options.ChannelName,
-		ChaincodeName:     options.ChaincodeName,
+		SandboxEnabled:       options.SandboxEnabled,
+		MultipartyEnabled:    options.MultipartyEnabled,
+		SharedServicesPtr:    &sharedServices,
+		SharedInfrastructure: options.SharedInfrastructure,
+		ChainIDPtr:           &options.ChainID,
+		RemoteNodeURL:        options.RemoteNodeURL,
+		RequestTimeout:       options.RequestTimeout,
+		HTTPClient:           &options.HTTPClient,
+		IPFSMode:             fftypes.FFEnum(options.IPFSMode),
+		ChannelName:          options.ChannelName,
+		ChaincodeName:        options.ChaincodeName,
+		TLSEnabled:           options.TLSEnabled,
+		BasicAuthEnabled:     options.BasicAuthEnabled,
+	}
+
+	if err := s.initSecretsEncryption(options.SecretsEncryption); err != nil {
+		return err
 	}
 
 	tokenProviders, err := types.FFEnumArray(s.ctx, options.TokenProviders)
@@ -119,15 +137,31 @@ func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
 	}
 	s.Stack.TokenProviders = tokenProviders
 
-	if s.Stack.IPFSMode.Equals(types.IPFSModePrivate) {
+	if s.Stack.State.Credentials, err = s.generateCredentials(options.PerMemberCredentials); err != nil {
+		return err
+	}
+
+	if s.Stack.SharedServicesEnabled() && s.Stack.IPFSMode.Equals(types.IPFSModePrivate) {
 		s.Stack.SwarmKey = GenerateSwarmKey()
 	}
 
+	if options.GrafanaEnabled {
+		// Grafana shows the metrics that Prometheus collects
+		options.PrometheusEnabled = true
+		s.Stack.GrafanaEnabled = true
+		s.Stack.ExposedGrafanaPort = options.GrafanaPort
+	}
+
 	if options.PrometheusEnabled {
 		s.Stack.PrometheusEnabled = true
 		s.Stack.ExposedPrometheusPort = options.PrometheusPort
 	}
 
+	if options.TracingEnabled {
+		s.Stack.TracingEnabled = true
+		s.Stack.ExposedJaegerPort = options.JaegerPort
+	}
+
 	if len(options.CCPYAMLPaths) != 0 && len(options.MSPPaths) != 0 {
 		s.Stack.RemoteFabricNetwork = true
 	} else {
@@ -158,9 +192,19 @@ func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
 		}
 	}
 
+	// Pin the third party images that the manifest does not specify to the defaults of this CLI version
+	core.SetDefaultImages(manifest, constants.DefaultThirdPartyImages)
+
 	s.Stack.VersionManifest = manifest
+	if err := s.setImages(options.ImageOverrides); err != nil {
+		return err
+	}
+	if err := s.setHTTPClient(); err != nil {
+		return err
+	}
 	s.blockchainProvider = s.getBlockchainProvider()
-	s.tokenProviders = s.getITokenProviders()
+	s.loadBlockchainProviders()
+	s.loadTokenProviders()
 
 	for i := 0; i < options.MemberCount; i++ {
 		externalProcess := i < options.ExternalProcesses
@@ -173,6 +217,13 @@ func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
 			s.Stack.State.Accounts[i] = s.Stack.Members[i].Account
 		}
 	}
+	if s.Stack.SharedInfrastructure {
+		s.shareInfrastructurePorts()
+	}
+
+	if err := s.addBlockchains(options); err != nil {
+		return err
+	}
 
 	if err := s.ensureInitDirectories(); err != nil {
 		return err
@@ -188,6 +239,25 @@ func (s *StackManager) InitStack(options *types.InitOptions) (err error) {
 	return s.writeConfig(options)
 }
 
+// setHTTPClient builds the HTTP client for this stack and attaches it to the context,
+// so that the blockchain and tokens providers created from it use the stack's retry
+// policy and request timeout, and trust the stack CA if TLS is enabled
+func (s *StackManager) setHTTPClient() error {
+	client := core.NewHTTPClient(s.Stack.HTTPClient, s.Stack.RequestTimeout)
+	if s.Stack.TLSEnabled {
+		// The CA does not exist yet while a stack is being initialized
+		if caPEM, err := ioutil.ReadFile(s.caCertPath()); err == nil {
+			if err := client.TrustCA(caPEM); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	s.ctx = core.WithHTTPClient(s.ctx, client)
+	return nil
+}
+
 func (s *StackManager) runDockerComposeCommand(command ...string) error {
 	baseCompose := filepath.Join(s.Stack.StackDir, "docker-compose.yml")
 	runtimeCompose := filepath.Join(s.Stack.RuntimeDir, "docker-compose.yml")
@@ -202,9 +272,9 @@ func (s *StackManager) runDockerComposeCommand(command ...string) error {
 
 func (s *StackManager) buildDockerCompose() *docker.DockerComposeConfig {
 	compose := docker.CreateDockerCompose(s.Stack)
-	extraServices := s.blockchainProvider.GetDockerServiceDefinitions()
+	extraServices := s.blockchainServiceDefinitions()
 	for i, tp := range s.tokenProviders {
-		extraServices = append(extraServices, tp.GetDockerServiceDefinitions(i)...)
+		extraServices = append(extraServices, tp.GetDockerServiceDefinitions(s.tokenPlugins[i].Index)...)
 	}
 
 	for _, serviceDefinition := range extraServices {
@@ -226,6 +296,8 @@ func (s *StackManager) buildDockerCompose() *docker.DockerComposeConfig {
 			}
 		}
 	}
+	s.detachExternalServices(compose)
+	s.attachSharedNetwork(compose)
 	return compose
 }
 
@@ -258,13 +330,26 @@ func (s *StackManager) LoadStack(stackName string) error {
 		return err
 	}
 	s.Stack = stack
+	if s.secretsEncrypted() {
+		if err := s.loadSecretsCipher(); err != nil {
+			return err
+		}
+		if d, err = s.decryptStackFile(d); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(d, &stack); err != nil {
+			return err
+		}
+		s.Stack = stack
+	}
 	s.Stack.StackDir = stackDir
-	s.blockchainProvider = s.getBlockchainProvider()
-	s.tokenProviders = s.getITokenProviders()
-
-	if s.Stack.RequestTimeout > 0 {
-		core.SetRequestTimeout(s.Stack.RequestTimeout)
+	if err := s.ensureStackDir(); err != nil {
+		return err
 	}
+	if err := s.setHTTPClient(); err != nil {
+		return err
+	}
+	s.blockchainProvider = s.getBlockchainProvider()
 
 	isOldFileStructure, err := s.Stack.IsOldFileStructure()
 	if err != nil {
@@ -285,6 +370,7 @@ func (s *StackManager) LoadStack(stackName string) error {
 			member.Account = s.blockchainProvider.ParseAccount(member.Account)
 		}
 	}
+	s.parseBlockchainAccounts()
 
 	// For backwards compatibility, add a "default" VersionManifest
 	// in memory for stacks that were created with old CLI versions
@@ -325,15 +411,28 @@ func (s *StackManager) LoadStack(stackName string) error {
 		}
 	}
 
+	// Likewise, third party images that are not in the manifest are the ones that were previously hardcoded,
+	// or the current defaults for components that were added since
+	core.SetDefaultImages(s.Stack.VersionManifest, constants.LegacyThirdPartyImages)
+	core.SetDefaultImages(s.Stack.VersionManifest, constants.DefaultThirdPartyImages)
+
 	stackHasRunBefore, err := s.Stack.HasRunBefore()
 	if err != nil {
 		return nil
 	}
 	if stackHasRunBefore {
-		return s.loadStackStateJSON()
+		if err := s.loadStackStateJSON(); err != nil {
+			return err
+		}
 	} else {
 		s.Stack.State = &types.StackState{}
+		if err := s.loadInitCredentials(); err != nil {
+			return err
+		}
 	}
+	s.loadBlockchainProviders()
+	s.loadTokenProviders()
+	s.setHTTPBasicAuth()
 	return nil
 }
 
@@ -352,6 +451,9 @@ func (s *StackManager) loadStackStateJSON() error {
 	if err != nil {
 		return err
 	}
+	if b, err = s.decryptStackFile(b); err != nil {
+		return err
+	}
 	var stackState *types.StackState
 	if err := json.Unmarshal(b, &stackState); err != nil {
 		return err
@@ -368,20 +470,38 @@ func (s *StackManager) loadStackStateJSON() error {
 }
 
 func (s *StackManager) writeStackStateJSON(directory string) error {
-	stackStateBytes, err := json.MarshalIndent(s.Stack.State, "", "  ")
+	stackStateBytes, err := s.marshalStackFile(s.Stack.State, "  ")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filepath.Join(directory, "stackState.json"), stackStateBytes, 0755)
+	return ioutil.WriteFile(filepath.Join(directory, "stackState.json"), stackStateBytes, 0600)
+}
+
+// ensureStackDir creates the directory of the stack, which only the user can access, as the configs, keystores
+// and docker compose file of the stack hold its credentials. The files in it are left readable by other users,
+// where they need to be for the containers they are mounted into.
+func (s *StackManager) ensureStackDir() error {
+	if err := os.MkdirAll(s.Stack.StackDir, 0700); err != nil {
+		return err
+	}
+	// Stacks created by older versions of the CLI could be read by every user
+	return os.Chmod(s.Stack.StackDir, 0700)
 }
 
 func (s *StackManager) ensureInitDirectories() error {
 	configDir := filepath.Join(s.Stack.InitDir, "config")
 
+	if err := s.ensureStackDir(); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(filepath.Join(configDir), 0755); err != nil {
 		return err
 	}
 
+	if !s.Stack.SharedServicesEnabled() {
+		return nil
+	}
 	for _, member := range s.Stack.Members {
 		if err := os.MkdirAll(filepath.Join(configDir, "dataexchange_"+member.ID, "peer-certs"), 0755); err != nil {
 			return err
@@ -399,7 +519,7 @@ func (s *StackManager) writeDockerCompose(compose *docker.DockerComposeConfig) e
 		return err
 	}
 	bytes = append(bytes, yamlBytes...)
-	return ioutil.WriteFile(filepath.Join(s.Stack.StackDir, "docker-compose.yml"), bytes, 0755)
+	return ioutil.WriteFile(filepath.Join(s.Stack.StackDir, "docker-compose.yml"), bytes, 0600)
 }
 
 func (s *StackManager) writeDockerComposeOverride(compose *docker.DockerComposeConfig) error {
@@ -414,39 +534,57 @@ func (s *StackManager) writeDockerComposeOverride(compose *docker.DockerComposeC
 }
 
 func (s *StackManager) writeStackConfig() error {
-	stackConfigBytes, err := json.MarshalIndent(s.Stack, "", " ")
-	if err != nil {
+	if err := s.writeStackJSON(); err != nil {
 		return err
 	}
-	if err := ioutil.WriteFile(filepath.Join(s.Stack.StackDir, "stack.json"), stackConfigBytes, 0755); err != nil {
+	return s.writeStackStateJSON(s.Stack.InitDir)
+}
+
+func (s *StackManager) writeStackJSON() error {
+	stackConfigBytes, err := s.marshalStackFile(s.Stack, " ")
+	if err != nil {
 		return err
 	}
-	return s.writeStackStateJSON(s.Stack.InitDir)
+	return ioutil.WriteFile(filepath.Join(s.Stack.StackDir, "stack.json"), stackConfigBytes, 0600)
 }
 
 func (s *StackManager) writeConfig(options *types.InitOptions) error {
-	if err := s.writeDataExchangeCerts(); err != nil {
-		return err
+	if s.Stack.SharedServicesEnabled() {
+		if err := s.writeDataExchangeCerts(); err != nil {
+			return err
+		}
+	}
+
+	if s.Stack.TLSEnabled {
+		if err := s.writeTLSCerts(); err != nil {
+			return err
+		}
+	}
+
+	if s.Stack.BasicAuthEnabled {
+		if err := s.writeBasicAuthPasswordFiles(); err != nil {
+			return err
+		}
 	}
 
 	for _, member := range s.Stack.Members {
 		config := core.NewFireflyConfig(s.Stack, member)
 
-		// TODO: This code assumes that there is only one plugin instance per type. When we add support for
-		// multiple namespaces, this code will likely have to change a lot
-		blockchainConfig := s.blockchainProvider.GetBlockchainPluginConfig(s.Stack, member)
-		blockchainConfig.Name = "blockchain0"
-		config.Plugins.Blockchain = []*types.BlockchainConfig{
-			blockchainConfig,
+		config.Plugins.Blockchain = []*types.BlockchainConfig{}
+		for i, provider := range s.blockchainProviders {
+			stack := s.blockchainStacks[i]
+			blockchainConfig := provider.GetBlockchainPluginConfig(stack, stack.Members[*member.Index])
+			blockchainConfig.Name = types.BlockchainPluginName(i)
+			config.Plugins.Blockchain = append(config.Plugins.Blockchain, blockchainConfig)
 		}
 
 		if config.Plugins.Tokens == nil {
 			config.Plugins.Tokens = []*types.TokensConfig{}
 		}
 
-		for iTok, tp := range s.tokenProviders {
-			tokenConfig := tp.GetFireflyConfig(member, iTok)
-			tokenConfig.Name = tp.GetName()
+		for i, tp := range s.tokenProviders {
+			tokenConfig := tp.GetFireflyConfig(member, s.tokenPlugins[i].Index)
+			tokenConfig.Name = s.tokenPlugins[i].Name
 			config.Plugins.Tokens = append(config.Plugins.Tokens, tokenConfig)
 		}
 
@@ -460,7 +598,21 @@ func (s *StackManager) writeConfig(options *types.InitOptions) error {
 		return err
 	}
 
-	if err := s.blockchainProvider.WriteConfig(options); err != nil {
+	for _, provider := range s.blockchainProviders {
+		if err := provider.WriteConfig(options); err != nil {
+			return err
+		}
+	}
+
+	for memberID, configPath := range options.MemberCoreConfigPaths {
+		if err := s.setMemberCoreConfig(memberID, configPath); err != nil {
+			return err
+		}
+	}
+	if err := s.applyConfigOverrides(filepath.Join(s.Stack.InitDir, "config")); err != nil {
+		return err
+	}
+	if err := s.validateConfigFiles(filepath.Join(s.Stack.InitDir, "config")); err != nil {
 		return err
 	}
 
@@ -475,23 +627,36 @@ func (s *StackManager) writeConfig(options *types.InitOptions) error {
 		}
 	}
 
+	if s.Stack.GrafanaEnabled {
+		if err := s.writeGrafanaConfig(filepath.Join(s.Stack.InitDir, "config")); err != nil {
+			return err
+		}
+	}
+
+	if s.Stack.TracingEnabled {
+		if err := s.writeOTelCollectorConfig(filepath.Join(s.Stack.InitDir, "config")); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (s *StackManager) writeDataExchangeCerts() error {
 	configDir := filepath.Join(s.Stack.InitDir, "config")
+	ca, err := s.loadCA()
+	if err != nil {
+		return err
+	}
 	for _, member := range s.Stack.Members {
 
 		memberDXDir := path.Join(configDir, "dataexchange_"+member.ID)
 
-		// TODO: remove dependency on openssl here
-		opensslCmd := exec.Command("openssl", "req", "-new", "-x509", "-nodes", "-days", "365", "-subj", fmt.Sprintf("/CN=dataexchange_%s/O=member_%s", member.ID, member.ID), "-keyout", "key.pem", "-out", "cert.pem")
-		opensslCmd.Dir = filepath.Join(configDir, "dataexchange_"+member.ID)
-		if err := opensslCmd.Run(); err != nil {
+		if err := s.issueDataExchangeCert(ca, configDir, member.ID); err != nil {
 			return err
 		}
 
-		dataExchangeConfig := s.GenerateDataExchangeHTTPSConfig(member.ID)
+		dataExchangeConfig := s.GenerateDataExchangeHTTPSConfig(member)
 		configBytes, err := json.Marshal(dataExchangeConfig)
 		if err != nil {
 			return err
@@ -504,22 +669,44 @@ func (s *StackManager) writeDataExchangeCerts() error {
 }
 
 func (s *StackManager) copyDataExchangeConfigToVolumes() error {
-	configDir := filepath.Join(s.Stack.RuntimeDir, "config")
 	for _, member := range s.Stack.Members {
-		// Copy files into docker volumes
-		memberDXDir := path.Join(configDir, "dataexchange_"+member.ID)
-		volumeName := fmt.Sprintf("%s_dataexchange_%s", s.Stack.Name, member.ID)
-		docker.MkdirInVolume(s.ctx, volumeName, "peer-certs")
-		if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "config.json"), "/config.json"); err != nil {
-			return err
-		}
-		if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "cert.pem"), "/cert.pem"); err != nil {
+		if err := s.copyDataExchangeConfigToVolume(member); err != nil {
 			return err
 		}
-		if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "key.pem"), "/key.pem"); err != nil {
+	}
+	return nil
+}
+
+func (s *StackManager) copyDataExchangeConfigToVolume(member *types.Organization) error {
+	if member.IsExternal(types.ComponentDataExchange) {
+		// An external data exchange reads its config where the CLI wrote it
+		return nil
+	}
+	// Copy files into docker volumes
+	memberDXDir := path.Join(s.Stack.RuntimeDir, "config", "dataexchange_"+member.ID)
+	volumeName := fmt.Sprintf("%s_dataexchange_%s", s.Stack.Name, member.ID)
+	docker.MkdirInVolume(s.ctx, volumeName, "peer-certs")
+	if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "config.json"), "/config.json"); err != nil {
+		return err
+	}
+	if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "cert.pem"), "/cert.pem"); err != nil {
+		return err
+	}
+	if err := docker.CopyFileToVolume(s.ctx, volumeName, path.Join(memberDXDir, "key.pem"), "/key.pem"); err != nil {
+		return err
+	}
+	// Certificates of peers in other stacks that share a network with this one
+	peerCertsDir := path.Join(memberDXDir, "peer-certs")
+	if _, err := os.Stat(peerCertsDir); err == nil {
+		if err := docker.CopyFileToVolume(s.ctx, volumeName, peerCertsDir, "/"); err != nil {
 			return err
 		}
 	}
+	// Stacks created before certificates were issued from a stack CA do not have a ca.pem
+	caPath := path.Join(memberDXDir, "ca.pem")
+	if _, err := os.Stat(caPath); err == nil {
+		return docker.CopyFileToVolume(s.ctx, volumeName, caPath, "/ca.pem")
+	}
 	return nil
 }
 
@@ -567,6 +754,18 @@ func (s *StackManager) createMember(id string, index int, options *types.InitOpt
 		member.ExposedSandboxPort = nextPort
 		nextPort++
 	}
+
+	for _, component := range options.ExternalComponents[id] {
+		if component == types.ComponentCore {
+			member.External = true
+		} else {
+			member.ExternalComponents = append(member.ExternalComponents, component)
+		}
+	}
+	// Peers reach an external data exchange on the host, so it needs a port of its own for P2P
+	if member.IsExternal(types.ComponentDataExchange) {
+		member.ExposedDataexchangeP2PPort = nextPort
+	}
 	return member, nil
 }
 
@@ -630,33 +829,32 @@ func (s *StackManager) PullStack(options *types.PullOptions) error {
 		}
 	}
 
-	images = append(images, constants.IPFSImageName)
-
-	// Also pull postgres if we're using it
-	if s.Stack.Database.Equals(types.DatabaseSelectionPostgres) {
-		images = append(images, constants.PostgresImageName)
-	}
-
-	// Also pull the Sandbox if we're using it
-	if s.Stack.SandboxEnabled {
-		images = append(images, constants.SandboxImageName)
+	// Third party images that were overridden with a local build are not pulled either
+	for _, entry := range s.Stack.VersionManifest.ThirdPartyEntries() {
+		if *entry != nil && (*entry).Local {
+			manifestImages[(*entry).GetDockerImageString()] = true
+		}
 	}
 
-	// Iterate over all images used by the blockchain provider
-	for _, service := range s.blockchainProvider.GetDockerServiceDefinitions() {
-		if !manifestImages[service.Service.Image] {
-			images = append(images, service.Service.Image)
+	// Iterate over all other images used by the stack, which are pinned in the manifest too
+	var otherImages []string
+	for _, service := range s.buildDockerCompose().Services {
+		if !manifestImages[service.Image] {
+			manifestImages[service.Image] = true
+			otherImages = append(otherImages, service.Image)
 		}
 	}
-
-	// Iterate over all images used by the tokens provider
-	for iTok, tp := range s.tokenProviders {
-		for _, service := range tp.GetDockerServiceDefinitions(iTok) {
-			if !manifestImages[service.Service.Image] {
-				images = append(images, service.Service.Image)
+	// The fabric tools are run outside of docker compose to set up the network
+	for i := range s.blockchainProviders {
+		if s.Stack.BlockchainStack(i).BlockchainProvider.Equals(types.BlockchainProviderFabric) {
+			if toolsImage := s.Stack.VersionManifest.FabricTools.GetDockerImageString(); !manifestImages[toolsImage] {
+				manifestImages[toolsImage] = true
+				otherImages = append(otherImages, toolsImage)
 			}
 		}
 	}
+	sort.Strings(otherImages)
+	images = append(images, otherImages...)
 
 	// Use docker to pull every image - retry on failure
 	for _, image := range images {
@@ -670,11 +868,11 @@ func (s *StackManager) PullStack(options *types.PullOptions) error {
 
 func (s *StackManager) removeVolumes() {
 	var volumes []string
-	for _, service := range s.blockchainProvider.GetDockerServiceDefinitions() {
+	for _, service := range s.blockchainServiceDefinitions() {
 		volumes = append(volumes, service.VolumeNames...)
 	}
-	for iTok, tp := range s.tokenProviders {
-		for _, service := range tp.GetDockerServiceDefinitions(iTok) {
+	for i, tp := range s.tokenProviders {
+		for _, service := range tp.GetDockerServiceDefinitions(s.tokenPlugins[i].Index) {
 			volumes = append(volumes, service.VolumeNames...)
 		}
 	}
@@ -687,8 +885,16 @@ func (s *StackManager) removeVolumes() {
 }
 
 func (s *StackManager) runStartupSequence(firstTimeSetup bool) error {
-	if err := s.blockchainProvider.PreStart(); err != nil {
-		return err
+	for _, provider := range s.blockchainProviders {
+		if err := provider.PreStart(); err != nil {
+			return err
+		}
+	}
+
+	if firstTimeSetup {
+		if err := s.createMemberDatabases(); err != nil {
+			return err
+		}
 	}
 
 	s.Log.Info("starting FireFly dependencies")
@@ -696,10 +902,20 @@ func (s *StackManager) runStartupSequence(firstTimeSetup bool) error {
 		return err
 	}
 
-	if err := s.blockchainProvider.PostStart(firstTimeSetup); err != nil {
+	// Token connectors need their contracts, which are only deployed after the first start
+	if err := s.ensureExternalProcessesUp(!firstTimeSetup); err != nil {
 		return err
 	}
 
+	for _, provider := range s.blockchainProviders {
+		if err := provider.PostStart(firstTimeSetup); err != nil {
+			return err
+		}
+	}
+
+	if s.Stack.JoinedNetwork() {
+		return s.connectToHostStack()
+	}
 	return nil
 }
 
@@ -714,10 +930,21 @@ func (s *StackManager) ResetStack() error {
 	if err := os.RemoveAll(s.Stack.RuntimeDir); err != nil {
 		return err
 	}
-	if err := s.blockchainProvider.Reset(); err != nil {
-		return err
+	for _, provider := range s.blockchainProviders {
+		if err := provider.Reset(); err != nil {
+			return err
+		}
 	}
 	s.removeVolumes()
+	if len(s.Stack.Namespaces) > 0 {
+		// Added namespaces only exist in the runtime config, so they are removed with it
+		s.Stack.Namespaces = nil
+		s.loadTokenProviders()
+		if err := s.writeStackJSON(); err != nil {
+			return err
+		}
+		return s.writeDockerCompose(s.buildDockerCompose())
+	}
 	return nil
 }
 
@@ -726,35 +953,60 @@ func (s *StackManager) RemoveStack() error {
 		return err
 	}
 	s.removeVolumes()
+	s.removeSecretsKey()
 	return os.RemoveAll(s.Stack.StackDir)
 }
 
 func (s *StackManager) checkPortsAvailable() error {
 	ports := make([]int, 1)
 	ports[0] = s.Stack.ExposedBlockchainPort
+	// The ports of components that run outside of docker are in use when their processes are already running
 	for _, member := range s.Stack.Members {
 
-		ports = append(ports, member.ExposedConnectorPort)
 		ports = append(ports, member.ExposedDatabasePort)
 		ports = append(ports, member.ExposedUIPort)
-		ports = append(ports, member.ExposedTokensPorts...)
 
+		if !member.IsExternal(types.ComponentConnector) {
+			ports = append(ports, member.ExposedConnectorPort)
+		}
+		if !member.IsExternal(types.ComponentTokens) {
+			ports = append(ports, member.ExposedTokensPorts...)
+		}
 		if !member.External {
 			ports = append(ports, member.ExposedFireflyAdminSPIPort)
 			ports = append(ports, member.ExposedFireflyPort)
 			ports = append(ports, member.ExposedFireflyMetricsPort)
 		}
-		ports = append(ports, member.ExposedDataexchangePort)
-		ports = append(ports, member.ExposedIPFSApiPort)
-		ports = append(ports, member.ExposedIPFSGWPort)
+		if s.Stack.SharedServicesEnabled() {
+			if !member.IsExternal(types.ComponentDataExchange) {
+				ports = append(ports, member.ExposedDataexchangePort)
+			}
+			ports = append(ports, member.ExposedIPFSApiPort)
+			ports = append(ports, member.ExposedIPFSGWPort)
+		}
 		if s.Stack.SandboxEnabled {
 			ports = append(ports, member.ExposedSandboxPort)
 		}
 	}
 
+	for _, b := range s.Stack.Blockchains {
+		ports = append(ports, b.ExposedBlockchainPort)
+		for i, member := range b.Members {
+			if !s.Stack.Members[i].IsExternal(types.ComponentConnector) {
+				ports = append(ports, member.ExposedConnectorPort)
+			}
+		}
+	}
+
 	if s.Stack.PrometheusEnabled {
 		ports = append(ports, s.Stack.ExposedPrometheusPort)
 	}
+	if s.Stack.GrafanaEnabled {
+		ports = append(ports, s.Stack.ExposedGrafanaPort)
+	}
+	if s.Stack.TracingEnabled {
+		ports = append(ports, s.Stack.ExposedJaegerPort)
+	}
 
 	for _, port := range ports {
 		available, err := checkPortAvailable(port)
@@ -768,6 +1020,41 @@ func (s *StackManager) checkPortsAvailable() error {
 	return nil
 }
 
+// highestExposedPort returns the highest port that the stack exposes, above which new ports are allocated
+func (s *StackManager) highestExposedPort() int {
+	ports := []int{s.Stack.ExposedBlockchainPort, s.Stack.ExposedPrometheusPort, s.Stack.ExposedGrafanaPort, s.Stack.ExposedJaegerPort}
+	for _, member := range s.Stack.Members {
+		ports = append(ports,
+			member.ExposedFireflyPort,
+			member.ExposedFireflyAdminSPIPort,
+			member.ExposedFireflyMetricsPort,
+			member.ExposedConnectorPort,
+			member.ExposedConnectorMetricsPort,
+			member.ExposedDatabasePort,
+			member.ExposedDataexchangePort,
+			member.ExposedDataexchangeP2PPort,
+			member.ExposedIPFSApiPort,
+			member.ExposedIPFSGWPort,
+			member.ExposedUIPort,
+			member.ExposedSandboxPort,
+		)
+		ports = append(ports, member.ExposedTokensPorts...)
+	}
+	for _, b := range s.Stack.Blockchains {
+		ports = append(ports, b.ExposedBlockchainPort)
+		for _, member := range b.Members {
+			ports = append(ports, member.ExposedConnectorPort, member.ExposedConnectorMetricsPort)
+		}
+	}
+	highest := 0
+	for _, port := range ports {
+		if port > highest {
+			highest = port
+		}
+	}
+	return highest
+}
+
 func checkPortAvailable(port int) (bool, error) {
 	timeout := time.Millisecond * 500
 	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", fmt.Sprint(port)), timeout)
@@ -816,6 +1103,9 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 	if err := copy.Copy(s.Stack.InitDir, s.Stack.RuntimeDir); err != nil {
 		return messages, err
 	}
+	if err := s.applyConfigOverrides(configDir); err != nil {
+		return messages, err
+	}
 
 	// Re-write the docker-compose config to temporarily short-circuit the core runtimes
 	if err := s.disableFireflyCoreContainers(); err != nil {
@@ -823,8 +1113,10 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 	}
 
 	s.Log.Info("initializing blockchain node")
-	if err := s.blockchainProvider.FirstTimeSetup(); err != nil {
-		return messages, err
+	for _, provider := range s.blockchainProviders {
+		if err := provider.FirstTimeSetup(); err != nil {
+			return messages, err
+		}
 	}
 
 	if s.Stack.PrometheusEnabled {
@@ -835,8 +1127,10 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 		}
 	}
 
-	if err := s.copyDataExchangeConfigToVolumes(); err != nil {
-		return messages, err
+	if s.Stack.SharedServicesEnabled() {
+		if err := s.copyDataExchangeConfigToVolumes(); err != nil {
+			return messages, err
+		}
 	}
 
 	pullOptions := &types.PullOptions{
@@ -852,7 +1146,7 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 
 	for i, tp := range s.tokenProviders {
 		if !s.Stack.DisableTokenFactories {
-			result, err := tp.DeploySmartContracts(i)
+			result, err := tp.DeploySmartContracts(s.tokenPlugins[i].Index)
 			if err != nil {
 				return messages, err
 			}
@@ -872,19 +1166,20 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 				{
 					Name:        "default",
 					Description: "Default predefined namespace",
-					Plugins:     []string{"database0", "blockchain0", "dataexchange0", "sharedstorage0"},
+					Plugins:     []string{"database0", "blockchain0"},
 				},
 			},
 		},
 	}
+	if s.Stack.SharedServicesEnabled() {
+		newConfig.Namespaces.Predefined[0].Plugins = append(newConfig.Namespaces.Predefined[0].Plugins, "dataexchange0", "sharedstorage0")
+	}
 
 	newConfig.Namespaces.Predefined[0].Plugins = append(newConfig.Namespaces.Predefined[0].Plugins, types.FFEnumArrayToStrings(s.Stack.TokenProviders)...)
 
 	var contractDeploymentResult *types.ContractDeploymentResult
 	if s.Stack.MultipartyEnabled {
 		if s.Stack.ContractAddress == "" {
-			// TODO: This code assumes that there is only one plugin instance per type. When we add support for
-			// multiple namespaces, this code will likely have to change a lot
 			s.Log.Info("deploying FireFly smart contracts")
 			contractDeploymentResult, err = s.blockchainProvider.DeployFireFlyContract()
 			if err != nil {
@@ -923,6 +1218,10 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 		}
 		s.patchFireFlyCoreConfigs(configDir, member, newConfig)
 	}
+	// Overrides made with ff config take precedence over the settings patched in above
+	if err := s.applyConfigOverrides(configDir, ConfigComponentCore); err != nil {
+		return messages, err
+	}
 
 	// Re-write the docker-compose config again, in case new values have been added
 	compose := s.buildDockerCompose()
@@ -944,9 +1243,10 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 	}
 
 	if s.Stack.MultipartyEnabled {
-		if s.Stack.ContractAddress == "" {
+		// Stacks that joined another stack's network use its contract, but still register their own identities
+		if s.Stack.ContractAddress == "" || s.Stack.JoinedNetwork() {
 			s.Log.Info("registering FireFly identities")
-			if err := s.registerFireflyIdentities(); err != nil {
+			if err := s.registerFireflyIdentities("default"); err != nil {
 				return messages, err
 			}
 		} else {
@@ -956,8 +1256,8 @@ func (s *StackManager) runFirstTimeSetup(options *types.StartOptions) (messages
 	}
 
 	s.Log.Info("initializing token providers")
-	for iTok, tp := range s.tokenProviders {
-		if err := tp.FirstTimeSetup(iTok); err != nil {
+	for i, tp := range s.tokenProviders {
+		if err := tp.FirstTimeSetup(s.tokenPlugins[i].Index); err != nil {
 			return messages, err
 		}
 	}
@@ -983,7 +1283,7 @@ func (s *StackManager) ensureFireflyNodesUp(firstTimeSetup bool) error {
 			}
 			if available {
 				s.Log.Info(fmt.Sprintf("please start your firefly core with the config file for this stack: firefly -f %s  ", configFilename))
-				if err := s.waitForFireflyStart(port); err != nil {
+				if err := s.waitForExternalProcess("firefly", port); err != nil {
 					return err
 				}
 			}
@@ -992,7 +1292,7 @@ func (s *StackManager) ensureFireflyNodesUp(firstTimeSetup bool) error {
 	return nil
 }
 
-func (s *StackManager) waitForFireflyStart(port int) error {
+func (s *StackManager) waitForExternalProcess(name string, port int) error {
 	retries := 120
 	retryPeriod := 1000 // ms
 	retriesRemaining := retries
@@ -1007,7 +1307,7 @@ func (s *StackManager) waitForFireflyStart(port int) error {
 		}
 		retriesRemaining--
 	}
-	return fmt.Errorf("waited for %v seconds for firefly to start on port %v but it was never available", retries*retryPeriod/1000, port)
+	return fmt.Errorf("waited for %v seconds for %s to start on port %v but it was never available", retries*retryPeriod/1000, name, port)
 }
 
 func (s *StackManager) UpgradeStack() error {
@@ -1026,6 +1326,16 @@ func (s *StackManager) PrintStackInfo() error {
 	if err := s.runDockerComposeCommand("ps"); err != nil {
 		return err
 	}
+	if s.Stack.PrometheusEnabled {
+		fmt.Printf("\nWeb UI for shared Prometheus: http://127.0.0.1:%v\n", s.Stack.ExposedPrometheusPort)
+	}
+	if s.Stack.GrafanaEnabled {
+		fmt.Printf("Grafana dashboards: http://127.0.0.1:%v\n", s.Stack.ExposedGrafanaPort)
+	}
+	if s.Stack.TracingEnabled {
+		fmt.Printf("Jaeger UI for traces: http://127.0.0.1:%v\n", s.Stack.ExposedJaegerPort)
+		s.PrintTracingSummary()
+	}
 	fmt.Printf("\nYour docker compose file for this stack can be found at: %s\n\n", filepath.Join(s.Stack.StackDir, "docker-compose.yml"))
 	return nil
 }
@@ -1138,36 +1448,55 @@ func (s *StackManager) getBlockchainProvider() blockchain.IBlockchainProvider {
 		}
 	}
 
-	s.Stack.DisableTokenFactories = false
+	return newBlockchainProvider(s.ctx, s.Stack)
+}
 
-	switch s.Stack.BlockchainProvider {
+// newBlockchainProvider creates the provider for the blockchain of a stack, or of a view of the stack for one of its additional blockchains
+func newBlockchainProvider(ctx context.Context, stack *types.Stack) blockchain.IBlockchainProvider {
+	stack.DisableTokenFactories = false
+
+	switch stack.BlockchainProvider {
 	case types.BlockchainProviderEthereum:
-		switch s.Stack.BlockchainNodeProvider {
+		switch stack.BlockchainNodeProvider {
 		case types.BlockchainNodeProviderGeth:
-			return geth.NewGethProvider(s.ctx, s.Stack)
+			return geth.NewGethProvider(ctx, stack)
 		case types.BlockchainNodeProviderBesu:
-			return besu.NewBesuProvider(s.ctx, s.Stack)
+			return besu.NewBesuProvider(ctx, stack)
 		case types.BlockchainNodeProviderRemoteRPC:
-			s.Stack.DisableTokenFactories = true
-			return remoterpc.NewRemoteRPCProvider(s.ctx, s.Stack)
+			stack.DisableTokenFactories = true
+			return remoterpc.NewRemoteRPCProvider(ctx, stack)
 		default:
 			return nil
 		}
 	case types.BlockchainProviderFabric:
-		s.Stack.DisableTokenFactories = true
-		return fabric.NewFabricProvider(s.ctx, s.Stack)
+		stack.DisableTokenFactories = true
+		return fabric.NewFabricProvider(ctx, stack)
 	}
 	return nil
 }
 
-func (s *StackManager) getITokenProviders() []tokens.ITokensProvider {
-	tps := make([]tokens.ITokensProvider, len(s.Stack.TokenProviders))
-	for i, tp := range s.Stack.TokenProviders {
-		switch tp {
+// loadTokenProviders creates a tokens provider for each token connector of the stack, and keeps
+// the plugins they were created for alongside them
+func (s *StackManager) loadTokenProviders() {
+	s.tokenPlugins = s.Stack.TokenPlugins()
+	s.tokenProviders = s.getITokenProviders(s.tokenPlugins)
+}
+
+func (s *StackManager) getITokenProviders(plugins []*types.TokenPlugin) []tokens.ITokensProvider {
+	tps := make([]tokens.ITokensProvider, len(plugins))
+	for i, plugin := range plugins {
+		stack := s.tokensStack(plugin.Blockchain)
+		var blockchainProvider blockchain.IBlockchainProvider
+		if plugin.Blockchain == 0 {
+			blockchainProvider = s.getBlockchainProvider()
+		} else {
+			blockchainProvider = newBlockchainProvider(s.ctx, stack)
+		}
+		switch plugin.Provider {
 		case types.TokenProviderERC1155:
-			tps[i] = erc1155.NewERC1155Provider(s.ctx, s.Stack, s.getBlockchainProvider())
+			tps[i] = erc1155.NewERC1155Provider(s.ctx, stack, blockchainProvider)
 		case types.TokenProviderERC20_ERC721:
-			tps[i] = erc20erc721.NewERC20ERC721Provider(s.ctx, s.Stack, s.getBlockchainProvider())
+			tps[i] = erc20erc721.NewERC20ERC721Provider(s.ctx, stack, blockchainProvider)
 		default:
 			return nil
 		}
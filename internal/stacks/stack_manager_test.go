@@ -0,0 +1,91 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hyperledger/firefly-cli/internal/blockchain/ethereum"
+	"github.com/hyperledger/firefly-cli/internal/log"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+)
+
+// The addresses of the accounts of the members of test stacks
+var testAccountAddresses = []string{"0x1234", "0x5678", "0x9abc"}
+
+// testStackOption changes the stack of a test before its blockchain and tokens providers are loaded
+type testStackOption func(stack *types.Stack)
+
+// withStackDir puts the directories of a test stack under the given directory
+func withStackDir(stackDir string) testStackOption {
+	return func(stack *types.Stack) {
+		stack.StackDir = stackDir
+		stack.InitDir = filepath.Join(stackDir, "init")
+		stack.RuntimeDir = filepath.Join(stackDir, "runtime")
+	}
+}
+
+// newTestStackManager returns the manager of a stack named "dev", with the given number of members, that
+// runs geth and evmconnect on sqlite. Every image it could need is in its manifest, named after its component.
+func newTestStackManager(memberCount int, options ...testStackOption) *StackManager {
+	s := &StackManager{
+		ctx: log.WithVerbosity(context.Background(), false),
+		Log: &log.StdoutLogger{},
+		Stack: &types.Stack{
+			Name:                   "dev",
+			Database:               types.DatabaseSelectionSQLite,
+			BlockchainProvider:     types.BlockchainProviderEthereum,
+			BlockchainNodeProvider: types.BlockchainNodeProviderGeth,
+			BlockchainConnector:    types.BlockchainConnectorEvmconnect,
+			Members:                make([]*types.Organization, memberCount),
+			State:                  &types.StackState{},
+			VersionManifest: &types.VersionManifest{
+				FireFly:       &types.ManifestEntry{Image: "firefly"},
+				DataExchange:  &types.ManifestEntry{Image: "dataexchange"},
+				IPFS:          &types.ManifestEntry{Image: "ipfs"},
+				Evmconnect:    &types.ManifestEntry{Image: "evmconnect"},
+				Signer:        &types.ManifestEntry{Image: "signer"},
+				Geth:          &types.ManifestEntry{Image: "geth"},
+				Besu:          &types.ManifestEntry{Image: "besu"},
+				TokensERC1155: &types.ManifestEntry{Image: "tokens"},
+				TLSProxy:      &types.ManifestEntry{Image: "ghostunnel"},
+				Prometheus:    &types.ManifestEntry{Image: "prometheus"},
+				OTelCollector: &types.ManifestEntry{Image: "otel-collector"},
+				Jaeger:        &types.ManifestEntry{Image: "jaeger"},
+			},
+		},
+	}
+	withStackDir("/stacks/dev")(s.Stack)
+	for i := range s.Stack.Members {
+		index := i
+		s.Stack.Members[i] = &types.Organization{
+			ID:      fmt.Sprint(i),
+			Index:   &index,
+			OrgName: fmt.Sprintf("org_%d", i),
+			Account: &ethereum.Account{Address: testAccountAddresses[i]},
+		}
+	}
+	for _, option := range options {
+		option(s.Stack)
+	}
+	s.blockchainProvider = newBlockchainProvider(s.ctx, s.Stack)
+	s.loadBlockchainProviders()
+	s.loadTokenProviders()
+	return s
+}
@@ -0,0 +1,260 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/firefly-cli/internal/core"
+	"github.com/hyperledger/firefly-cli/internal/docker"
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+type SupportBundleManifest struct {
+	Stack      string                    `json:"stack"`
+	CreatedAt  time.Time                 `json:"createdAt"`
+	CLIVersion string                    `json:"cliVersion"`
+	Files      []*SupportBundleFileEntry `json:"files"`
+}
+
+type SupportBundleFileEntry struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Error       string `json:"error,omitempty"`
+}
+
+type supportBundleWriter struct {
+	zip      *zip.Writer
+	manifest *SupportBundleManifest
+}
+
+// Config files that contain private keys or passwords are never included in a bundle
+var supportBundleExcludedFiles = map[string]bool{
+	"key.pem":  true,
+	"password": true,
+	"nodeKey":  true,
+}
+
+var supportBundleConfigExtensions = map[string]bool{
+	".yml":  true,
+	".yaml": true,
+	".json": true,
+	".toml": true,
+	".pem":  true,
+}
+
+func (w *supportBundleWriter) add(name, description string, data []byte, err error) {
+	entry := &SupportBundleFileEntry{
+		Path:        name,
+		Description: description,
+	}
+	w.manifest.Files = append(w.manifest.Files, entry)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+	f, err := w.zip.Create(name)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+}
+
+// redactFile removes secrets from the content of a JSON or YAML file
+func redactFile(filename string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return []byte(core.RedactJSON(data)), nil
+	case ".yml", ".yaml":
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(core.RedactValue(doc))
+	default:
+		return []byte(core.RedactString(string(data))), nil
+	}
+}
+
+func (w *supportBundleWriter) addFile(name, description, filename string) {
+	data, err := ioutil.ReadFile(filename)
+	if err == nil {
+		data, err = redactFile(filename, data)
+	}
+	w.add(name, description, data, err)
+}
+
+func (w *supportBundleWriter) addJSON(name, description string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	w.add(name, description, data, err)
+}
+
+// CreateSupportBundle collects the configuration, container details and logs of the
+// stack into a single zip file that can be attached to a bug report
+func (s *StackManager) CreateSupportBundle(options *types.SupportBundleOptions) error {
+	f, err := os.Create(options.OutputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := &supportBundleWriter{
+		zip: zip.NewWriter(f),
+		manifest: &SupportBundleManifest{
+			Stack:      s.Stack.Name,
+			CreatedAt:  time.Now().UTC(),
+			CLIVersion: options.CLIVersion,
+			Files:      []*SupportBundleFileEntry{},
+		},
+	}
+
+	s.Log.Info("collecting stack configuration")
+	stackJSON, err := json.Marshal(s.Stack)
+	if err == nil {
+		stackJSON = []byte(core.RedactJSON(stackJSON))
+	}
+	w.add("stack/stack.json", "stack configuration, with secrets redacted", stackJSON, err)
+
+	stackStatePath := filepath.Join(s.Stack.RuntimeDir, "stackState.json")
+	if _, err := os.Stat(stackStatePath); os.IsNotExist(err) {
+		stackStatePath = filepath.Join(s.Stack.InitDir, "stackState.json")
+	}
+	w.addFile("stack/stackState.json", "stack state, with private keys redacted", stackStatePath)
+	w.addFile("stack/docker-compose.yml", "generated docker compose file", filepath.Join(s.Stack.StackDir, "docker-compose.yml"))
+	w.addFile("stack/docker-compose.override.yml", "user docker compose overrides", filepath.Join(s.Stack.StackDir, "docker-compose.override.yml"))
+	s.addSupportBundleConfigFiles(w)
+
+	s.Log.Info("collecting container details and logs")
+	for _, service := range s.Services() {
+		inspect, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "inspect", service.ContainerName)
+		w.add(path.Join("containers", service.ServiceName, "inspect.json"), fmt.Sprintf("docker inspect of %s, with secrets redacted", service.ContainerName), []byte(core.RedactJSON([]byte(inspect))), err)
+		logs, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "logs", "--timestamps", "--tail", fmt.Sprint(options.LogLines), service.ContainerName)
+		w.add(path.Join("containers", service.ServiceName, "logs.txt"), fmt.Sprintf("last %d lines of logs from %s", options.LogLines, service.ContainerName), []byte(logs), err)
+	}
+
+	s.Log.Info("collecting image and host details")
+	w.addJSON("images.json", "image digests for every image in the stack", s.getImageDigests())
+	w.addJSON("host.json", "host, docker and CLI versions", s.getHostInfo(options))
+
+	w.addJSON("manifest.json", "the contents of this bundle", w.manifest)
+	return w.zip.Close()
+}
+
+// addSupportBundleConfigFiles adds the generated config files for core, the connectors,
+// the signer, data exchange and prometheus from the runtime directory, or from the init
+// directory if the stack has not yet been started
+func (s *StackManager) addSupportBundleConfigFiles(w *supportBundleWriter) {
+	configDir := filepath.Join(s.Stack.RuntimeDir, "config")
+	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+		configDir = filepath.Join(s.Stack.InitDir, "config")
+	}
+	files := make([]string, 0)
+	err := filepath.Walk(configDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || supportBundleExcludedFiles[info.Name()] || !supportBundleConfigExtensions[filepath.Ext(info.Name())] {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		w.add("config", "generated config files", nil, err)
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		rel, _ := filepath.Rel(configDir, file)
+		w.addFile(path.Join("config", filepath.ToSlash(rel)), "generated config file, with secrets redacted", file)
+	}
+}
+
+type imageDigest struct {
+	ID          string   `json:"id,omitempty"`
+	RepoDigests []string `json:"repoDigests,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func (s *StackManager) getImageDigests() map[string]*imageDigest {
+	images := make(map[string]*imageDigest)
+	for _, service := range s.buildDockerCompose().Services {
+		if service.Image == "" || images[service.Image] != nil {
+			continue
+		}
+		digest := &imageDigest{}
+		images[service.Image] = digest
+		out, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "image", "inspect", "--format", "{{json .}}", service.Image)
+		if err != nil {
+			digest.Error = err.Error()
+			continue
+		}
+		var inspect struct {
+			ID          string   `json:"Id"`
+			RepoDigests []string `json:"RepoDigests"`
+		}
+		if err := json.Unmarshal([]byte(out), &inspect); err != nil {
+			digest.Error = err.Error()
+			continue
+		}
+		digest.ID = inspect.ID
+		digest.RepoDigests = inspect.RepoDigests
+	}
+	return images
+}
+
+func (s *StackManager) getHostInfo(options *types.SupportBundleOptions) map[string]interface{} {
+	info := map[string]interface{}{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+		"cli": map[string]string{
+			"version": options.CLIVersion,
+			"commit":  options.CLICommit,
+			"date":    options.CLIBuildDate,
+		},
+	}
+	if out, err := docker.RunDockerCommandBuffered(s.ctx, s.Stack.StackDir, "version", "--format", "{{json .}}"); err != nil {
+		info["docker"] = err.Error()
+	} else {
+		var dockerVersion interface{}
+		if err := json.Unmarshal([]byte(out), &dockerVersion); err != nil {
+			info["docker"] = strings.TrimSpace(out)
+		} else {
+			info["docker"] = dockerVersion
+		}
+	}
+	if out, err := exec.Command("docker-compose", "version").CombinedOutput(); err != nil {
+		info["dockerCompose"] = err.Error()
+	} else {
+		info["dockerCompose"] = strings.TrimSpace(string(out))
+	}
+	return info
+}
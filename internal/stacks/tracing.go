@@ -0,0 +1,152 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"gopkg.in/yaml.v3"
+)
+
+// The components that cannot export OpenTelemetry traces in the versions the CLI runs, by the prefix of their services
+var untracedComponents = []struct {
+	prefix string
+	name   string
+}{
+	{"firefly_core_", "FireFly core"},
+	{"ethconnect_", "ethconnect"},
+	{"evmconnect_", "evmconnect"},
+	{"fabconnect_", "fabconnect"},
+	{"tokens_", "the token connectors"},
+	{"dataexchange_", "data exchange"},
+	{"ethsigner", "the signer"},
+	{"geth", "geth"},
+	{"fabric_peer", "the fabric peer"},
+}
+
+// ValidateTracing rejects --tracing for a stack where no component would export traces. Besu is the only
+// component that can, and only when its metrics are not exported to Prometheus instead.
+func ValidateTracing(ctx context.Context, options *types.InitOptions) error {
+	if !options.TracingEnabled {
+		return nil
+	}
+	besu := fftypes.FFEnum(options.BlockchainProvider).Equals(types.BlockchainProviderEthereum) &&
+		fftypes.FFEnum(options.BlockchainNodeProvider).Equals(types.BlockchainNodeProviderBesu)
+	for _, input := range options.AdditionalBlockchains {
+		b, err := types.ParseBlockchainSelection(ctx, input)
+		if err != nil {
+			return err
+		}
+		besu = besu || b.BlockchainNodeProvider.Equals(types.BlockchainNodeProviderBesu)
+	}
+	if !besu {
+		return fmt.Errorf("--tracing needs a besu node, as no other component of the stack can export OpenTelemetry traces")
+	}
+	if options.PrometheusEnabled || options.GrafanaEnabled {
+		return fmt.Errorf("--tracing cannot be used with --prometheus-enabled or --grafana-enabled, as besu then exports its metrics to Prometheus instead of its traces to the collector")
+	}
+	return nil
+}
+
+// writeOTelCollectorConfig writes the config of the OpenTelemetry collector, which receives traces
+// over OTLP and forwards them to Jaeger. Besu sends its metrics over OTLP alongside its traces, and
+// the collector just logs them.
+func (s *StackManager) writeOTelCollectorConfig(configDir string) error {
+	config := map[string]interface{}{
+		"receivers": map[string]interface{}{
+			"otlp": map[string]interface{}{
+				"protocols": map[string]interface{}{
+					"grpc": map[string]interface{}{"endpoint": "0.0.0.0:4317"},
+					"http": map[string]interface{}{"endpoint": "0.0.0.0:4318"},
+				},
+			},
+		},
+		"processors": map[string]interface{}{
+			"batch": map[string]interface{}{},
+		},
+		"exporters": map[string]interface{}{
+			"otlp/jaeger": map[string]interface{}{
+				"endpoint": "jaeger:4317",
+				"tls":      map[string]interface{}{"insecure": true},
+			},
+			"logging": map[string]interface{}{},
+		},
+		"service": map[string]interface{}{
+			"pipelines": map[string]interface{}{
+				"traces": map[string]interface{}{
+					"receivers":  []string{"otlp"},
+					"processors": []string{"batch"},
+					"exporters":  []string{"otlp/jaeger"},
+				},
+				"metrics": map[string]interface{}{
+					"receivers":  []string{"otlp"},
+					"processors": []string{"batch"},
+					"exporters":  []string{"logging"},
+				},
+			},
+		},
+	}
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(configDir, "otel-collector.yaml"), b, 0755)
+}
+
+// TracingSummary returns the components of the stack that export traces to the collector, and those that
+// cannot. Besu is the only one that can, and only when its metrics are not exported to Prometheus instead.
+func (s *StackManager) TracingSummary() (traced, untraced []string) {
+	compose := s.buildDockerCompose()
+	has := func(prefix string) bool {
+		for serviceName := range compose.Services {
+			if strings.HasPrefix(serviceName, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, component := range untracedComponents {
+		if has(component.prefix) {
+			untraced = append(untraced, component.name)
+		}
+	}
+	if has("besu") {
+		if s.Stack.PrometheusEnabled {
+			untraced = append(untraced, "besu (which exports its metrics to Prometheus instead)")
+		} else {
+			traced = append(traced, "besu")
+		}
+	}
+	return traced, untraced
+}
+
+// PrintTracingSummary tells the user which components of the stack show up in Jaeger
+func (s *StackManager) PrintTracingSummary() {
+	traced, untraced := s.TracingSummary()
+	if len(traced) > 0 {
+		fmt.Printf("Components exporting traces: %s\n", strings.Join(traced, ", "))
+	}
+	if len(untraced) > 0 {
+		fmt.Printf("Note: these components cannot export OpenTelemetry traces, so they do not appear in Jaeger: %s\n", strings.Join(untraced, ", "))
+	}
+}
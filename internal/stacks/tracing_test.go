@@ -0,0 +1,87 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stacks
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-cli/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTracingConfig(T *testing.T) {
+	newStackManager := func(prometheus bool) *StackManager {
+		return newTestStackManager(1, func(stack *types.Stack) {
+			stack.BlockchainNodeProvider = types.BlockchainNodeProviderBesu
+			stack.TracingEnabled = true
+			stack.PrometheusEnabled = prometheus
+		})
+	}
+
+	// Besu exports its traces to the collector, and the components that cannot are listed
+	s := newStackManager(false)
+	compose := s.buildDockerCompose()
+	assert.Contains(T, compose.Services, "otel_collector")
+	assert.Contains(T, compose.Services["besu"].Command, "--metrics-enabled --metrics-protocol=opentelemetry")
+	assert.Equal(T, "http://otel_collector:4317", compose.Services["besu"].Environment["OTEL_EXPORTER_OTLP_ENDPOINT"])
+	assert.Empty(T, compose.Services["firefly_core_0"].Environment["OTEL_EXPORTER_OTLP_ENDPOINT"])
+	traced, untraced := s.TracingSummary()
+	assert.Equal(T, []string{"besu"}, traced)
+	assert.Equal(T, []string{"FireFly core", "evmconnect", "data exchange", "the signer"}, untraced)
+
+	configDir := T.TempDir()
+	assert.NoError(T, s.writeOTelCollectorConfig(configDir))
+	b, err := ioutil.ReadFile(filepath.Join(configDir, "otel-collector.yaml"))
+	assert.NoError(T, err)
+	var config map[string]map[string]interface{}
+	assert.NoError(T, yaml.Unmarshal(b, &config))
+	pipelines := config["service"]["pipelines"].(map[string]interface{})
+	assert.Equal(T, []interface{}{"otlp/jaeger"}, pipelines["traces"].(map[string]interface{})["exporters"])
+	assert.Contains(T, pipelines, "metrics")
+
+	// When Prometheus scrapes the metrics of Besu, it cannot export traces
+	s = newStackManager(true)
+	compose = s.buildDockerCompose()
+	assert.NotContains(T, compose.Services["besu"].Command, "opentelemetry")
+	assert.Nil(T, compose.Services["besu"].Environment)
+	traced, untraced = s.TracingSummary()
+	assert.Empty(T, traced)
+	assert.Contains(T, untraced, "besu (which exports its metrics to Prometheus instead)")
+}
+
+func TestValidateTracing(T *testing.T) {
+	ctx := context.Background()
+	options := func(node string, additional []string, prometheus bool) *types.InitOptions {
+		return &types.InitOptions{
+			BlockchainProvider:     "ethereum",
+			BlockchainNodeProvider: node,
+			AdditionalBlockchains:  additional,
+			PrometheusEnabled:      prometheus,
+			TracingEnabled:         true,
+		}
+	}
+	assert.NoError(T, ValidateTracing(ctx, options("besu", nil, false)))
+	assert.NoError(T, ValidateTracing(ctx, options("geth", []string{"ethereum:besu"}, false)))
+	assert.Regexp(T, "needs a besu node", ValidateTracing(ctx, options("geth", []string{"fabric"}, false)))
+	assert.Regexp(T, "cannot be used with --prometheus-enabled", ValidateTracing(ctx, options("besu", nil, true)))
+	assert.Regexp(T, "cannot be used with --prometheus-enabled", ValidateTracing(ctx, &types.InitOptions{BlockchainProvider: "ethereum", BlockchainNodeProvider: "besu", GrafanaEnabled: true, TracingEnabled: true}))
+	assert.NoError(T, ValidateTracing(ctx, &types.InitOptions{BlockchainProvider: "ethereum", BlockchainNodeProvider: "geth"}))
+}
@@ -31,7 +31,6 @@ import (
 )
 
 const tokenProviderName = "erc1155"
-const contractName = "ERC1155MixedFungible"
 
 type ERC1155Provider struct {
 	ctx                context.Context
@@ -51,7 +50,7 @@ func (p *ERC1155Provider) DeploySmartContracts(tokenIndex int) (*types.ContractD
 	l := log.LoggerFromContext(p.ctx)
 	var containerName string
 	for _, member := range p.stack.Members {
-		if !member.External {
+		if !member.IsExternal(types.ComponentTokens) {
 			containerName = fmt.Sprintf("%s_tokens_%s_%d", p.stack.Name, member.ID, tokenIndex)
 			break
 		}
@@ -65,14 +64,15 @@ func (p *ERC1155Provider) DeploySmartContracts(tokenIndex int) (*types.ContractD
 		return nil, err
 	}
 	constructorArgs := []string{"firefly://"}
-	return p.blockchainProvider.DeployContract(filepath.Join(p.stack.RuntimeDir, "contracts", "ERC1155MixedFungible.json"), contractName, contractName, p.stack.Members[0], constructorArgs)
+	return p.blockchainProvider.DeployContract(filepath.Join(p.stack.RuntimeDir, "contracts", "ERC1155MixedFungible.json"), "ERC1155MixedFungible", contractName(tokenIndex), p.stack.Members[0], constructorArgs)
 }
 
 func (p *ERC1155Provider) FirstTimeSetup(tokenIdx int) error {
 	l := log.LoggerFromContext(p.ctx)
 	for _, member := range p.stack.Members {
 		l.Info(fmt.Sprintf("initializing tokens on member %s", member.ID))
-		tokenInitUrl := fmt.Sprintf("http://localhost:%d/api/v1/init", member.ExposedTokensPorts[tokenIdx])
+		scheme, _ := p.tokensEndpoint(member)
+		tokenInitUrl := fmt.Sprintf("%s://localhost:%d/api/v1/init", scheme, member.ExposedTokensPorts[tokenIdx])
 		if err := core.RequestWithRetry(p.ctx, "POST", tokenInitUrl, nil, nil); err != nil {
 			return err
 		}
@@ -87,7 +87,7 @@ func (p *ERC1155Provider) GetDockerServiceDefinitions(tokenIdx int) []*docker.Se
 
 		var contractAddress types.HexAddress
 		for _, contract := range p.stack.State.DeployedContracts {
-			if contract.Name == contractName {
+			if contract.Name == contractName(tokenIdx) {
 				switch loc := contract.Location.(type) {
 				case map[string]string:
 					contractAddress = types.HexAddress(loc["address"])
@@ -96,12 +96,16 @@ func (p *ERC1155Provider) GetDockerServiceDefinitions(tokenIdx int) []*docker.Se
 		}
 
 		env := map[string]interface{}{
-			"ETHCONNECT_URL":   p.blockchainProvider.GetConnectorURL(member),
+			"ETHCONNECT_URL":   member.ComponentURL(types.ComponentTokens, types.ComponentConnector, p.blockchainProvider.GetConnectorURL(member), p.blockchainProvider.GetConnectorExternalURL(member)),
 			"ETHCONNECT_TOPIC": connectorName,
 			"AUTO_INIT":        "false",
 			"CONTRACT_ADDRESS": contractAddress,
 		}
-		serviceDefinitions = append(serviceDefinitions, &docker.ServiceDefinition{
+		if auth := p.stack.ConnectorCredentials(member.ID); auth != nil {
+			env["ETHCONNECT_USERNAME"] = auth.Username
+			env["ETHCONNECT_PASSWORD"] = auth.Password
+		}
+		serviceDefinition := &docker.ServiceDefinition{
 			ServiceName: connectorName,
 			Service: &docker.Service{
 				Image:         p.stack.VersionManifest.TokensERC1155.GetDockerImageString(),
@@ -109,14 +113,21 @@ func (p *ERC1155Provider) GetDockerServiceDefinitions(tokenIdx int) []*docker.Se
 				Ports:         []string{fmt.Sprintf("%d:3000", member.ExposedTokensPorts[tokenIdx])},
 				Environment:   env,
 				DependsOn: map[string]map[string]string{
-					fmt.Sprintf("%s_%s", p.blockchainProvider.GetConnectorName(), member.ID): {"condition": "service_started"},
+					p.stack.BlockchainMemberServiceName(p.blockchainProvider.GetConnectorName(), member.ID): {"condition": "service_started"},
 				},
 				HealthCheck: &docker.HealthCheck{
 					Test: []string{"CMD", "curl", "http://localhost:3000/api"},
 				},
 				Logging: docker.StandardLogOptions,
 			},
-		})
+		}
+		docker.TrustStackCA(p.stack, serviceDefinition.Service)
+		serviceDefinitions = append(serviceDefinitions, serviceDefinition)
+		if !member.IsExternal(types.ComponentTokens) {
+			if proxy := docker.ServeTLS(p.stack, serviceDefinition, member.ExposedTokensPorts[tokenIdx], 3000); proxy != nil {
+				serviceDefinitions = append(serviceDefinitions, proxy)
+			}
+		}
 	}
 	return serviceDefinitions
 }
@@ -131,18 +142,36 @@ func (p *ERC1155Provider) GetFireflyConfig(m *types.Organization, tokenIdx int)
 		Name: name,
 		FFTokens: &types.FFTokensConfig{
 			URL: p.getTokensURL(m, tokenIdx),
+			TLS: p.stack.ClientTLSConfig("firefly_core_"+m.ID, m.External),
 		},
 	}
 }
 
 func (p *ERC1155Provider) getTokensURL(member *types.Organization, tokenIdx int) string {
-	if !member.External {
-		return fmt.Sprintf("http://tokens_%s_%d:3000", member.ID, tokenIdx)
-	} else {
-		return fmt.Sprintf("http://127.0.0.1:%v", member.ExposedTokensPorts[tokenIdx])
+	scheme, port := p.tokensEndpoint(member)
+	return member.ComponentURL(types.ComponentCore, types.ComponentTokens,
+		fmt.Sprintf("%s://tokens_%s_%d:%d", scheme, member.ID, tokenIdx, port),
+		fmt.Sprintf("%s://127.0.0.1:%v", scheme, member.ExposedTokensPorts[tokenIdx]))
+}
+
+// tokensEndpoint returns the scheme and port that the token connectors of a member are reached on from docker. They
+// serve HTTPS through a TLS proxy when TLS is enabled, except when they run on the host, where they serve plain HTTP.
+func (p *ERC1155Provider) tokensEndpoint(member *types.Organization) (string, int) {
+	if p.stack.TLSEnabled && !member.IsExternal(types.ComponentTokens) {
+		return "https", docker.TLSProxyPort
 	}
+	return "http", 3000
 }
 
 func (p *ERC1155Provider) GetName() string {
 	return tokenProviderName
 }
+
+// contractName is the name the contract of a token connector is recorded under. The first
+// connector keeps the name that stacks created before there could be several of them used.
+func contractName(tokenIndex int) string {
+	if tokenIndex == 0 {
+		return "ERC1155MixedFungible"
+	}
+	return fmt.Sprintf("ERC1155MixedFungible_%d", tokenIndex)
+}
@@ -50,7 +50,7 @@ func (p *ERC20ERC721Provider) DeploySmartContracts(tokenIndex int) (*types.Contr
 	l := log.LoggerFromContext(p.ctx)
 	var containerName string
 	for _, member := range p.stack.Members {
-		if !member.External {
+		if !member.IsExternal(types.ComponentTokens) {
 			containerName = fmt.Sprintf("%s_tokens_%s_%d", p.stack.Name, member.ID, tokenIndex)
 			break
 		}
@@ -71,7 +71,8 @@ func (p *ERC20ERC721Provider) FirstTimeSetup(tokenIdx int) error {
 	l := log.LoggerFromContext(p.ctx)
 	for _, member := range p.stack.Members {
 		l.Info(fmt.Sprintf("initializing tokens on member %s", member.ID))
-		tokenInitUrl := fmt.Sprintf("http://localhost:%d/api/v1/init", member.ExposedTokensPorts[tokenIdx])
+		scheme, _ := p.tokensEndpoint(member)
+		tokenInitUrl := fmt.Sprintf("%s://localhost:%d/api/v1/init", scheme, member.ExposedTokensPorts[tokenIdx])
 		if err := core.RequestWithRetry(p.ctx, "POST", tokenInitUrl, nil, nil); err != nil {
 			return err
 		}
@@ -95,16 +96,20 @@ func (p *ERC20ERC721Provider) GetDockerServiceDefinitions(tokenIdx int) []*docke
 		}
 
 		env := map[string]interface{}{
-			"ETHCONNECT_URL":   p.blockchainProvider.GetConnectorURL(member),
+			"ETHCONNECT_URL":   member.ComponentURL(types.ComponentTokens, types.ComponentConnector, p.blockchainProvider.GetConnectorURL(member), p.blockchainProvider.GetConnectorExternalURL(member)),
 			"ETHCONNECT_TOPIC": connectorName,
 			"AUTO_INIT":        "false",
 		}
+		if auth := p.stack.ConnectorCredentials(member.ID); auth != nil {
+			env["ETHCONNECT_USERNAME"] = auth.Username
+			env["ETHCONNECT_PASSWORD"] = auth.Password
+		}
 
 		if !p.stack.DisableTokenFactories && factoryAddress != "" {
 			env["FACTORY_CONTRACT_ADDRESS"] = factoryAddress
 		}
 
-		serviceDefinitions = append(serviceDefinitions, &docker.ServiceDefinition{
+		serviceDefinition := &docker.ServiceDefinition{
 			ServiceName: connectorName,
 			Service: &docker.Service{
 				Image:         p.stack.VersionManifest.TokensERC20ERC721.GetDockerImageString(),
@@ -112,14 +117,21 @@ func (p *ERC20ERC721Provider) GetDockerServiceDefinitions(tokenIdx int) []*docke
 				Ports:         []string{fmt.Sprintf("%d:3000", member.ExposedTokensPorts[tokenIdx])},
 				Environment:   env,
 				DependsOn: map[string]map[string]string{
-					fmt.Sprintf("%s_%s", p.blockchainProvider.GetConnectorName(), member.ID): {"condition": "service_started"},
+					p.stack.BlockchainMemberServiceName(p.blockchainProvider.GetConnectorName(), member.ID): {"condition": "service_started"},
 				},
 				HealthCheck: &docker.HealthCheck{
 					Test: []string{"CMD", "curl", "http://localhost:3000/api"},
 				},
 				Logging: docker.StandardLogOptions,
 			},
-		})
+		}
+		docker.TrustStackCA(p.stack, serviceDefinition.Service)
+		serviceDefinitions = append(serviceDefinitions, serviceDefinition)
+		if !member.IsExternal(types.ComponentTokens) {
+			if proxy := docker.ServeTLS(p.stack, serviceDefinition, member.ExposedTokensPorts[tokenIdx], 3000); proxy != nil {
+				serviceDefinitions = append(serviceDefinitions, proxy)
+			}
+		}
 	}
 	return serviceDefinitions
 }
@@ -134,16 +146,25 @@ func (p *ERC20ERC721Provider) GetFireflyConfig(m *types.Organization, tokenIdx i
 		Name: name,
 		FFTokens: &types.FFTokensConfig{
 			URL: p.getTokensURL(m, tokenIdx),
+			TLS: p.stack.ClientTLSConfig("firefly_core_"+m.ID, m.External),
 		},
 	}
 }
 
 func (p *ERC20ERC721Provider) getTokensURL(member *types.Organization, tokenIdx int) string {
-	if !member.External {
-		return fmt.Sprintf("http://tokens_%s_%d:3000", member.ID, tokenIdx)
-	} else {
-		return fmt.Sprintf("http://127.0.0.1:%v", member.ExposedTokensPorts[tokenIdx])
+	scheme, port := p.tokensEndpoint(member)
+	return member.ComponentURL(types.ComponentCore, types.ComponentTokens,
+		fmt.Sprintf("%s://tokens_%s_%d:%d", scheme, member.ID, tokenIdx, port),
+		fmt.Sprintf("%s://127.0.0.1:%v", scheme, member.ExposedTokensPorts[tokenIdx]))
+}
+
+// tokensEndpoint returns the scheme and port that the token connectors of a member are reached on from docker. They
+// serve HTTPS through a TLS proxy when TLS is enabled, except when they run on the host, where they serve plain HTTP.
+func (p *ERC20ERC721Provider) tokensEndpoint(member *types.Organization) (string, int) {
+	if p.stack.TLSEnabled && !member.IsExternal(types.ComponentTokens) {
+		return "https", docker.TLSProxyPort
 	}
+	return "http", 3000
 }
 
 func (p *ERC20ERC721Provider) GetName() string {
@@ -0,0 +1,72 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// BasicAuthContainerFile is where the password file of a member is mounted in the containers that check it
+const BasicAuthContainerFile = "/etc/firefly-auth/passwords"
+
+// APICredentials returns the username and password for the FireFly APIs of a member, or nil if basic auth is disabled
+func (s *Stack) APICredentials(memberID string) *BasicAuth {
+	if !s.BasicAuthEnabled {
+		return nil
+	}
+	if member, ok := s.credentials().Members[memberID]; ok && member.APIPassword != "" {
+		return &BasicAuth{Username: member.APIUsername, Password: member.APIPassword}
+	}
+	return nil
+}
+
+// ConnectorCredentials returns the credentials for the blockchain connector of a member, or nil if the connector
+// does not check them. Only evmconnect supports basic auth; it accepts the same users as the member's FireFly core.
+func (s *Stack) ConnectorCredentials(memberID string) *BasicAuth {
+	if !s.BlockchainConnector.Equals(BlockchainConnectorEvmconnect) {
+		return nil
+	}
+	return s.APICredentials(memberID)
+}
+
+// BasicAuthPasswordFile returns the htpasswd file with the users of a member's APIs
+func (s *Stack) BasicAuthPasswordFile(memberID string) string {
+	return filepath.Join(s.RuntimeDir, "config", "auth", fmt.Sprintf("passwords_%s", memberID))
+}
+
+// ServerAuthConfig returns the settings for a service to require basic auth, or nil if basic auth is disabled
+func (s *Stack) ServerAuthConfig(memberID string, external bool) *AuthConfig {
+	if !s.BasicAuthEnabled {
+		return nil
+	}
+	passwordFile := BasicAuthContainerFile
+	if external {
+		passwordFile = s.BasicAuthPasswordFile(memberID)
+	}
+	return &AuthConfig{
+		Type: "basic",
+		Basic: &BasicAuthConfig{
+			PasswordFile: passwordFile,
+		},
+	}
+}
+
+// BasicAuthVolume returns the bind mount of a member's password file into a container
+func (s *Stack) BasicAuthVolume(memberID string) string {
+	return fmt.Sprintf("%s:%s:ro", s.BasicAuthPasswordFile(memberID), BasicAuthContainerFile)
+}
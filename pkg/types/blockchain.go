@@ -0,0 +1,159 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+)
+
+// StackBlockchain is a blockchain that a stack runs alongside the one it was created with. It has
+// its own node and block of ports, and a connector for each member that FireFly core uses as the
+// blockchain plugin named blockchain<index>.
+type StackBlockchain struct {
+	BlockchainProvider     fftypes.FFEnum      `json:"blockchainProvider"`
+	BlockchainConnector    fftypes.FFEnum      `json:"blockchainConnector"`
+	BlockchainNodeProvider fftypes.FFEnum      `json:"blockchainNodeProvider,omitempty"`
+	ExposedBlockchainPort  int                 `json:"exposedBlockchainPort,omitempty"`
+	ChainIDPtr             *int64              `json:"chainID,omitempty"`
+	Members                []*BlockchainMember `json:"members"`
+}
+
+// BlockchainMember holds the connector ports and the account of a member on an additional blockchain
+type BlockchainMember struct {
+	ExposedConnectorPort        int         `json:"exposedConnectorPort,omitempty"`
+	ExposedConnectorMetricsPort int         `json:"exposedConnectorMetricsPort,omitempty"`
+	Account                     interface{} `json:"account,omitempty"`
+}
+
+// ParseBlockchainSelection parses an additional blockchain in the form <provider>[:<node>[:<connector>]],
+// for example "ethereum:besu:evmconnect" or "fabric"
+func ParseBlockchainSelection(ctx context.Context, input string) (*StackBlockchain, error) {
+	parts := strings.Split(input, ":")
+	provider, err := fftypes.FFEnumParseString(ctx, BlockchainProvider, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	b := &StackBlockchain{BlockchainProvider: provider}
+	switch provider {
+	case BlockchainProviderEthereum:
+		b.BlockchainNodeProvider = BlockchainNodeProviderGeth
+		b.BlockchainConnector = BlockchainConnectorEvmconnect
+		if len(parts) > 1 {
+			if b.BlockchainNodeProvider, err = fftypes.FFEnumParseString(ctx, BlockchainNodeProvider, parts[1]); err != nil {
+				return nil, err
+			}
+		}
+		if len(parts) > 2 {
+			if b.BlockchainConnector, err = fftypes.FFEnumParseString(ctx, BlockchainConnector, parts[2]); err != nil {
+				return nil, err
+			}
+		}
+		if b.BlockchainNodeProvider.Equals(BlockchainNodeProviderRemoteRPC) {
+			return nil, fmt.Errorf("an additional blockchain cannot use a remote-rpc node")
+		}
+	case BlockchainProviderFabric:
+		b.BlockchainConnector = BlockchainConnectorFabconnect
+	default:
+		return nil, fmt.Errorf("'%s' is not supported as an additional blockchain", provider)
+	}
+	if len(parts) > 3 || (provider.Equals(BlockchainProviderFabric) && len(parts) > 1) {
+		return nil, fmt.Errorf("invalid blockchain '%s' - expected <provider>[:<node>[:<connector>]]", input)
+	}
+	return b, nil
+}
+
+// BlockchainPluginName is the name of the blockchain plugin in the FireFly core config
+func BlockchainPluginName(index int) string {
+	return fmt.Sprintf("blockchain%d", index)
+}
+
+// BlockchainDirName is the directory, under the init and runtime directories, that the
+// blockchain of this stack (or of this view of the stack) keeps its files in
+func (s *Stack) BlockchainDirName() string {
+	if s.BlockchainIndex == 0 {
+		return "blockchain"
+	}
+	return BlockchainPluginName(s.BlockchainIndex)
+}
+
+// BlockchainServiceName is the name of a service, volume or config file that the blockchain of this
+// view of the stack runs once, such as its node or signer. The blockchain the stack was created with
+// keeps the plain name, so that existing stacks are unchanged.
+func (s *Stack) BlockchainServiceName(name string) string {
+	if s.BlockchainIndex == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", name, s.BlockchainIndex)
+}
+
+// BlockchainMemberServiceName is the name of a service, volume or config file that the blockchain of
+// this view of the stack runs for each member, such as its connector
+func (s *Stack) BlockchainMemberServiceName(name, memberID string) string {
+	return fmt.Sprintf("%s_%s", s.BlockchainServiceName(name), memberID)
+}
+
+// BlockchainCount returns the number of blockchains the stack runs, including the one it was created with
+func (s *Stack) BlockchainCount() int {
+	return 1 + len(s.Blockchains)
+}
+
+// BlockchainStack returns the view of the stack that the providers of a blockchain work with. Index 0
+// is the stack itself. For an additional blockchain it is a copy of the stack, with the blockchain
+// settings, connector ports and accounts of that blockchain, that shares the state and credentials.
+func (s *Stack) BlockchainStack(index int) *Stack {
+	if index == 0 {
+		return s
+	}
+	b := s.Blockchains[index-1]
+	view := *s
+	view.BlockchainIndex = index
+	view.BlockchainProvider = b.BlockchainProvider
+	view.BlockchainConnector = b.BlockchainConnector
+	view.BlockchainNodeProvider = b.BlockchainNodeProvider
+	view.ExposedBlockchainPort = b.ExposedBlockchainPort
+	view.ChainIDPtr = b.ChainIDPtr
+	// The FireFly contract of the default namespace, and any network that was joined, belong to the first blockchain
+	view.ContractAddress = ""
+	view.RemoteNodeURL = ""
+	view.RemoteFabricNetwork = false
+	view.Network = nil
+	view.Members = make([]*Organization, len(s.Members))
+	accounts := []interface{}{}
+	for i, member := range s.Members {
+		m := *member
+		m.ExposedConnectorPort = b.Members[i].ExposedConnectorPort
+		m.ExposedConnectorMetricsPort = b.Members[i].ExposedConnectorMetricsPort
+		m.Account = b.Members[i].Account
+		view.Members[i] = &m
+		if m.Account != nil {
+			accounts = append(accounts, m.Account)
+		}
+	}
+	if s.State != nil {
+		// The node only unlocks the accounts of its own members
+		view.State = &StackState{
+			DeployedContracts: s.State.DeployedContracts,
+			Accounts:          accounts,
+			Credentials:       s.State.Credentials,
+		}
+	}
+	return &view
+}
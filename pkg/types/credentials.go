@@ -0,0 +1,91 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "strings"
+
+// Stacks created before credentials were generated at init time used these fixed passwords
+const (
+	DefaultKeystorePassword = "correcthorsebatterystaple"
+	DefaultDatabasePassword = "f1refly"
+)
+
+type Credentials struct {
+	KeystorePassword string `json:"keystorePassword,omitempty"`
+	DatabasePassword string `json:"databasePassword,omitempty"`
+	APIUsername      string `json:"apiUsername,omitempty"`
+	APIPassword      string `json:"apiPassword,omitempty"`
+}
+
+type StackCredentials struct {
+	Credentials
+	PerMember bool `json:"perMember,omitempty"`
+	// Members holds the database and API passwords of each member, keyed by member ID
+	Members map[string]*Credentials `json:"members,omitempty"`
+	// Accounts holds the keystore password of each account, keyed by lower case address
+	Accounts map[string]*Credentials `json:"accounts,omitempty"`
+}
+
+func (s *Stack) credentials() *StackCredentials {
+	if s.State == nil || s.State.Credentials == nil {
+		return &StackCredentials{
+			Credentials: Credentials{
+				KeystorePassword: DefaultKeystorePassword,
+				DatabasePassword: DefaultDatabasePassword,
+			},
+		}
+	}
+	return s.State.Credentials
+}
+
+// KeystorePassword returns the password that the keystore file of the given account is encrypted with
+func (s *Stack) KeystorePassword(address string) string {
+	c := s.credentials()
+	if account, ok := c.Accounts[strings.ToLower(address)]; ok && account.KeystorePassword != "" {
+		return account.KeystorePassword
+	}
+	return c.KeystorePassword
+}
+
+// HasAccountKeystorePassword returns true if the keystore file of the given account has its own password
+func (s *Stack) HasAccountKeystorePassword(address string) bool {
+	account, ok := s.credentials().Accounts[strings.ToLower(address)]
+	return ok && account.KeystorePassword != ""
+}
+
+// DatabasePassword returns the postgres password for the given member
+func (s *Stack) DatabasePassword(memberID string) string {
+	c := s.credentials()
+	if member, ok := c.Members[memberID]; ok && member.DatabasePassword != "" {
+		return member.DatabasePassword
+	}
+	return c.DatabasePassword
+}
+
+// PerMemberCredentials returns true if each member (and each account) has its own passwords
+func (s *Stack) PerMemberCredentials() bool {
+	return s.credentials().PerMember
+}
+
+// SetKeystorePassword records the password used for the keystore file of a new account
+func (s *Stack) SetKeystorePassword(address, password string) {
+	c := s.credentials()
+	if c.Accounts == nil {
+		c.Accounts = make(map[string]*Credentials)
+	}
+	c.Accounts[strings.ToLower(address)] = &Credentials{KeystorePassword: password}
+}
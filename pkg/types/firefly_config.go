@@ -20,10 +20,28 @@ type LogConfig struct {
 	Level string `yaml:"level,omitempty"`
 }
 
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled,omitempty"`
+	CAFile   string `yaml:"caFile,omitempty"`
+	CertFile string `yaml:"certFile,omitempty"`
+	KeyFile  string `yaml:"keyFile,omitempty"`
+}
+
 type HttpServerConfig struct {
-	Port      int    `yaml:"port,omitempty"`
-	Address   string `yaml:"address,omitempty"`
-	PublicURL string `yaml:"publicURL,omitempty"`
+	Port      int         `yaml:"port,omitempty"`
+	Address   string      `yaml:"address,omitempty"`
+	PublicURL string      `yaml:"publicURL,omitempty"`
+	TLS       *TLSConfig  `yaml:"tls,omitempty"`
+	Auth      *AuthConfig `yaml:"auth,omitempty"`
+}
+
+type AuthConfig struct {
+	Type  string           `yaml:"type,omitempty"`
+	Basic *BasicAuthConfig `yaml:"basic,omitempty"`
+}
+
+type BasicAuthConfig struct {
+	PasswordFile string `yaml:"passwordfile,omitempty"`
 }
 
 type AdminServerConfig struct {
@@ -49,8 +67,9 @@ type BasicAuth struct {
 }
 
 type HttpEndpointConfig struct {
-	URL  string    `yaml:"url,omitempty"`
-	Auth BasicAuth `yaml:"auth,omitempty"`
+	URL  string     `yaml:"url,omitempty"`
+	Auth BasicAuth  `yaml:"auth,omitempty"`
+	TLS  *TLSConfig `yaml:"tls,omitempty"`
 }
 
 type UIConfig struct {
@@ -70,6 +89,7 @@ type EthconnectConfig struct {
 	URL   string     `yaml:"url,omitempty"`
 	Topic string     `yaml:"topic,omitempty"`
 	Auth  *BasicAuth `yaml:"auth,omitempty"`
+	TLS   *TLSConfig `yaml:"tls,omitempty"`
 }
 
 type FabconnectConfig struct {
@@ -136,7 +156,8 @@ type TokensConfig struct {
 }
 
 type FFTokensConfig struct {
-	URL string `yaml:"url,omitempty"`
+	URL string     `yaml:"url,omitempty"`
+	TLS *TLSConfig `yaml:"tls,omitempty"`
 }
 
 type DBEventsConfig struct {
@@ -0,0 +1,30 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+// HTTPClientOptions controls how the CLI talks to the services in a stack.
+// Any field left at its zero value falls back to the built-in default.
+type HTTPClientOptions struct {
+	RetryMaxAttempts    int     `json:"retryMaxAttempts,omitempty"`
+	RetryInitialDelayMs int     `json:"retryInitialDelayMs,omitempty"`
+	RetryMaxDelayMs     int     `json:"retryMaxDelayMs,omitempty"`
+	RetryFactor         float64 `json:"retryFactor,omitempty"`
+	RetryDeadlineSecs   int     `json:"retryDeadlineSecs,omitempty"`
+	RetryStatusCodes    []int   `json:"retryStatusCodes,omitempty"`
+	ConnectTimeoutSecs  int     `json:"connectTimeoutSecs,omitempty"`
+	ResponseTimeoutSecs int     `json:"responseTimeoutSecs,omitempty"`
+}
@@ -0,0 +1,74 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "strings"
+
+// SharedIPFS returns whether the members of the stack share a single IPFS node, which is only
+// done in public IPFS mode, where the node of each member would join the same network anyway
+func (s *Stack) SharedIPFS() bool {
+	return s.SharedInfrastructure && s.IPFSMode.Equals(IPFSModePublic)
+}
+
+// PostgresServiceName returns the postgres service that holds the database of a member
+func (s *Stack) PostgresServiceName(memberID string) string {
+	if s.SharedInfrastructure {
+		return "postgres"
+	}
+	return "postgres_" + memberID
+}
+
+// PostgresUser returns the user a member connects to postgres as. With shared infrastructure each
+// member has its own user, which owns its database, so that members cannot see each other's data.
+func (s *Stack) PostgresUser(memberID string) string {
+	if s.SharedInfrastructure {
+		return "firefly_" + memberID
+	}
+	return "postgres"
+}
+
+// PostgresDatabase returns the database of a member, or an empty string if the member has
+// a postgres of its own and uses the default database
+func (s *Stack) PostgresDatabase(memberID string) string {
+	if s.SharedInfrastructure {
+		return "firefly_" + memberID
+	}
+	return ""
+}
+
+// PostgresSuperuserPassword returns the password of the postgres user of the service that holds
+// the database of a member. A shared postgres uses the password of the stack.
+func (s *Stack) PostgresSuperuserPassword(memberID string) string {
+	if s.SharedInfrastructure {
+		return s.credentials().DatabasePassword
+	}
+	return s.DatabasePassword(memberID)
+}
+
+// PostgresExporterServiceName returns the service that exports the metrics of the postgres service
+// of a member to Prometheus
+func (s *Stack) PostgresExporterServiceName(memberID string) string {
+	return "postgres_exporter" + strings.TrimPrefix(s.PostgresServiceName(memberID), "postgres")
+}
+
+// IPFSServiceName returns the IPFS service that a member uses for shared storage
+func (s *Stack) IPFSServiceName(memberID string) string {
+	if s.SharedIPFS() {
+		return "ipfs"
+	}
+	return "ipfs_" + memberID
+}
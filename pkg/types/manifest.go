@@ -16,7 +16,11 @@
 
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 type GitHubRelease struct {
 	TagName string `json:"tag_name,omitempty"`
@@ -31,6 +35,21 @@ type VersionManifest struct {
 	TokensERC1155     *ManifestEntry `json:"tokens-erc1155"`
 	TokensERC20ERC721 *ManifestEntry `json:"tokens-erc20-erc721"`
 	Signer            *ManifestEntry `json:"signer"`
+	IPFS              *ManifestEntry `json:"ipfs,omitempty"`
+	Postgres          *ManifestEntry `json:"postgres,omitempty"`
+	PostgresExporter  *ManifestEntry `json:"postgres-exporter,omitempty"`
+	Prometheus        *ManifestEntry `json:"prometheus,omitempty"`
+	Grafana           *ManifestEntry `json:"grafana,omitempty"`
+	OTelCollector     *ManifestEntry `json:"otel-collector,omitempty"`
+	Jaeger            *ManifestEntry `json:"jaeger,omitempty"`
+	Sandbox           *ManifestEntry `json:"sandbox,omitempty"`
+	Geth              *ManifestEntry `json:"geth,omitempty"`
+	Besu              *ManifestEntry `json:"besu,omitempty"`
+	FabricTools       *ManifestEntry `json:"fabric-tools,omitempty"`
+	FabricCA          *ManifestEntry `json:"fabric-ca,omitempty"`
+	FabricOrderer     *ManifestEntry `json:"fabric-orderer,omitempty"`
+	FabricPeer        *ManifestEntry `json:"fabric-peer,omitempty"`
+	TLSProxy          *ManifestEntry `json:"tls-proxy,omitempty"`
 }
 
 func (m *VersionManifest) Entries() []*ManifestEntry {
@@ -49,6 +68,76 @@ func (m *VersionManifest) Entries() []*ManifestEntry {
 	}
 }
 
+// ThirdPartyEntries returns the entries for the images that are not built by FireFly, by their key in the manifest,
+// so that missing entries can be filled in with defaults
+func (m *VersionManifest) ThirdPartyEntries() map[string]**ManifestEntry {
+	return map[string]**ManifestEntry{
+		"ipfs":              &m.IPFS,
+		"postgres":          &m.Postgres,
+		"postgres-exporter": &m.PostgresExporter,
+		"prometheus":        &m.Prometheus,
+		"grafana":           &m.Grafana,
+		"otel-collector":    &m.OTelCollector,
+		"jaeger":            &m.Jaeger,
+		"sandbox":           &m.Sandbox,
+		"geth":              &m.Geth,
+		"besu":              &m.Besu,
+		"fabric-tools":      &m.FabricTools,
+		"fabric-ca":         &m.FabricCA,
+		"fabric-orderer":    &m.FabricOrderer,
+		"fabric-peer":       &m.FabricPeer,
+		"tls-proxy":         &m.TLSProxy,
+	}
+}
+
+// entriesByKey returns every entry of the manifest by its key, for the components that can be overridden by name
+func (m *VersionManifest) entriesByKey() map[string]**ManifestEntry {
+	entries := m.ThirdPartyEntries()
+	entries["firefly"] = &m.FireFly
+	entries["ethconnect"] = &m.Ethconnect
+	entries["evmconnect"] = &m.Evmconnect
+	entries["fabconnect"] = &m.Fabconnect
+	entries["dataexchange-https"] = &m.DataExchange
+	entries["tokens-erc1155"] = &m.TokensERC1155
+	entries["tokens-erc20-erc721"] = &m.TokensERC20ERC721
+	entries["signer"] = &m.Signer
+	return entries
+}
+
+// SetEntry replaces the entry of a component, by its key in the manifest
+func (m *VersionManifest) SetEntry(key string, entry *ManifestEntry) error {
+	entries := m.entriesByKey()
+	target, ok := entries[key]
+	if !ok {
+		keys := make([]string, 0, len(entries))
+		for k := range entries {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("unknown component '%s' - options are: %s", key, strings.Join(keys, ", "))
+	}
+	*target = entry
+	return nil
+}
+
+// CheckManifestKey returns an error if a component is not a key of the manifest
+func CheckManifestKey(key string) error {
+	return (&VersionManifest{}).SetEntry(key, nil)
+}
+
+// ParseImageOverride parses an image override in the form "<component>=<image>[:tag]", where
+// the component is a key of the manifest
+func ParseImageOverride(override string) (string, *ManifestEntry, error) {
+	parts := strings.SplitN(override, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", nil, fmt.Errorf("invalid image override '%s' - use <component>=<image>", override)
+	}
+	if err := CheckManifestKey(parts[0]); err != nil {
+		return "", nil, err
+	}
+	return parts[0], ParseManifestEntry(parts[1]), nil
+}
+
 type ManifestEntry struct {
 	Image string `json:"image,omitempty"`
 	Local bool   `json:"local,omitempty"`
@@ -64,3 +153,17 @@ func (m *ManifestEntry) GetDockerImageString() string {
 	}
 	return m.Image
 }
+
+// ParseManifestEntry creates an entry from a docker image reference in the form "image[:tag]" or "image@sha256:<digest>"
+func ParseManifestEntry(image string) *ManifestEntry {
+	if i := strings.Index(image, "@sha256:"); i >= 0 {
+		return &ManifestEntry{Image: image[:i], SHA: image[i+len("@sha256:"):]}
+	}
+	entry := &ManifestEntry{Image: image}
+	// A colon before the last slash belongs to a registry port, not a tag
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		entry.Image = image[:i]
+		entry.Tag = image[i+1:]
+	}
+	return entry
+}
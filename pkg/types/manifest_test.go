@@ -0,0 +1,64 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageOverride(T *testing.T) {
+	testCases := []struct {
+		override string
+		key      string
+		entry    *ManifestEntry
+		err      string
+	}{
+		{override: "firefly=ghcr.io/hyperledger/firefly:v1.1.0", key: "firefly", entry: &ManifestEntry{Image: "ghcr.io/hyperledger/firefly", Tag: "v1.1.0"}},
+		{override: "postgres=localhost:5000/postgres", key: "postgres", entry: &ManifestEntry{Image: "localhost:5000/postgres"}},
+		{override: "geth=ethereum/client-go@sha256:abcd", key: "geth", entry: &ManifestEntry{Image: "ethereum/client-go", SHA: "abcd"}},
+		{override: "firefly", err: "invalid image override 'firefly'"},
+		{override: "=firefly", err: "invalid image override '=firefly'"},
+		{override: "firefly=", err: "invalid image override 'firefly='"},
+		{override: "core=firefly", err: "unknown component 'core'"},
+	}
+	for _, tc := range testCases {
+		key, entry, err := ParseImageOverride(tc.override)
+		if tc.err != "" {
+			assert.ErrorContains(T, err, tc.err, tc.override)
+			continue
+		}
+		assert.NoError(T, err, tc.override)
+		assert.Equal(T, tc.key, key, tc.override)
+		assert.Equal(T, tc.entry, entry, tc.override)
+	}
+}
+
+func TestSetEntry(T *testing.T) {
+	manifest := &VersionManifest{FireFly: &ManifestEntry{Image: "firefly", Tag: "v1.0.0"}}
+	assert.NoError(T, manifest.SetEntry("firefly", &ManifestEntry{Image: "firefly", Tag: "v1.1.0"}))
+	assert.Equal(T, "firefly:v1.1.0", manifest.FireFly.GetDockerImageString())
+
+	assert.NoError(T, manifest.SetEntry("fabric-peer", &ManifestEntry{Image: "ff-dev/fabric-peer", Local: true}))
+	assert.True(T, manifest.FabricPeer.Local)
+	assert.True(T, (*manifest.ThirdPartyEntries()["fabric-peer"]).Local)
+
+	err := manifest.SetEntry("core", &ManifestEntry{Image: "firefly"})
+	assert.ErrorContains(T, err, "unknown component 'core' - options are: besu, dataexchange-https, ethconnect")
+	assert.Equal(T, "firefly:v1.1.0", manifest.FireFly.GetDockerImageString())
+}
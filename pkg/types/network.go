@@ -0,0 +1,32 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+// NetworkConfig records that a stack shares a multiparty network with other local stacks
+type NetworkConfig struct {
+	// DockerNetwork is the external docker network that the services which talk to the other stacks are attached to
+	DockerNetwork string `json:"dockerNetwork"`
+	// HostStack is the stack whose blockchain and FireFly contract this stack uses. It is empty on the host itself.
+	HostStack string `json:"hostStack,omitempty"`
+	// HostBlockchainNode is the container name of the blockchain node of the host stack
+	HostBlockchainNode string `json:"hostBlockchainNode,omitempty"`
+}
+
+// JoinedNetwork returns true if the stack uses the blockchain and FireFly contract of another stack
+func (s *Stack) JoinedNetwork() bool {
+	return s.Network != nil && s.Network.HostStack != ""
+}
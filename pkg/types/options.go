@@ -29,6 +29,35 @@ type StartOptions struct {
 	NoRollback bool
 }
 
+type LogsOptions struct {
+	Follow   bool
+	Members  []string
+	Services []string
+	Tail     string
+	Since    string
+	Until    string
+	Grep     string
+	Level    string
+	JSON     bool
+	Color    bool
+}
+
+type SupportBundleOptions struct {
+	OutputPath   string
+	LogLines     int
+	CLIVersion   string
+	CLICommit    string
+	CLIBuildDate string
+}
+
+type NamespaceOptions struct {
+	Name            string
+	Multiparty      bool
+	ContractAddress string
+	TokenProviders  []string
+	Blockchain      int
+}
+
 type InitOptions struct {
 	StackName                string
 	MemberCount              int
@@ -36,18 +65,26 @@ type InitOptions struct {
 	ServicesBasePort         int
 	DatabaseProvider         string
 	ExternalProcesses        int
+	ExternalComponents       map[string][]string
 	OrgNames                 []string
 	NodeNames                []string
 	BlockchainConnector      string
 	BlockchainProvider       string
 	BlockchainNodeProvider   string
+	AdditionalBlockchains    []string
 	TokenProviders           []string
 	FireFlyVersion           string
 	ManifestPath             string
+	ImageOverrides           []string
 	PrometheusEnabled        bool
 	PrometheusPort           int
+	GrafanaEnabled           bool
+	GrafanaPort              int
+	TracingEnabled           bool
+	JaegerPort               int
 	SandboxEnabled           bool
 	ExtraCoreConfigPath      string
+	MemberCoreConfigPaths    map[string]string
 	ExtraConnectorConfigPath string
 	BlockPeriod              int
 	ContractAddress          string
@@ -55,13 +92,20 @@ type InitOptions struct {
 	ChainID                  int64
 	DisableTokenFactories    bool
 	RequestTimeout           int
+	HTTPClient               HTTPClientOptions
 	ReleaseChannel           string
 	MultipartyEnabled        bool
+	GatewaySharedServices    bool
+	SharedInfrastructure     bool
 	IPFSMode                 string
 	CCPYAMLPaths             []string
 	MSPPaths                 []string
 	ChannelName              string
 	ChaincodeName            string
+	SecretsEncryption        string
+	PerMemberCredentials     bool
+	TLSEnabled               bool
+	BasicAuthEnabled         bool
 }
 
 const IPFSMode = "ipfs_mode"
@@ -71,6 +115,14 @@ var (
 	IPFSModePublic  = fftypes.FFEnumValue(IPFSMode, "public")
 )
 
+const SecretsEncryption = "secrets_encryption"
+
+var (
+	SecretsEncryptionNone       = fftypes.FFEnumValue(SecretsEncryption, "none")
+	SecretsEncryptionPassphrase = fftypes.FFEnumValue(SecretsEncryption, "passphrase")
+	SecretsEncryptionKeyring    = fftypes.FFEnumValue(SecretsEncryption, "keyring")
+)
+
 const BlockchainProvider = "blockchain_provider"
 
 var (
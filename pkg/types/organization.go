@@ -16,6 +16,31 @@
 
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
+// The components of a member that can run outside of docker compose, as processes on the host
+const (
+	ComponentCore         = "core"
+	ComponentConnector    = "connector"
+	ComponentTokens       = "tokens"
+	ComponentDataExchange = "dataexchange"
+)
+
+var MemberComponents = []string{ComponentCore, ComponentConnector, ComponentTokens, ComponentDataExchange}
+
+// CheckMemberComponent returns an error if a name is not a component that can run outside of docker compose
+func CheckMemberComponent(component string) error {
+	for _, c := range MemberComponents {
+		if c == component {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown component '%s' - options are: %s", component, strings.Join(MemberComponents, ", "))
+}
+
 type Organization struct {
 	ID                          string       `json:"id,omitempty"`
 	Index                       *int         `json:"index,omitempty"`
@@ -27,13 +52,48 @@ type Organization struct {
 	ExposedConnectorMetricsPort int          `json:"exposedConnectorMetricsPort,omitempty"`
 	ExposedDatabasePort         int          `json:"exposedPostgresPort,omitempty"`
 	ExposedDataexchangePort     int          `json:"exposedDataexchangePort,omitempty"`
+	ExposedDataexchangeP2PPort  int          `json:"exposedDataexchangeP2PPort,omitempty"`
 	ExposedIPFSApiPort          int          `json:"exposedIPFSApiPort,omitempty"`
 	ExposedIPFSGWPort           int          `json:"exposedIPFSGWPort,omitempty"`
 	ExposedUIPort               int          `json:"exposedUiPort,omitempty"`
 	ExposedSandboxPort          int          `json:"exposedSandboxPort,omitempty"`
 	ExposedTokensPorts          []int        `json:"exposedTokensPorts,omitempty"`
 	External                    bool         `json:"external,omitempty"`
+	ExternalComponents          []string     `json:"externalComponents,omitempty"`
 	OrgName                     string       `json:"orgName,omitempty"`
 	NodeName                    string       `json:"nodeName,omitempty"`
 	Namespaces                  []*Namespace `json:"namespaces"`
 }
+
+// IsExternal returns whether a component of the member runs outside of docker compose. Core keeps its own
+// flag, from before other components could be external.
+func (o *Organization) IsExternal(component string) bool {
+	if component == ComponentCore {
+		return o.External
+	}
+	for _, c := range o.ExternalComponents {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// HasExternalComponents returns whether any component of the member runs outside of docker compose
+func (o *Organization) HasExternalComponents() bool {
+	return o.External || len(o.ExternalComponents) > 0
+}
+
+// ComponentURL picks the URL at which one component of the member reaches another, from the URL of the target
+// inside docker compose and on the host. Processes on the host use the exposed ports on localhost, and
+// containers reach processes on the host through host.docker.internal.
+func (o *Organization) ComponentURL(from, to, internalURL, hostURL string) string {
+	switch {
+	case o.IsExternal(from):
+		return hostURL
+	case o.IsExternal(to):
+		return strings.Replace(hostURL, "127.0.0.1", "host.docker.internal", 1)
+	default:
+		return internalURL
+	}
+}
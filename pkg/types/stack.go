@@ -25,33 +25,49 @@ import (
 )
 
 type Stack struct {
-	Name                   string           `json:"name,omitempty"`
-	Members                []*Organization  `json:"members,omitempty"`
-	SwarmKey               string           `json:"swarmKey,omitempty"`
-	ExposedBlockchainPort  int              `json:"exposedBlockchainPort,omitempty"`
-	Database               fftypes.FFEnum   `json:"database"`
-	BlockchainProvider     fftypes.FFEnum   `json:"blockchainProvider"`
-	BlockchainConnector    fftypes.FFEnum   `json:"blockchainConnector"`
-	BlockchainNodeProvider fftypes.FFEnum   `json:"blockchainNodeProvider"`
-	TokenProviders         []fftypes.FFEnum `json:"tokenProviders"`
-	VersionManifest        *VersionManifest `json:"versionManifest,omitempty"`
-	PrometheusEnabled      bool             `json:"prometheusEnabled,omitempty"`
-	SandboxEnabled         bool             `json:"sandboxEnabled,omitempty"`
-	MultipartyEnabled      bool             `json:"multiparty"`
-	ExposedPrometheusPort  int              `json:"exposedPrometheusPort,omitempty"`
-	ContractAddress        string           `json:"contractAddress,omitempty"`
-	ChainIDPtr             *int64           `json:"chainID,omitempty"`
-	RemoteNodeURL          string           `json:"remoteNodeURL,omitempty"`
-	DisableTokenFactories  bool             `json:"disableTokenFactories,omitempty"`
-	RequestTimeout         int              `json:"requestTimeout,omitempty"`
-	IPFSMode               fftypes.FFEnum   `json:"ipfsMode"`
-	RemoteFabricNetwork    bool             `json:"remoteFabricNetwork,omitempty"`
-	ChannelName            string           `json:"channelName,omitempty"`
-	ChaincodeName          string           `json:"chaincodeName,omitempty"`
-	InitDir                string           `json:"-"`
-	RuntimeDir             string           `json:"-"`
-	StackDir               string           `json:"-"`
-	State                  *StackState      `json:"-"`
+	Name                   string             `json:"name,omitempty"`
+	Members                []*Organization    `json:"members,omitempty"`
+	SwarmKey               string             `json:"swarmKey,omitempty"`
+	ExposedBlockchainPort  int                `json:"exposedBlockchainPort,omitempty"`
+	Database               fftypes.FFEnum     `json:"database"`
+	BlockchainProvider     fftypes.FFEnum     `json:"blockchainProvider"`
+	BlockchainConnector    fftypes.FFEnum     `json:"blockchainConnector"`
+	BlockchainNodeProvider fftypes.FFEnum     `json:"blockchainNodeProvider"`
+	TokenProviders         []fftypes.FFEnum   `json:"tokenProviders"`
+	VersionManifest        *VersionManifest   `json:"versionManifest,omitempty"`
+	PrometheusEnabled      bool               `json:"prometheusEnabled,omitempty"`
+	SandboxEnabled         bool               `json:"sandboxEnabled,omitempty"`
+	MultipartyEnabled      bool               `json:"multiparty"`
+	SharedServicesPtr      *bool              `json:"sharedServices,omitempty"`
+	SharedInfrastructure   bool               `json:"sharedInfrastructure,omitempty"`
+	ExposedPrometheusPort  int                `json:"exposedPrometheusPort,omitempty"`
+	GrafanaEnabled         bool               `json:"grafanaEnabled,omitempty"`
+	ExposedGrafanaPort     int                `json:"exposedGrafanaPort,omitempty"`
+	TracingEnabled         bool               `json:"tracingEnabled,omitempty"`
+	ExposedJaegerPort      int                `json:"exposedJaegerPort,omitempty"`
+	ContractAddress        string             `json:"contractAddress,omitempty"`
+	ChainIDPtr             *int64             `json:"chainID,omitempty"`
+	RemoteNodeURL          string             `json:"remoteNodeURL,omitempty"`
+	DisableTokenFactories  bool               `json:"disableTokenFactories,omitempty"`
+	RequestTimeout         int                `json:"requestTimeout,omitempty"`
+	HTTPClient             *HTTPClientOptions `json:"httpClient,omitempty"`
+	IPFSMode               fftypes.FFEnum     `json:"ipfsMode"`
+	RemoteFabricNetwork    bool               `json:"remoteFabricNetwork,omitempty"`
+	ChannelName            string             `json:"channelName,omitempty"`
+	ChaincodeName          string             `json:"chaincodeName,omitempty"`
+	SecretsEncryption      fftypes.FFEnum     `json:"secretsEncryption,omitempty"`
+	SecretsSalt            string             `json:"secretsSalt,omitempty"`
+	TLSEnabled             bool               `json:"tlsEnabled,omitempty"`
+	BasicAuthEnabled       bool               `json:"basicAuthEnabled,omitempty"`
+	Network                *NetworkConfig     `json:"network,omitempty"`
+	Namespaces             []*StackNamespace  `json:"namespaces,omitempty"`
+	Blockchains            []*StackBlockchain `json:"blockchains,omitempty"`
+	DevLinks               map[string]string  `json:"devLinks,omitempty"`
+	BlockchainIndex        int                `json:"-"`
+	InitDir                string             `json:"-"`
+	RuntimeDir             string             `json:"-"`
+	StackDir               string             `json:"-"`
+	State                  *StackState        `json:"-"`
 }
 
 func (s *Stack) ChainID() int64 {
@@ -61,6 +77,15 @@ func (s *Stack) ChainID() int64 {
 	return *s.ChainIDPtr
 }
 
+// SharedServicesEnabled returns whether each member runs IPFS and data exchange, which
+// multiparty needs, but a stack in gateway mode only runs if asked to
+func (s *Stack) SharedServicesEnabled() bool {
+	if s.SharedServicesPtr == nil {
+		return true // stacks created before gateway mode was trimmed always run them
+	}
+	return *s.SharedServicesPtr
+}
+
 func (s *Stack) HasRunBefore() (bool, error) {
 	stackDir := filepath.Join(constants.StacksDir, s.Name)
 	isOldFileStructure, err := s.IsOldFileStructure()
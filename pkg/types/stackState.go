@@ -23,4 +23,8 @@ type DeployedContract struct {
 type StackState struct {
 	DeployedContracts []*DeployedContract `json:"deployedContracts"`
 	Accounts          []interface{}       `json:"accounts"`
+	Credentials       *StackCredentials   `json:"credentials,omitempty"`
+	// PendingCredentials are the credentials that a rotation is changing the stack over to. They are saved
+	// before anything is changed, so that a rotation that fails part way can be completed by running it again.
+	PendingCredentials *StackCredentials `json:"pendingCredentials,omitempty"`
 }
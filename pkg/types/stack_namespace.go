@@ -0,0 +1,82 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+)
+
+// StackNamespace is a predefined namespace that was added to a stack after it was first started
+type StackNamespace struct {
+	Name            string           `json:"name"`
+	Blockchain      int              `json:"blockchain,omitempty"`
+	Multiparty      bool             `json:"multiparty"`
+	ContractAddress string           `json:"contractAddress,omitempty"`
+	TokenProviders  []fftypes.FFEnum `json:"tokenProviders,omitempty"`
+	// TokenIndexes are the indexes of the token connectors of the namespace, which select their ports
+	// and container names. Indexes are never reused, so removing a namespace leaves a hole.
+	TokenIndexes []int `json:"tokenIndexes,omitempty"`
+}
+
+// TokenPlugin is an instance of a token connector, which runs for every member of the stack
+type TokenPlugin struct {
+	Index      int
+	Provider   fftypes.FFEnum
+	Name       string
+	Namespace  string
+	Blockchain int
+}
+
+// TokenPlugins returns the token connectors of the default namespace, followed by those of each added namespace
+func (s *Stack) TokenPlugins() []*TokenPlugin {
+	plugins := make([]*TokenPlugin, 0, len(s.TokenProviders))
+	for i, tp := range s.TokenProviders {
+		if tp.Equals(TokenProviderNone) {
+			continue
+		}
+		plugins = append(plugins, &TokenPlugin{
+			Index:     i,
+			Provider:  tp,
+			Name:      tp.String(),
+			Namespace: "default",
+		})
+	}
+	for _, ns := range s.Namespaces {
+		for i, tp := range ns.TokenProviders {
+			plugins = append(plugins, &TokenPlugin{
+				Index:      ns.TokenIndexes[i],
+				Provider:   tp,
+				Name:       fmt.Sprintf("%s_%s", tp, ns.Name),
+				Namespace:  ns.Name,
+				Blockchain: ns.Blockchain,
+			})
+		}
+	}
+	return plugins
+}
+
+// GetNamespace returns the added namespace with the given name, or nil if there is none
+func (s *Stack) GetNamespace(name string) *StackNamespace {
+	for _, ns := range s.Namespaces {
+		if ns.Name == name {
+			return ns
+		}
+	}
+	return nil
+}
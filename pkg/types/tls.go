@@ -0,0 +1,85 @@
+// Copyright © 2022 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// TLSContainerDir is where the certificate, key and CA of a service are mounted in its container
+const TLSContainerDir = "/etc/firefly-tls"
+
+// Scheme returns the URL scheme of the FireFly core, connector and signer endpoints of the stack
+func (s *Stack) Scheme() string {
+	if s.TLSEnabled {
+		return "https"
+	}
+	return "http"
+}
+
+// TLSConfigDir returns the directory holding the certificate, key and CA of a service
+func (s *Stack) TLSConfigDir(serviceName string) string {
+	return filepath.Join(s.RuntimeDir, "config", "tls", serviceName)
+}
+
+// TLSCAFile returns the CA bundle of the stack, which clients outside the stack can use to trust its endpoints
+func (s *Stack) TLSCAFile() string {
+	return filepath.Join(s.RuntimeDir, "config", "tls", "ca.pem")
+}
+
+func (s *Stack) tlsDir(serviceName string, external bool) string {
+	if external {
+		// External processes run on the host, so they read the files where the CLI wrote them
+		return s.TLSConfigDir(serviceName)
+	}
+	return TLSContainerDir
+}
+
+// ServerTLSConfig returns the settings for a service to serve HTTPS with its certificate, or nil if TLS is disabled
+func (s *Stack) ServerTLSConfig(serviceName string, external bool) *TLSConfig {
+	if !s.TLSEnabled {
+		return nil
+	}
+	dir := s.tlsDir(serviceName, external)
+	return &TLSConfig{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}
+}
+
+// ClientTLSConfig returns the settings for a service to trust the stack CA when calling other services, or nil if TLS is disabled
+func (s *Stack) ClientTLSConfig(serviceName string, external bool) *TLSConfig {
+	if !s.TLSEnabled {
+		return nil
+	}
+	return &TLSConfig{
+		Enabled: true,
+		CAFile:  filepath.Join(s.tlsDir(serviceName, external), "ca.pem"),
+	}
+}
+
+// TLSVolume returns the bind mount of a service's TLS directory into its container
+func (s *Stack) TLSVolume(serviceName string) string {
+	return fmt.Sprintf("%s:%s:ro", s.TLSConfigDir(serviceName), TLSContainerDir)
+}
+
+// TLSCAVolume returns the bind mount of the stack CA alone, for containers that only act as clients
+func (s *Stack) TLSCAVolume() string {
+	return fmt.Sprintf("%s:%s:ro", s.TLSCAFile(), filepath.Join(TLSContainerDir, "ca.pem"))
+}